@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// batchCommand implements the batch subcommand which harvests the names of
+// formats in several languages and writes a single multilingual corpus.
+//
+//	batch -langs en,de,fr -o corpus.json
+func batchCommand(args []string) error {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	langs := flags.String("langs", "", "comma separated list of languages to harvest labels in, e.g. en,de,fr")
+	output := flags.String("o", "corpus.json", "write the corpus to this file (JSON, or NDJSON with a .ndjson extension)")
+	endpoint := flags.String("endpoint", wdanalysis.DefaultEndpoint, "SPARQL endpoint to harvest")
+	cfgPath := flags.String("config", "", "read configuration from a JSON file")
+	logDir := flags.String("log-dir", "", "write an operational log for the run to this directory")
+	flags.Parse(args)
+	languages := wdanalysis.ParseLanguages(*langs)
+	if len(languages) == 0 {
+		return misused("no languages to harvest", nil, "usage: batch -langs <languages> -o <corpus.json>")
+	}
+	config := wdanalysis.DefaultConfig()
+	if *cfgPath != "" {
+		var err error
+		if config, err = wdanalysis.LoadConfig(*cfgPath); err != nil {
+			return failed("cannot read configuration", err, "check the path given to -config and that the file is valid JSON")
+		}
+	}
+	if *logDir != "" {
+		config.Logging.Directory = *logDir
+	}
+	start := time.Now()
+	if err := startOpLog(config.Logging, start); err != nil {
+		return err
+	}
+	format := wdanalysis.FormatJSON
+	if strings.ToLower(filepath.Ext(*output)) == ".ndjson" {
+		format = wdanalysis.FormatNDJSON
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return failed("cannot create corpus", err, "check the path given to -o can be written to")
+	}
+	defer file.Close()
+	p := &wdanalysis.Pipeline{
+		Tool:      version(),
+		Endpoint:  *endpoint,
+		Config:    config,
+		Languages: languages,
+		Exporters: []wdanalysis.Exporter{wdanalysis.RecordsExporter{Writer: file, Format: format}},
+		Logger:    log.New(log.Writer(), "", log.LstdFlags),
+	}
+	if err := p.Run(context.Background()); err != nil {
+		return failed("batch harvest failed", err, doctorHint)
+	}
+	if err := file.Close(); err != nil {
+		return failed("cannot write corpus", err, "check there is space to write the corpus to -o")
+	}
+	finishOpLog(start, p)
+	if err := writeJSON(os.Stdout, p.Summary()); err != nil {
+		return failed("cannot write summary", err, "")
+	}
+	return nil
+}
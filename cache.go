@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// cacheCommand implements the cache subcommand which manages the cached
+// results of harvests.
+//
+//	cache list
+//	cache clear
+func cacheCommand(args []string) error {
+	const usage = "usage: cache list | cache clear"
+	if len(args) == 0 {
+		return misused("no cache command given", nil, usage)
+	}
+	flags := flag.NewFlagSet(fmt.Sprintf("cache %s", args[0]), flag.ExitOnError)
+	dir := flags.String("dir", wdanalysis.CacheDir(), "directory harvests are cached in")
+	flags.Parse(args[1:])
+	switch args[0] {
+	case "list":
+		entries, err := wdanalysis.ListCache(*dir)
+		if err != nil {
+			return fmt.Errorf("cannot list cache: %s", err)
+		}
+		if err := writeJSON(os.Stdout, entries); err != nil {
+			return failed("cannot write report", err, "")
+		}
+	case "clear":
+		removed, err := wdanalysis.ClearCache(*dir)
+		if err != nil {
+			return fmt.Errorf("cannot clear cache: %s", err)
+		}
+		fmt.Fprintf(os.Stdout, "removed %d cached harvests\n", removed)
+	default:
+		return misused(fmt.Sprintf("unknown cache command '%s'", args[0]), nil, usage)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package main
+
+// Subcommands provide modes of operation that are distinct from harvesting
+// and are given as the first argument to the tool, e.g.
+//
+//	wdanalysis heuristics compare -a single -b relativity-pair
+var commands = map[string]func(args []string) error{
+	"heuristics":   heuristicsCommand,
+	"compare":      compareCommand,
+	"batch":        batchCommand,
+	"cache":        cacheCommand,
+	"doctor":       doctorCommand,
+	"endpoints":    endpointsCommand,
+	"merge-shards": mergeShardsCommand,
+}
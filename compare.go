@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// compareCommand implements the compare subcommand which compares the
+// sequences of a cached harvest with another corpus of signatures.
+//
+//	compare kessler -sigs file_sigs.json
+//	compare trid -defs triddefs_xml
+func compareCommand(args []string) error {
+	const usage = "usage: compare kessler -sigs <file_sigs.json> | compare trid -defs <definitions>"
+	if len(args) == 0 {
+		return misused("no corpus to compare with given", nil, usage)
+	}
+	flags := flag.NewFlagSet(fmt.Sprintf("compare %s", args[0]), flag.ExitOnError)
+	input := flags.String("input", wdanalysis.ResultsCache(), "cached SPARQL results to process")
+	var comparison func(p *wdanalysis.Pipeline) (interface{}, error)
+	switch args[0] {
+	case "kessler":
+		sigs := flags.String("sigs", "", "Gary Kessler's File Signatures Table as JSON")
+		comparison = func(p *wdanalysis.Pipeline) (interface{}, error) {
+			kessler, err := wdanalysis.LoadKessler(*sigs)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read Kessler table: %s", err)
+			}
+			return p.CompareKessler(kessler), nil
+		}
+	case "trid":
+		defs := flags.String("defs", "", "a TrID XML definition or a directory of definitions")
+		comparison = func(p *wdanalysis.Pipeline) (interface{}, error) {
+			definitions, err := wdanalysis.LoadTrID(*defs)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read TrID definitions: %s", err)
+			}
+			return p.CompareTrID(definitions), nil
+		}
+	default:
+		return misused(fmt.Sprintf("unknown corpus '%s'", args[0]), nil, usage)
+	}
+	flags.Parse(args[1:])
+	results, err := wdanalysis.ReadResults(*input)
+	if err != nil {
+		return failed("cannot read cached harvest", err, "harvest first, or give the path of cached results with -input")
+	}
+	p := &wdanalysis.Pipeline{}
+	if err := p.Process(results); err != nil {
+		return err
+	}
+	res, err := comparison(p)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(os.Stdout, res); err != nil {
+		return failed("cannot write report", err, "")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// The demo runs the whole pipeline against the bundled demo corpus rather
+// than a harvest, so that the tool can be tried without network access.
+// The corpus is written to the demo directory and processed as cached
+// results, which skips everything that needs the endpoint, and sample
+// exports are written alongside it.
+
+// demoCorpus is the name the demo corpus is written to in the demo
+// directory.
+const demoCorpus = "demo-results.json"
+
+// prepareDemo writes the demo corpus to dir and returns its path.
+func prepareDemo(dir string) (string, error) {
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"-input", input != ""},
+		{"-dump", dump != ""},
+		{"-offline", offline},
+		{"-fetch", fetch != ""},
+		{"-incremental", increment != ""},
+	}
+	for _, conflict := range conflicts {
+		if conflict.set {
+			return "", misused(fmt.Sprintf("-demo cannot be used with %s", conflict.flag), nil, "the demo processes only the bundled corpus")
+		}
+	}
+	path := filepath.Join(dir, demoCorpus)
+	if err := wdanalysis.WriteDemoCorpus(path); err != nil {
+		return "", failed("cannot write demo corpus", err, "check the directory given to -demo can be written to")
+	}
+	return path, nil
+}
+
+// demoExporters returns the exporters of the sample exports written to dir.
+func demoExporters(dir string) []wdanalysis.Exporter {
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	return []wdanalysis.Exporter{
+		wdanalysis.ReportExporter{Path: path("report.md")},
+		wdanalysis.DocumentExporter{Path: path("records.json")},
+		wdanalysis.CSVExporter{Path: path("sequences.csv"), Columns: wdanalysis.CSVColumns},
+		wdanalysis.OpenRefineExporter{Path: path("openrefine.tsv")},
+		wdanalysis.DROIDExporter{Path: path("droid.xml")},
+		wdanalysis.SourcesExporter{Path: path("sources.dot")},
+		wdanalysis.RollupExporter{Path: path("rollup.tsv")},
+		wdanalysis.ConversionsExporter{Path: path("conversions.tsv")},
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// doctorCommand implements the doctor subcommand which checks that the
+// environment and configuration are ready for a harvest.
+//
+//	doctor -config config.json
+func doctorCommand(args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	endpoint := flags.String("endpoint", wdanalysis.DefaultEndpoint, "SPARQL endpoint to check")
+	cfgPath := flags.String("config", "", "read configuration from a JSON file")
+	flags.Parse(args)
+	p := &wdanalysis.Pipeline{Endpoint: *endpoint, Config: wdanalysis.DefaultConfig()}
+	if *cfgPath != "" {
+		var err error
+		if p.Config, err = wdanalysis.LoadConfig(*cfgPath); err != nil {
+			return failed("cannot read configuration", err, "check the path given to -config and that the file is valid JSON")
+		}
+	}
+	diagnosis := p.Doctor(context.Background())
+	fmt.Fprintf(os.Stdout, "%s\n", diagnosis)
+	if !diagnosis.Ready {
+		return failed("not ready to harvest", nil, "fix the checks that failed above")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// endpointsCommand implements the endpoints subcommand which runs the
+// harvest query against two endpoints and reports the rows that differ.
+//
+//	endpoints -b https://qlever.cs.uni-freiburg.de/api/wikidata
+func endpointsCommand(args []string) error {
+	flags := flag.NewFlagSet("endpoints", flag.ExitOnError)
+	endpointA := flags.String("a", wdanalysis.DefaultEndpoint, "first SPARQL endpoint to query")
+	endpointB := flags.String("b", "", "second SPARQL endpoint to query, e.g. a mirror of the first")
+	cfgPath := flags.String("config", "", "read configuration from a JSON file")
+	qidList := flags.String("qid", "", "compare only these comma separated QIDs")
+	logDir := flags.String("log-dir", "", "write an operational log for the run to this directory")
+	flags.Parse(args)
+	if *endpointB == "" {
+		return misused("no endpoint to compare with given", nil, "usage: endpoints -a <endpoint> -b <endpoint>")
+	}
+	config := wdanalysis.DefaultConfig()
+	if *cfgPath != "" {
+		var err error
+		if config, err = wdanalysis.LoadConfig(*cfgPath); err != nil {
+			return failed("cannot read configuration", err, "check the path given to -config and that the file is valid JSON")
+		}
+	}
+	if *logDir != "" {
+		config.Logging.Directory = *logDir
+	}
+	start := time.Now()
+	if err := startOpLog(config.Logging, start); err != nil {
+		return err
+	}
+	var qids []string
+	if *qidList != "" {
+		var err error
+		if qids, err = wdanalysis.ParseQIDs(*qidList); err != nil {
+			return misused("cannot use -qid", err, "")
+		}
+	}
+	pipeline := func(endpoint string) *wdanalysis.Pipeline {
+		return &wdanalysis.Pipeline{
+			Tool:     version(),
+			Endpoint: endpoint,
+			Config:   config,
+			QIDs:     qids,
+			Logger:   log.New(log.Writer(), "", log.LstdFlags),
+		}
+	}
+	comparison, err := wdanalysis.CompareEndpoints(context.Background(), pipeline(*endpointA), pipeline(*endpointB))
+	if err != nil {
+		return failed("cannot compare endpoints", err, doctorHint)
+	}
+	if err := writeJSON(os.Stdout, comparison); err != nil {
+		return failed("cannot write report", err, "")
+	}
+	// A difference fails the command so that a scheduled harvest can stop
+	// before building an identifier from a mirror that lags behind.
+	if !comparison.Identical {
+		return failed(fmt.Sprintf("the rows of %d formats differ between the endpoints", len(comparison.Differences)), nil, "build from the endpoint that is up to date, or wait for the mirror to catch up")
+	}
+	opEvent("finish", map[string]interface{}{"duration": time.Since(start).String()})
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Failures are presented to the user in one form, whichever part of the
+// tool they come from, so that each says what failed, why, and what the
+// user can do about it. Each kind of failure exits with its own status so
+// that scheduled harvests can tell them apart.
+
+// Exit statuses.
+const (
+	exitFailed       = 1 // A harvest or subcommand failed.
+	exitUsage        = 2 // The tool was used incorrectly, as the flag package exits.
+	exitNoSignatures = 3 // No signatures were harvested so exports were kept.
+	exitSuspicious   = 4 // The changes since the baseline exceed a threshold so identifiers were kept.
+)
+
+// doctorHint is given for failures that doctor can help diagnose.
+const doctorHint = "run 'wdanalysis doctor' to check the configuration and the endpoint"
+
+// failure is an error to be presented to the user.
+type failure struct {
+	what string // What failed.
+	err  error  // Why it failed, if known.
+	hint string // What the user can do about it, if anything.
+	code int    // Status to exit with.
+}
+
+// Error satisfies the error interface.
+func (f failure) Error() string {
+	if f.err == nil {
+		return f.what
+	}
+	return fmt.Sprintf("%s: %s", f.what, f.err)
+}
+
+// failed returns a failure of the tool to do what was asked.
+func failed(what string, err error, hint string) error {
+	return failure{what: what, err: err, hint: hint, code: exitFailed}
+}
+
+// misused returns a failure caused by how the tool was invoked.
+func misused(what string, err error, hint string) error {
+	return failure{what: what, err: err, hint: hint, code: exitUsage}
+}
+
+// present reports an error to the user and in the operational log, and
+// returns the status to exit with.
+func present(err error) int {
+	f, ok := err.(failure)
+	if !ok {
+		f = failure{what: "wdanalysis failed", err: err, code: exitFailed}
+	}
+	log.Printf("error: %s", f)
+	if f.hint != "" {
+		log.Printf("%s", f.hint)
+	}
+	opEvent("error", map[string]interface{}{"error": f.Error(), "status": f.code})
+	return f.code
+}
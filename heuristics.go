@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// heuristicsCommand implements the heuristics subcommand.
+//
+//	heuristics compare -a provenance-pair -b relativity-pair
+func heuristicsCommand(args []string) error {
+	if len(args) == 0 || args[0] != "compare" {
+		return misused("no heuristics command given", nil, "usage: heuristics compare -a <heuristic> -b <heuristic>")
+	}
+	names := wdanalysis.HeuristicNames()
+	flags := flag.NewFlagSet("heuristics compare", flag.ExitOnError)
+	nameA := flags.String("a", "single", fmt.Sprintf("first heuristic to compare %v", names))
+	nameB := flags.String("b", "relativity-pair", fmt.Sprintf("second heuristic to compare %v", names))
+	input := flags.String("input", wdanalysis.ResultsCache(), "cached SPARQL results to process")
+	overrides := flags.String("overrides", "", "honour curated signature groupings from a JSON file over the heuristics")
+	flags.Parse(args[1:])
+	a, ok := wdanalysis.Heuristics[*nameA]
+	if !ok {
+		return misused(fmt.Sprintf("unknown heuristic '%s'", *nameA), nil, fmt.Sprintf("use one of: %v", names))
+	}
+	b, ok := wdanalysis.Heuristics[*nameB]
+	if !ok {
+		return misused(fmt.Sprintf("unknown heuristic '%s'", *nameB), nil, fmt.Sprintf("use one of: %v", names))
+	}
+	results, err := wdanalysis.ReadResults(*input)
+	if err != nil {
+		return failed("cannot read cached harvest", err, "harvest first, or give the path of cached results with -input")
+	}
+	p := &wdanalysis.Pipeline{}
+	if *overrides != "" {
+		if p.Overrides, err = wdanalysis.LoadOverrides(*overrides); err != nil {
+			return fmt.Errorf("cannot read overrides: %s", err)
+		}
+	}
+	if err := p.Process(results); err != nil {
+		return err
+	}
+	if err := writeJSON(os.Stdout, p.CompareHeuristics(a, b)); err != nil {
+		return failed("cannot write report", err, "")
+	}
+	return nil
+}
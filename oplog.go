@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// The operational log records what happened during a run, as JSON lines,
+// separately from the analytical outputs so that scheduled harvests can be
+// audited. A new log is created for every run and old logs are removed
+// according to the retention settings.
+
+const (
+	opLogPrefix    = "wdanalysis-"
+	opLogSuffix    = ".jsonl"
+	opLogTimestamp = "20060102T150405Z"
+	opLogRetain    = 30
+)
+
+// opLog writes the operational log of the current run.
+type opLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var operations *opLog
+
+// openOpLog creates the operational log for this run and applies the
+// retention policy to the logs of previous runs.
+func openOpLog(cfg wdanalysis.LoggingConfig, now time.Time) error {
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s%s%s", opLogPrefix, now.UTC().Format(opLogTimestamp), opLogSuffix)
+	file, err := os.OpenFile(filepath.Join(cfg.Directory, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	operations = &opLog{file: file}
+	log.SetOutput(io.MultiWriter(os.Stderr, opLogWriter{}))
+	return rotateOpLogs(cfg, now)
+}
+
+// startOpLog opens the operational log if the configuration asks for one
+// and records the start of the run. Every command that harvests starts it.
+func startOpLog(cfg wdanalysis.LoggingConfig, now time.Time) error {
+	if cfg.Directory == "" {
+		return nil
+	}
+	if err := openOpLog(cfg, now); err != nil {
+		return failed("cannot open operational log", err, "check the directory given to -log-dir can be written to")
+	}
+	opEvent("start", map[string]interface{}{"version": version(), "args": os.Args[1:]})
+	return nil
+}
+
+// finishOpLog records the end of a harvest that succeeded.
+func finishOpLog(start time.Time, p *wdanalysis.Pipeline) {
+	opEvent("finish", map[string]interface{}{
+		"duration": time.Since(start).String(),
+		"records":  p.Manifest().Records,
+		"results":  p.Summary().AllSparqlResults,
+	})
+}
+
+// closeOpLog closes the operational log if one is open.
+func closeOpLog() {
+	if operations != nil {
+		log.SetOutput(os.Stderr)
+		operations.file.Close()
+	}
+}
+
+// rotateOpLogs removes logs beyond the number to retain, oldest first, and
+// logs older than the maximum age.
+func rotateOpLogs(cfg wdanalysis.LoggingConfig, now time.Time) error {
+	retain := cfg.Retain
+	if retain <= 0 {
+		retain = opLogRetain
+	}
+	files, err := ioutil.ReadDir(cfg.Directory)
+	if err != nil {
+		return err
+	}
+	var logs []string
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), opLogPrefix) && strings.HasSuffix(file.Name(), opLogSuffix) {
+			logs = append(logs, file.Name())
+		}
+	}
+	// Names sort by their timestamp, newest last.
+	sort.Strings(logs)
+	for idx, name := range logs {
+		expired := idx < len(logs)-retain
+		if cfg.MaxAgeDays > 0 {
+			stamp := strings.TrimSuffix(strings.TrimPrefix(name, opLogPrefix), opLogSuffix)
+			created, err := time.Parse(opLogTimestamp, stamp)
+			if err == nil && now.Sub(created) > time.Duration(cfg.MaxAgeDays)*24*time.Hour {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(filepath.Join(cfg.Directory, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// write records an entry in the operational log.
+func (o *opLog) write(entry map[string]interface{}) {
+	if o == nil {
+		return
+	}
+	if _, ok := entry["time"]; !ok {
+		entry["time"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintf(o.file, "%s\n", line)
+}
+
+// opEvent records an event and its details in the operational log.
+func opEvent(event string, details map[string]interface{}) {
+	entry := map[string]interface{}{"event": event}
+	for key, value := range details {
+		entry[key] = value
+	}
+	operations.write(entry)
+}
+
+// opLogWriter receives the output of the standard logger and records each
+// message in the operational log.
+type opLogWriter struct{}
+
+// stdLogTimestamp is the layout of the standard logger's timestamp which we
+// replace with our own.
+const stdLogTimestamp = "2006/01/02 15:04:05 "
+
+// Write satisfies the io.Writer interface.
+func (opLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if len(line) >= len(stdLogTimestamp) {
+			if _, err := time.Parse(stdLogTimestamp, line[:len(stdLogTimestamp)]); err == nil {
+				line = line[len(stdLogTimestamp):]
+			}
+		}
+		opEvent("log", map[string]interface{}{"message": line})
+	}
+	return len(p), nil
+}
@@ -0,0 +1,181 @@
+package wdanalysis
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Vandalism or a mass edit gone wrong can remove many signatures from
+// Wikidata at once. A scheduled harvest compared with a baseline can be
+// given thresholds on how much may change, e.g. signatures-removed>5%. A
+// run exceeding any of them is suspicious: identifiers are not built from
+// it, so that downstream users keep the last good ones, while every other
+// export is written so that the changes can be reviewed.
+
+// ErrSuspicious is returned by Run when the changes since the baseline
+// exceed a threshold and identifiers were not exported as a result.
+var ErrSuspicious = errors.New("the changes since the baseline exceed an alert threshold")
+
+// Metrics of the changes since a baseline that thresholds can be set on.
+const (
+	MetricRecordsAdded      = "records-added"
+	MetricRecordsRemoved    = "records-removed"
+	MetricRecordsChanged    = "records-changed"
+	MetricSignaturesAdded   = "signatures-added"
+	MetricSignaturesRemoved = "signatures-removed"
+)
+
+// ThresholdMetrics lists the metrics thresholds can be set on.
+var ThresholdMetrics = []string{
+	MetricRecordsAdded,
+	MetricRecordsRemoved,
+	MetricRecordsChanged,
+	MetricSignaturesAdded,
+	MetricSignaturesRemoved,
+}
+
+// thresholdPattern matches a threshold, e.g. signatures-removed>5%.
+var thresholdPattern = regexp.MustCompile(`^([a-z-]+)\s*>\s*([0-9]+(?:\.[0-9]+)?)(%?)$`)
+
+// Threshold is the most a metric may change by before a run is
+// suspicious. Percentages are of the records or signatures of the
+// baseline.
+type Threshold struct {
+	Metric  string
+	Limit   float64
+	Percent bool
+}
+
+// ParseThreshold parses a threshold in the form metric>limit, where limit
+// is a count or a percentage, e.g. records-removed>10 or
+// signatures-removed>5%.
+func ParseThreshold(value string) (Threshold, error) {
+	var threshold Threshold
+	match := thresholdPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return threshold, fmt.Errorf("invalid threshold '%s', expected e.g. %s>5%%", value, MetricSignaturesRemoved)
+	}
+	if !contains(ThresholdMetrics, match[1]) {
+		return threshold, fmt.Errorf("unknown metric '%s', expected one of: %v", match[1], ThresholdMetrics)
+	}
+	limit, _ := strconv.ParseFloat(match[2], 64)
+	return Threshold{Metric: match[1], Limit: limit, Percent: match[3] == "%"}, nil
+}
+
+// String returns the threshold in the form it is parsed from.
+func (t Threshold) String() string {
+	if t.Percent {
+		return fmt.Sprintf("%s>%s%%", t.Metric, strconv.FormatFloat(t.Limit, 'f', -1, 64))
+	}
+	return fmt.Sprintf("%s>%s", t.Metric, strconv.FormatFloat(t.Limit, 'f', -1, 64))
+}
+
+// magnitude describes how much the records have changed since a baseline.
+type magnitude struct {
+	records    int // Records of the baseline.
+	signatures int // Signatures of the baseline.
+	metrics    map[string]int
+}
+
+// signatureKeys returns the keys of the signatures of a record.
+func signatureKeys(wd Wikidata) []string {
+	var keys []string
+	for _, s := range wd.Signatures {
+		keys = append(keys, s.Key)
+	}
+	return keys
+}
+
+// magnitude returns how much the records have changed since the baseline.
+func (b Baseline) magnitude(records []Wikidata) (magnitude, error) {
+	m := magnitude{records: len(b.wikidata), metrics: make(map[string]int)}
+	changed, removed, err := b.changed(records)
+	if err != nil {
+		return m, err
+	}
+	m.metrics[MetricRecordsRemoved] = removed
+	current := make(map[string]bool)
+	for _, wd := range records {
+		current[wd.ID] = true
+	}
+	for _, wd := range changed {
+		if _, ok := b.wikidata[wd.ID]; ok {
+			m.metrics[MetricRecordsChanged]++
+		} else {
+			m.metrics[MetricRecordsAdded]++
+		}
+	}
+	keys := make(map[string][]string)
+	for _, wd := range records {
+		keys[wd.ID] = signatureKeys(wd)
+	}
+	for id, wd := range b.wikidata {
+		previous := signatureKeys(wd)
+		m.signatures += len(previous)
+		for _, key := range previous {
+			if !contains(keys[id], key) {
+				m.metrics[MetricSignaturesRemoved]++
+			}
+		}
+	}
+	for id, now := range keys {
+		previous := signatureKeys(b.wikidata[id])
+		for _, key := range now {
+			if !contains(previous, key) {
+				m.metrics[MetricSignaturesAdded]++
+			}
+		}
+	}
+	return m, nil
+}
+
+// exceeds returns a description of the change if it exceeds the threshold.
+func (m magnitude) exceeds(t Threshold) (string, bool) {
+	count := m.metrics[t.Metric]
+	if !t.Percent {
+		return fmt.Sprintf("%s: %d", t, count), float64(count) > t.Limit
+	}
+	total := m.records
+	if strings.HasPrefix(t.Metric, "signatures") {
+		total = m.signatures
+	}
+	percent := 100.0
+	if total > 0 {
+		percent = float64(count) * 100 / float64(total)
+	}
+	return fmt.Sprintf("%s: %d (%.1f%%)", t, count, percent), count > 0 && percent > t.Limit
+}
+
+// checkThresholds adds every threshold the changes since the baseline
+// exceed to the summary and returns true if there were any.
+func (p *Pipeline) checkThresholds() (bool, error) {
+	if len(p.Thresholds) == 0 || p.Baseline == nil {
+		return false, nil
+	}
+	m, err := p.Baseline.magnitude(p.Records())
+	if err != nil {
+		return false, err
+	}
+	for _, threshold := range p.Thresholds {
+		if description, exceeded := m.exceeds(threshold); exceeded {
+			p.Logger.Printf("alert threshold exceeded, %s", description)
+			p.summary.Suspicious = append(p.summary.Suspicious, description)
+		}
+	}
+	return len(p.summary.Suspicious) > 0, nil
+}
+
+// publisher is implemented by exporters that build identifiers for
+// downstream users, which are withheld from suspicious runs.
+type publisher interface {
+	publishes()
+}
+
+// publishes satisfies the publisher interface.
+func (e IdentifierExporter) publishes() {}
+
+// publishes satisfies the publisher interface.
+func (e DROIDExporter) publishes() {}
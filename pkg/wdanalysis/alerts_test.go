@@ -0,0 +1,40 @@
+package wdanalysis
+
+import "testing"
+
+// TestParseThreshold checks the thresholds that are parsed and those that
+// are rejected.
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Threshold
+		err   bool
+	}{
+		{"signatures-removed>5%", Threshold{Metric: MetricSignaturesRemoved, Limit: 5, Percent: true}, false},
+		{"records-removed>10", Threshold{Metric: MetricRecordsRemoved, Limit: 10}, false},
+		{" records-added > 2.5% ", Threshold{Metric: MetricRecordsAdded, Limit: 2.5, Percent: true}, false},
+		{"records-changed>0", Threshold{Metric: MetricRecordsChanged, Limit: 0}, false},
+		{"signatures-added>100", Threshold{Metric: MetricSignaturesAdded, Limit: 100}, false},
+		{"signatures-deleted>5%", Threshold{}, true},
+		{"signatures-removed<5%", Threshold{}, true},
+		{"signatures-removed>", Threshold{}, true},
+		{"signatures-removed>-5", Threshold{}, true},
+		{"signatures-removed>5%%", Threshold{}, true},
+		{"", Threshold{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseThreshold(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("ParseThreshold(%q): got error %v, want error %t", test.value, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseThreshold(%q) = %+v, want %+v", test.value, got, test.want)
+		}
+		if err == nil {
+			if again, _ := ParseThreshold(got.String()); again != got {
+				t.Errorf("%q was written as %q which parses as %+v", test.value, got.String(), again)
+			}
+		}
+	}
+}
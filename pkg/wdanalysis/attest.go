@@ -0,0 +1,119 @@
+package wdanalysis
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+)
+
+// Exported files can be signed so that institutions distributing them
+// internally can verify their integrity and provenance. Signatures are
+// in-toto attestations in a DSSE envelope, signed with an Ed25519 key in
+// PKCS#8 PEM form, e.g. as created by:
+//
+//		openssl genpkey -algorithm ed25519 -out key.pem
+//
+// The harvest manifest is embedded in the attestation's predicate.
+
+const (
+	intotoStatementType = "https://in-toto.io/Statement/v0.1"
+	intotoPayloadType   = "application/vnd.in-toto+json"
+	intotoPredicateType = "https://github.com/ross-spencer/wdanalysis/harvest/v0.1"
+	attestationSuffix   = ".intoto.json"
+)
+
+// intotoSubject describes the file an attestation is about.
+type intotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// intotoStatement is the payload of an attestation.
+type intotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []intotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     Manifest        `json:"predicate"`
+}
+
+// dsseSignature is a signature over a DSSE envelope's payload.
+type dsseSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// dsseEnvelope packages a signed payload.
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+// LoadSigningKey reads an Ed25519 private key from a PKCS#8 PEM file.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in '%s'", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key in '%s' is not an Ed25519 key", path)
+	}
+	return edKey, nil
+}
+
+// keyID returns an identifier for a key, the SHA-256 of its public key.
+func keyID(key ed25519.PrivateKey) string {
+	return fmt.Sprintf("%x", sha256.Sum256(key.Public().(ed25519.PublicKey)))
+}
+
+// pae returns the DSSE pre-authentication encoding of a payload which is
+// what is signed.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// attest returns a signed attestation for a file's content.
+func attest(name string, content []byte, key ed25519.PrivateKey, manifest Manifest) ([]byte, error) {
+	statement := intotoStatement{
+		Type: intotoStatementType,
+		Subject: []intotoSubject{{
+			Name:   name,
+			Digest: map[string]string{"sha256": fmt.Sprintf("%x", sha256.Sum256(content))},
+		}},
+		PredicateType: intotoPredicateType,
+		Predicate:     manifest,
+	}
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := key.Sign(rand.Reader, pae(intotoPayloadType, payload), crypto.Hash(0))
+	if err != nil {
+		return nil, err
+	}
+	envelope := dsseEnvelope{
+		PayloadType: intotoPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []dsseSignature{{
+			KeyID: keyID(key),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}
+	return json.MarshalIndent(envelope, "", "  ")
+}
@@ -0,0 +1,145 @@
+package wdanalysis
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Some institutions require a record of every outbound request a tool makes
+// before it can be run on their networks. The audit log records each request
+// as a line of JSON. Responses served from a cache in place of a request,
+// i.e. replayed results and the pages of a resumed harvest, are recorded as
+// hits so that the log accounts for every response the run used.
+
+// auditEntry describes a single outbound request, or a response served
+// from a cache in place of one.
+type auditEntry struct {
+	Time      string `json:"time"`
+	Method    string `json:"method,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Source    string `json:"source,omitempty"`
+	QueryHash string `json:"queryHash,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Duration  string `json:"duration"`
+	Bytes     int64  `json:"bytes"`
+	Cache     string `json:"cache"`
+	Error     string `json:"error,omitempty"`
+}
+
+// auditLog is the destination of the audit entries.
+type auditLog struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// OpenAuditLog opens, or creates, an audit log at path. Entries are
+// appended so that the log of previous runs is retained.
+func OpenAuditLog(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// write records an entry in the audit log.
+func (a *auditLog) write(entry auditEntry) {
+	if a == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fmt.Fprintf(a.writer, "%s\n", line)
+}
+
+// newAuditEntry describes a request in the audit log. SPARQL queries are
+// long so a hash of the query is recorded in place of the full URL.
+func newAuditEntry(req *http.Request, start time.Time) auditEntry {
+	entry := auditEntry{
+		Time:     start.UTC().Format(time.RFC3339),
+		Method:   req.Method,
+		Endpoint: fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, req.URL.Path),
+		Cache:    "miss",
+	}
+	if query := req.URL.Query().Get("query"); query != "" {
+		entry.QueryHash = fmt.Sprintf("%x", sha256.Sum256([]byte(query)))
+	} else {
+		entry.URL = req.URL.String()
+	}
+	return entry
+}
+
+// auditCacheHit records a response read from the file at source in place
+// of a request. The query is recorded if the response is known to answer
+// it, as for a request to the pipeline's endpoint.
+func (p *Pipeline) auditCacheHit(query string, source string, bytes int64, start time.Time) {
+	entry := auditEntry{
+		Time:     start.UTC().Format(time.RFC3339),
+		Source:   source,
+		Duration: time.Since(start).String(),
+		Bytes:    bytes,
+		Cache:    "hit",
+	}
+	if query != "" {
+		entry.Method = http.MethodGet
+		entry.Endpoint = p.Endpoint
+		entry.QueryHash = fmt.Sprintf("%x", sha256.Sum256([]byte(query)))
+	}
+	p.audit.write(entry)
+}
+
+// auditTransport records every request made through it in the audit log.
+type auditTransport struct {
+	next  http.RoundTripper
+	audit *auditLog
+}
+
+// RoundTrip satisfies the http.RoundTripper interface.
+func (t auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	entry := newAuditEntry(req, start)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		entry.Duration = time.Since(start).String()
+		entry.Error = err.Error()
+		t.audit.write(entry)
+		return resp, err
+	}
+	entry.Status = resp.StatusCode
+	resp.Body = &auditBody{ReadCloser: resp.Body, entry: entry, start: start, audit: t.audit}
+	return resp, nil
+}
+
+// auditBody counts the bytes read from a response and writes the audit
+// entry once the body has been closed.
+type auditBody struct {
+	io.ReadCloser
+	entry auditEntry
+	start time.Time
+	done  bool
+	audit *auditLog
+}
+
+// Read satisfies the io.Reader interface.
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.entry.Bytes += int64(n)
+	return n, err
+}
+
+// Close satisfies the io.Closer interface.
+func (b *auditBody) Close() error {
+	if !b.done {
+		b.done = true
+		b.entry.Duration = time.Since(b.start).String()
+		b.audit.write(b.entry)
+	}
+	return b.ReadCloser.Close()
+}
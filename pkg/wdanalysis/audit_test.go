@@ -0,0 +1,93 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// auditEntries returns the entries of an audit log.
+func auditEntries(t *testing.T, log *bytes.Buffer) []auditEntry {
+	t.Helper()
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("cannot read audit entry %q: %s", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// TestAuditCache checks that a harvest is recorded in the audit log as a
+// miss and that replaying its cached results offline is recorded as a hit
+// for the same endpoint and query.
+func TestAuditCache(t *testing.T) {
+	body := demoBody(t)
+	srv := newTestEndpoint(t, func(int32) []byte { return body })
+	var log bytes.Buffer
+	p := newTestPipeline(t, srv.URL)
+	p.AuditLog = &log
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	entries := auditEntries(t, &log)
+	if len(entries) != 1 {
+		t.Fatalf("got %d audit entries for the harvest, want 1", len(entries))
+	}
+	miss := entries[0]
+	if miss.Cache != "miss" || miss.Bytes != int64(len(body)) {
+		t.Errorf("got %s of %d bytes, want a miss of %d bytes", miss.Cache, miss.Bytes, len(body))
+	}
+
+	log.Reset()
+	offline := &Pipeline{Endpoint: srv.URL, Cache: p.Cache, Offline: true, AuditLog: &log}
+	if err := offline.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	entries = auditEntries(t, &log)
+	if len(entries) != 1 {
+		t.Fatalf("got %d audit entries for the replay, want 1", len(entries))
+	}
+	hit := entries[0]
+	if hit.Cache != "hit" {
+		t.Errorf("got %s, want a hit", hit.Cache)
+	}
+	if hit.Endpoint != miss.Endpoint || hit.QueryHash != miss.QueryHash {
+		t.Errorf("got a hit for %s (%s), want %s (%s)", hit.Endpoint, hit.QueryHash, miss.Endpoint, miss.QueryHash)
+	}
+	if hit.Source != p.Cache || hit.Bytes == 0 {
+		t.Errorf("got a hit of %d bytes from %q, want the cache %q", hit.Bytes, hit.Source, p.Cache)
+	}
+}
+
+// TestAuditReplay checks that replaying results that are not known to
+// answer the harvest query is recorded without an endpoint or query.
+func TestAuditReplay(t *testing.T) {
+	body := demoBody(t)
+	srv := newTestEndpoint(t, func(int32) []byte { return body })
+	p := newTestPipeline(t, srv.URL)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	replay := newTestPipeline(t, srv.URL)
+	replay.Input = p.Cache
+	replay.AuditLog = &log
+	if err := replay.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	entries := auditEntries(t, &log)
+	if len(entries) != 1 {
+		t.Fatalf("got %d audit entries, want 1", len(entries))
+	}
+	if hit := entries[0]; hit.Cache != "hit" || hit.Source != p.Cache || hit.Endpoint != "" || hit.QueryHash != "" {
+		t.Errorf("got %+v, want a hit from %s without an endpoint or query", hit, p.Cache)
+	}
+}
@@ -0,0 +1,84 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+)
+
+// A baseline is the records export of a previous run, in JSON or NDJSON.
+// Systems that ingest exports automatically only need the records that
+// have been added or modified since they last did so, so exports can be
+// limited to the records that differ from a baseline.
+
+// Baseline describes the records of a previous export.
+type Baseline struct {
+	records  map[string][]byte   // Record ID to its JSON encoding.
+	wikidata map[string]Wikidata // Record ID to the record.
+}
+
+// LoadBaseline reads a records export written with FormatJSON or
+// FormatNDJSON.
+func LoadBaseline(path string) (Baseline, error) {
+	baseline := Baseline{records: make(map[string][]byte), wikidata: make(map[string]Wikidata)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return baseline, err
+	}
+	var records []Wikidata
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return baseline, err
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var wd Wikidata
+			err := decoder.Decode(&wd)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return baseline, err
+			}
+			records = append(records, wd)
+		}
+	}
+	for _, wd := range records {
+		// Records are encoded again so that they compare equal to the
+		// records of this run regardless of how the export was formatted.
+		for i := range wd.Signatures {
+			wd.Signatures[i].Normalized = canonicalHex(wd.Signatures[i].Normalized)
+		}
+		encoded, err := json.Marshal(wd)
+		if err != nil {
+			return baseline, err
+		}
+		baseline.records[wd.ID] = encoded
+		baseline.wikidata[wd.ID] = wd
+	}
+	return baseline, nil
+}
+
+// changed returns the records that are not in the baseline or differ from
+// it, and the number of baseline records that are no longer present.
+func (b Baseline) changed(records []Wikidata) ([]Wikidata, int, error) {
+	changed := []Wikidata{}
+	present := 0
+	for _, wd := range records {
+		previous, ok := b.records[wd.ID]
+		if ok {
+			present++
+		}
+		encoded, err := json.Marshal(wd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok || !bytes.Equal(previous, encoded) {
+			changed = append(changed, wd)
+		}
+	}
+	return changed, len(b.records) - present, nil
+}
@@ -0,0 +1,61 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadBaseline checks that baselines are read from JSON and NDJSON
+// exports, however their sequences were written, and compare equal to the
+// records they were exported from.
+func TestLoadBaseline(t *testing.T) {
+	records := []Wikidata{
+		{ID: "Q2192", Name: "GIF", Signatures: []Signature{{Signature: "GIF89a", Normalized: "474946383961"}}},
+		{ID: "Q2195", Name: "JPEG", Signatures: []Signature{{Signature: "FFD8FF", Normalized: "FFD8FF"}}},
+	}
+	spaced := styleRecords(records, HexSpaced)
+	var ndjson []string
+	for _, wd := range spaced {
+		line, _ := json.Marshal(wd)
+		ndjson = append(ndjson, string(line))
+	}
+	array, _ := json.MarshalIndent(records, "", "  ")
+	tests := []struct {
+		name string
+		data string
+		err  bool
+	}{
+		{"json", string(array), false},
+		{"spaced ndjson", strings.Join(ndjson, "\n") + "\n", false},
+		{"spaced ndjson without a final newline", strings.Join(ndjson, "\n"), false},
+		{"truncated json", string(array[:len(array)/2]), true},
+		{"truncated ndjson", ndjson[0] + "\n" + ndjson[1][:10], true},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "baseline.json")
+		if err := ioutil.WriteFile(path, []byte(test.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		baseline, err := LoadBaseline(path)
+		if (err != nil) != test.err {
+			t.Errorf("%s: got error %v, want error %t", test.name, err, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		changed, removed, err := baseline.changed(records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changed) != 0 || removed != 0 {
+			t.Errorf("%s: got %d records changed and %d removed, want none", test.name, len(changed), removed)
+		}
+	}
+	if _, err := LoadBaseline(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("got no error for a missing baseline")
+	}
+}
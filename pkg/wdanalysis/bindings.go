@@ -0,0 +1,151 @@
+package wdanalysis
+
+import (
+	"sort"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Rows of the harvest query are read through the accessors of binding so
+// that the name of each variable is used in one place, and renaming one in
+// the query is a single edit. OPTIONAL variables are often unbound, which
+// an accessor cannot tell from a variable that has been renamed, so every
+// row is checked for the variables the accessors read and those it leaves
+// unbound are counted in the summary. A variable unbound in every row has
+// most likely been renamed or dropped from the query.
+
+// Variables of the harvest query not declared alongside the code that
+// interprets them.
+const (
+	labelField           = "formatLabel"
+	sigField             = "sig"
+	propertyField        = "sigProperty"
+	referenceField       = "reference"
+	referenceLabelField  = "referenceLabel"
+	dateField            = "date"
+	encodingField        = "encodingLabel"
+	offsetField          = "offset"
+	relativityField      = "relativity"
+	relativityLabelField = "relativityLabel"
+	partField            = "partLabel"
+)
+
+// bindingFields are the variables read by the accessors of binding.
+var bindingFields = []string{
+	formatField,
+	labelField,
+	puidField,
+	locField,
+	extField,
+	mimeField,
+	versionField,
+	modifiedField,
+	snakField,
+	sigField,
+	propertyField,
+	referenceField,
+	referenceLabelField,
+	dateField,
+	encodingField,
+	offsetField,
+	relativityField,
+	relativityLabelField,
+	partField,
+	statementField,
+	rankField,
+}
+
+// binding is a row of the results of the harvest query.
+type binding map[string]spargo.Item
+
+// URI returns the IRI of the format.
+func (b binding) URI() string { return b[formatField].Value }
+
+// Label returns the name of the format.
+func (b binding) Label() string { return b[labelField].Value }
+
+// PUID returns a PRONOM ID of the format.
+func (b binding) PUID() string { return b[puidField].Value }
+
+// LDD returns a Library of Congress format description ID of the format.
+func (b binding) LDD() string { return b[locField].Value }
+
+// Extension returns a file extension of the format.
+func (b binding) Extension() string { return b[extField].Value }
+
+// Mimetype returns a MIME type of the format.
+func (b binding) Mimetype() string { return b[mimeField].Value }
+
+// Version returns a version of the format.
+func (b binding) Version() string { return b[versionField].Value }
+
+// Modified returns the date the item of the format was last edited.
+func (b binding) Modified() string { return b[modifiedField].Value }
+
+// Snak returns NoValue if Wikidata asserts the format has no signature.
+func (b binding) Snak() string { return b[snakField].Value }
+
+// Signature returns the value of the signature.
+func (b binding) Signature() string { return b[sigField].Value }
+
+// SomeValue returns true if the signature is a somevalue snak.
+func (b binding) SomeValue() bool { return isSomeValue(b[sigField]) }
+
+// Property returns the property that supplied the signature, e.g. P4152.
+func (b binding) Property() string { return b[propertyField].Value }
+
+// Reference returns the IRI of the item the signature was sourced from.
+func (b binding) Reference() string { return b[referenceField].Value }
+
+// ReferenceLabel returns the name of the source of the signature.
+func (b binding) ReferenceLabel() string { return b[referenceLabelField].Value }
+
+// Date returns the date the signature was retrieved from its source.
+func (b binding) Date() string { return b[dateField].Value }
+
+// Encoding returns the name of the encoding of the signature.
+func (b binding) Encoding() string { return b[encodingField].Value }
+
+// OffsetRaw returns the offset of the signature as it is recorded.
+func (b binding) OffsetRaw() string { return b[offsetField].Value }
+
+// Relativity returns the IRI of the relativity of the offset.
+func (b binding) Relativity() string { return b[relativityField].Value }
+
+// RelativityLabel returns the name of the relativity of the offset.
+func (b binding) RelativityLabel() string { return b[relativityLabelField].Value }
+
+// Scope returns the name of the part of the format the signature is
+// scoped to.
+func (b binding) Scope() string { return b[partField].Value }
+
+// Statement returns the IRI of the signature statement.
+func (b binding) Statement() string { return b[statementField].Value }
+
+// Rank returns the IRI of the rank of the signature statement.
+func (b binding) Rank() string { return b[rankField].Value }
+
+// unbound adds the variables read by the accessors that a row leaves
+// unbound to the summary.
+func (summary *Summary) unbound(b binding) {
+	if summary.Unbound == nil {
+		summary.Unbound = make(map[string]int)
+	}
+	for _, field := range bindingFields {
+		if _, ok := b[field]; !ok {
+			summary.Unbound[field]++
+		}
+	}
+}
+
+// alwaysUnbound returns the variables that no row of the results bound.
+func (summary *Summary) alwaysUnbound() []string {
+	var fields []string
+	for field, rows := range summary.Unbound {
+		if rows == summary.AllSparqlResults {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,134 @@
+package wdanalysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Harvests are cached by the endpoint and the query they were run with so
+// that the results of one configuration are never replayed for another.
+// The query includes the property map, the languages of its labels, and
+// when a harvest is incremental the date it starts from.
+// Each entry is the SPARQL response alongside a small description of it
+// used to list the cache.
+
+// cacheSuffix and metaSuffix are the extensions of a cached response and
+// its description.
+const (
+	cacheSuffix = ".json"
+	metaSuffix  = ".meta.json"
+)
+
+// CacheEntry describes a cached harvest.
+type CacheEntry struct {
+	Key       string    `json:"key"`
+	Endpoint  string    `json:"endpoint"`
+	QueryHash string    `json:"queryHash"`
+	Harvested time.Time `json:"harvested"`
+	Results   int       `json:"results"`
+	Size      int64     `json:"size"`
+	Path      string    `json:"path"`
+}
+
+// CacheDir returns the directory harvests are cached in. The user's cache
+// directory is used where there is one.
+func CacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "wdanalysis"
+	}
+	return filepath.Join(dir, "wdanalysis")
+}
+
+// CacheKey returns the key harvests of a query against an endpoint are
+// cached under.
+func CacheKey(endpoint string, query string) string {
+	sum := sha256.Sum256([]byte(endpoint + "\n" + query))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachePath returns where the pipeline's harvest is cached.
+func (p *Pipeline) cachePath() string {
+	key := CacheKey(p.Endpoint, p.harvestQuery())
+	return filepath.Join(CacheDir(), key+cacheSuffix)
+}
+
+// writeCacheEntry describes a cached harvest alongside it.
+func (p *Pipeline) writeCacheEntry(results int, harvested time.Time) error {
+	query := p.harvestQuery()
+	sum := sha256.Sum256([]byte(query))
+	entry := CacheEntry{
+		Key:       strings.TrimSuffix(filepath.Base(p.Cache), cacheSuffix),
+		Endpoint:  p.Endpoint,
+		QueryHash: hex.EncodeToString(sum[:]),
+		Harvested: harvested.UTC(),
+		Results:   results,
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(strings.TrimSuffix(p.Cache, cacheSuffix)+metaSuffix, data)
+}
+
+// ListCache returns the harvests cached in dir, the most recent first.
+func ListCache(dir string) ([]CacheEntry, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []CacheEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []CacheEntry{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), metaSuffix) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var entry CacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entry.Path = filepath.Join(dir, strings.TrimSuffix(file.Name(), metaSuffix)+cacheSuffix)
+		info, err := os.Stat(entry.Path)
+		if err != nil {
+			continue
+		}
+		entry.Size = info.Size()
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Harvested.After(entries[j].Harvested)
+	})
+	return entries, nil
+}
+
+// ClearCache removes the harvests cached in dir and returns how many were
+// removed.
+func ClearCache(dir string) (int, error) {
+	entries, err := ListCache(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		meta := strings.TrimSuffix(entry.Path, cacheSuffix) + metaSuffix
+		if err := os.Remove(meta); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,84 @@
+package wdanalysis
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The same sequence is recorded in Wikidata, and in the files we compare
+// with, in many textual styles, e.g. "ff d8 ff" and "FFD8FF". Sequences are
+// held internally in one canonical form, uppercase and continuous, so that
+// dedupe, keys and diffs never see spurious differences. The style is only
+// applied when sequences are written out, and is undone when an export is
+// read back as a baseline.
+
+// Styles hexadecimal sequences can be written in.
+const (
+	HexContinuous = "continuous" // e.g. FFD8FF
+	HexSpaced     = "spaced"     // e.g. FF D8 FF
+)
+
+// HexStyles are the hexadecimal styles we support.
+var HexStyles = []string{HexContinuous, HexSpaced}
+
+// canonicalPattern returns a PRONOM style pattern with its hexadecimal
+// uppercased and whitespace removed. Quoted ASCII is kept as recorded.
+func canonicalPattern(pattern string) string {
+	var out strings.Builder
+	quoted := false
+	for _, r := range strings.TrimSpace(pattern) {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case unicode.IsSpace(r):
+			continue
+		default:
+			r = unicode.ToUpper(r)
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// canonicalHex returns a sequence written in any HexStyle in its canonical
+// form. Sequences that are not plain hexadecimal are returned unchanged.
+func canonicalHex(sequence string) string {
+	if !isHex(sequence) || strings.HasPrefix(sequence, "0x") || strings.HasPrefix(sequence, "0X") {
+		return sequence
+	}
+	return strings.ToUpper(strings.Replace(sequence, " ", "", -1))
+}
+
+// formatHex returns a canonical sequence written in a HexStyle. Only plain
+// hexadecimal is spaced, PRONOM syntax is returned unchanged.
+func formatHex(sequence string, style string) string {
+	if style != HexSpaced || !isHex(sequence) || len(sequence)%2 != 0 {
+		return sequence
+	}
+	bytes := make([]string, 0, len(sequence)/2)
+	for i := 0; i < len(sequence); i += 2 {
+		bytes = append(bytes, sequence[i:i+2])
+	}
+	return strings.Join(bytes, " ")
+}
+
+// styleRecords returns the records with their sequences written in a
+// HexStyle. The signatures are copied so the processed records keep the
+// canonical form.
+func styleRecords(records []Wikidata, style string) []Wikidata {
+	if style != HexSpaced {
+		return records
+	}
+	styled := make([]Wikidata, 0, len(records))
+	for _, wd := range records {
+		var signatures []Signature
+		for _, signature := range wd.Signatures {
+			signature.Normalized = formatHex(signature.Normalized, style)
+			signatures = append(signatures, signature)
+		}
+		wd.Signatures = signatures
+		styled = append(styled, wd)
+	}
+	return styled
+}
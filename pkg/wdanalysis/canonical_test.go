@@ -0,0 +1,44 @@
+package wdanalysis
+
+import "testing"
+
+// TestCanonicalHex checks that only plain hexadecimal is rewritten in the
+// canonical form.
+func TestCanonicalHex(t *testing.T) {
+	tests := []struct {
+		sequence string
+		want     string
+	}{
+		{"89504E47", "89504E47"},
+		{"89504e47", "89504E47"},
+		{"89 50 4e 47", "89504E47"},
+		{"0x89504E47", "0x89504E47"},
+		{"{4}ffd8[00:1f]", "{4}ffd8[00:1f]"},
+		{"'GIF'", "'GIF'"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := canonicalHex(test.sequence); got != test.want {
+			t.Errorf("canonicalHex(%q) = %q, want %q", test.sequence, got, test.want)
+		}
+	}
+}
+
+// TestFormatHex checks that only plain hexadecimal is spaced.
+func TestFormatHex(t *testing.T) {
+	tests := []struct {
+		sequence string
+		style    string
+		want     string
+	}{
+		{"89504E47", HexSpaced, "89 50 4E 47"},
+		{"89504E47", HexContinuous, "89504E47"},
+		{"89504E4", HexSpaced, "89504E4"},
+		{"{4}FFD8", HexSpaced, "{4}FFD8"},
+	}
+	for _, test := range tests {
+		if got := formatHex(test.sequence, test.style); got != test.want {
+			t.Errorf("formatHex(%q, %s) = %q, want %q", test.sequence, test.style, got, test.want)
+		}
+	}
+}
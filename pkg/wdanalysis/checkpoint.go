@@ -0,0 +1,151 @@
+package wdanalysis
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Long paged harvests can fail part-way through. Every page received is
+// appended to a checkpoint alongside the cached results so that a later
+// run resuming the harvest only requests the pages that are missing. The
+// checkpoint is removed once the harvest is complete. The checkpoint is
+// only resumed if it was written for the same endpoint, query, and page
+// size. A page that was only partly written when the harvest failed is
+// requested again.
+
+// checkpointSuffix is the extension of a checkpoint.
+const checkpointSuffix = ".checkpoint.ndjson"
+
+// checkpointHeader is the first line of a checkpoint and describes the
+// harvest it belongs to.
+type checkpointHeader struct {
+	Endpoint    string `json:"endpoint"`
+	QuerySHA256 string `json:"querySHA256"`
+	PageSize    int    `json:"pageSize"`
+}
+
+// checkpointPage is a page of results in a checkpoint.
+type checkpointPage struct {
+	Page     int                      `json:"page"`
+	Head     map[string]interface{}   `json:"head"`
+	Bindings []map[string]spargo.Item `json:"bindings"`
+}
+
+// checkpoint appends the pages of a harvest to a file.
+type checkpoint struct {
+	path string
+	file *os.File
+}
+
+// checkpointPath returns the path of the checkpoint of the pipeline's
+// harvest.
+func (p *Pipeline) checkpointPath() string {
+	return strings.TrimSuffix(p.Cache, cacheSuffix) + checkpointSuffix
+}
+
+// newCheckpointHeader describes the harvest of query.
+func (p *Pipeline) newCheckpointHeader(query string) checkpointHeader {
+	return checkpointHeader{
+		Endpoint:    p.Endpoint,
+		QuerySHA256: fmt.Sprintf("%x", sha256.Sum256([]byte(query))),
+		PageSize:    p.PageSize,
+	}
+}
+
+// readCheckpoint returns the pages of the harvest of query recorded in
+// the pipeline's checkpoint. No pages are returned if there is no
+// checkpoint or it belongs to a different harvest.
+func (p *Pipeline) readCheckpoint(query string) ([]checkpointPage, error) {
+	f, err := os.Open(p.checkpointPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, nil
+	}
+	var header checkpointHeader
+	if err := json.Unmarshal(line, &header); err != nil || header != p.newCheckpointHeader(query) {
+		return nil, nil
+	}
+	var pages []checkpointPage
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A line without a newline was not completely written.
+			return pages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var page checkpointPage
+		if err := json.Unmarshal(line, &page); err != nil || page.Page != len(pages) {
+			return pages, nil
+		}
+		pages = append(pages, page)
+	}
+}
+
+// openCheckpoint opens the pipeline's checkpoint to append the pages of
+// the harvest of query to. Unless resuming, any previous checkpoint is
+// replaced.
+func (p *Pipeline) openCheckpoint(query string, pages []checkpointPage) (*checkpoint, error) {
+	cp := &checkpoint{path: p.checkpointPath()}
+	file, err := createFile(cp.path)
+	if err != nil {
+		return nil, err
+	}
+	cp.file = file
+	if err := cp.write(p.newCheckpointHeader(query)); err != nil {
+		cp.close()
+		return nil, err
+	}
+	// Pages are rewritten so that a partly written page is not left in
+	// the middle of the checkpoint.
+	for _, page := range pages {
+		if err := cp.write(page); err != nil {
+			cp.close()
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+// write appends a line to the checkpoint.
+func (cp *checkpoint) write(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cp.file, "%s\n", line)
+	return err
+}
+
+// close closes the checkpoint.
+func (cp *checkpoint) close() error {
+	if cp == nil {
+		return nil
+	}
+	return cp.file.Close()
+}
+
+// remove closes and removes the checkpoint once it is no longer needed.
+func (cp *checkpoint) remove() error {
+	if cp == nil {
+		return nil
+	}
+	cp.file.Close()
+	return os.Remove(cp.path)
+}
@@ -0,0 +1,220 @@
+package wdanalysis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// checkpointPageSize is the page size of the harvests in these tests. The
+// synthetic results of three formats are nine rows, harvested in pages of
+// four, four, and one.
+const checkpointPageSize = 4
+
+// pageOffset reads the offset of a page query.
+var pageOffset = regexp.MustCompile(`OFFSET (\d+)\s*$`)
+
+// pagedEndpoint answers page queries with the rows at their offset and
+// records the offset of every page requested.
+type pagedEndpoint struct {
+	*httptest.Server
+	mu      sync.Mutex
+	offsets []int
+	// onRequest is called before each page is answered if set, with mu
+	// held.
+	onRequest func()
+}
+
+// newPagedEndpoint returns an endpoint serving rows a page at a time.
+func newPagedEndpoint(t *testing.T, rows []map[string]spargo.Item) *pagedEndpoint {
+	t.Helper()
+	e := &pagedEndpoint{}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match := pageOffset.FindStringSubmatch(r.FormValue("query"))
+		if match == nil {
+			http.Error(w, "not a page query", http.StatusBadRequest)
+			return
+		}
+		offset, _ := strconv.Atoi(match[1])
+		e.mu.Lock()
+		e.offsets = append(e.offsets, offset)
+		if e.onRequest != nil {
+			e.onRequest()
+		}
+		e.mu.Unlock()
+		var res spargo.SPARQLResult
+		res.Head = map[string]interface{}{"vars": []string{"format"}}
+		if offset < len(rows) {
+			end := offset + checkpointPageSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			res.Results.Bindings = rows[offset:end]
+		}
+		body, err := sparqlJSON(res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/sparql-results+json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// requested returns the offsets of the pages requested so far.
+func (e *pagedEndpoint) requested() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]int(nil), e.offsets...)
+}
+
+// newPagedPipeline returns a pipeline harvesting from endpoint in pages
+// without a delay between them.
+func newPagedPipeline(t *testing.T, endpoint string, cache string) *Pipeline {
+	t.Helper()
+	p := &Pipeline{
+		Endpoint: endpoint,
+		Cache:    cache,
+		PageSize: checkpointPageSize,
+	}
+	p.Config.Network.PageDelay = "0s"
+	return p
+}
+
+// harvestCheckpointed harvests the pipeline's query in pages.
+func harvestCheckpointed(t *testing.T, p *Pipeline) spargo.SPARQLResult {
+	t.Helper()
+	if err := p.init(); err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.harvestPages(context.Background(), p.harvestQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// writeCheckpoint writes a checkpoint of the first pages of rows for the
+// harvest of query by p, followed by a page that was only partly written.
+func writeCheckpoint(t *testing.T, p *Pipeline, query string, rows []map[string]spargo.Item, pages int) {
+	t.Helper()
+	cp, err := p.openCheckpoint(query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for page := 0; page < pages; page++ {
+		bindings := rows[page*checkpointPageSize : (page+1)*checkpointPageSize]
+		if err := cp.write(checkpointPage{Page: page, Bindings: bindings}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cp.file.WriteString(`{"page":` + strconv.Itoa(pages) + `,"bindings":[`); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCheckpoint checks that every page received is recorded in the
+// checkpoint and that the checkpoint is removed once the harvest is
+// complete.
+func TestCheckpoint(t *testing.T) {
+	rows := syntheticResults(3)
+	endpoint := newPagedEndpoint(t, rows)
+	p := newPagedPipeline(t, endpoint.URL, filepath.Join(t.TempDir(), "results.json"))
+	var checkpointed []int
+	endpoint.onRequest = func() {
+		pages, err := p.readCheckpoint(p.harvestQuery())
+		if err != nil {
+			t.Error(err)
+		}
+		checkpointed = append(checkpointed, len(pages))
+	}
+	res := harvestCheckpointed(t, p)
+	if got := len(res.Results.Bindings); got != len(rows) {
+		t.Errorf("got %d results, want %d", got, len(rows))
+	}
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(checkpointed, want) {
+		t.Errorf("got %v pages in the checkpoint at each request, want %v", checkpointed, want)
+	}
+	if _, err := os.Stat(p.checkpointPath()); !os.IsNotExist(err) {
+		t.Errorf("checkpoint was not removed once the harvest was complete: %v", err)
+	}
+}
+
+// TestResumeCheckpoint checks that a resumed harvest only requests the
+// pages missing from its checkpoint, including a page that was only partly
+// written, and that the results are complete.
+func TestResumeCheckpoint(t *testing.T) {
+	rows := syntheticResults(3)
+	endpoint := newPagedEndpoint(t, rows)
+	p := newPagedPipeline(t, endpoint.URL, filepath.Join(t.TempDir(), "results.json"))
+	p.Resume = true
+	if err := p.init(); err != nil {
+		t.Fatal(err)
+	}
+	writeCheckpoint(t, p, p.harvestQuery(), rows, 1)
+	res := harvestCheckpointed(t, p)
+	if want := []int{4, 8}; !reflect.DeepEqual(endpoint.requested(), want) {
+		t.Errorf("requested pages at offsets %v, want %v", endpoint.requested(), want)
+	}
+	if !reflect.DeepEqual(res.Results.Bindings, rows) {
+		t.Errorf("got %d results, want the %d rows in order", len(res.Results.Bindings), len(rows))
+	}
+	if _, err := os.Stat(p.checkpointPath()); !os.IsNotExist(err) {
+		t.Errorf("checkpoint was not removed once the harvest was complete: %v", err)
+	}
+}
+
+// TestCheckpointMismatch checks that a checkpoint written for a different
+// query or page size is not resumed.
+func TestCheckpointMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		pageSize int
+	}{
+		{"different query", "SELECT ?format WHERE { ?format ?p ?o }", checkpointPageSize},
+		{"different page size", "", checkpointPageSize + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := syntheticResults(3)
+			endpoint := newPagedEndpoint(t, rows)
+			cache := filepath.Join(t.TempDir(), "results.json")
+			p := newPagedPipeline(t, endpoint.URL, cache)
+			p.Resume = true
+			if err := p.init(); err != nil {
+				t.Fatal(err)
+			}
+			query := p.harvestQuery()
+			if tt.query != "" {
+				query = tt.query
+			}
+			previous := newPagedPipeline(t, endpoint.URL, cache)
+			previous.PageSize = tt.pageSize
+			writeCheckpoint(t, previous, query, rows, 2)
+			res := harvestCheckpointed(t, p)
+			if want := []int{0, 4, 8}; !reflect.DeepEqual(endpoint.requested(), want) {
+				t.Errorf("requested pages at offsets %v, want %v", endpoint.requested(), want)
+			}
+			if got := len(res.Results.Bindings); got != len(rows) {
+				t.Errorf("got %d results, want %d", got, len(rows))
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package wdanalysis
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Spreadsheets and some loaders cannot open exports of several hundred
+// thousand rows. When a TSV or CSV export has more rows than the pipeline's
+// ChunkRows it is split into numbered part files, each starting with the
+// header row, and a manifest listing the parts is written in place of the
+// single file.
+
+// partsSuffix is the suffix of the manifest of an export split into parts.
+const partsSuffix = ".parts.json"
+
+// Part describes one part of an export split into parts.
+type Part struct {
+	Path   string `json:"path"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// Parts describes an export split into parts.
+type Parts struct {
+	Export   string   `json:"export"`
+	Rows     int      `json:"rows"`
+	Parts    []Part   `json:"parts"`
+	Manifest Manifest `json:"manifest"`
+}
+
+// isTabular returns true if an export is a table with a header row that
+// can be split into parts.
+func isTabular(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".csv":
+		return true
+	}
+	return false
+}
+
+// partPath returns the path of a numbered part of an export, e.g.
+// registry.part001.tsv.
+func partPath(path string, part int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.part%03d%s", strings.TrimSuffix(path, ext), part, ext)
+}
+
+// partsPath returns the path of the manifest of an export split into parts,
+// e.g. registry.parts.json.
+func partsPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + partsSuffix
+}
+
+// tableRows splits the content of a tabular export into its header and
+// rows. Each keeps its line ending.
+func tableRows(content []byte) (string, []string) {
+	lines := strings.SplitAfter(string(content), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return lines[0], lines[1:]
+}
+
+// writeParts writes a tabular export as parts of at most size rows along
+// with a manifest of the parts. Any export previously written to path as a
+// single file is removed so that it cannot be mistaken for the current one.
+func (p *Pipeline) writeParts(path string, header string, rows []string, size int) error {
+	parts := Parts{Export: filepath.Base(path), Rows: len(rows), Manifest: p.manifest}
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		content := []byte(header + strings.Join(rows[start:end], ""))
+		part := partPath(path, len(parts.Parts)+1)
+		if err := p.writeSigned(part, content); err != nil {
+			return err
+		}
+		parts.Parts = append(parts.Parts, Part{
+			Path:   filepath.Base(part),
+			Rows:   end - start,
+			SHA256: fmt.Sprintf("%x", sha256.Sum256(p.textPolicy(part, content))),
+		})
+	}
+	out, err := json.MarshalIndent(parts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := p.writeSigned(partsPath(path), out); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	p.Logger.Printf("%s has %d rows and is written in %d parts", path, len(rows), len(parts.Parts))
+	return nil
+}
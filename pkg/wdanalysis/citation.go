@@ -0,0 +1,59 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Identifiers built from our exports want to show their users why a
+// signature is believed to identify a format. Every signature carries a
+// citation: the item it was sourced from, when that source was retrieved,
+// and a link to where it is stated in Wikidata. Citations have the same
+// shape in every export. The link is to the signature's statement where it
+// is known, otherwise to the statements of its property in the record.
+
+// Citation describes where a signature comes from.
+type Citation struct {
+	Source      string `json:"source"`      // ID of the item the signature was sourced from.
+	SourceLabel string `json:"sourceLabel"` // Label of the item the signature was sourced from.
+	Retrieved   string `json:"retrieved"`   // Date the source was retrieved.
+	Statement   string `json:"statement"`   // Link to the statement of the signature in its record.
+}
+
+// statementLink returns a link to a statement, or the statements of a
+// property, on the page of the entity at uri, e.g.
+// https://www.wikidata.org/wiki/Q12#P4152.
+func statementLink(uri string, anchor string) string {
+	page := strings.Replace(uri, "/entity/", "/wiki/", 1)
+	if strings.HasPrefix(page, "http://") {
+		page = "https://" + strings.TrimPrefix(page, "http://")
+	}
+	return fmt.Sprintf("%s#%s", page, anchor)
+}
+
+// newCitation returns the citation of the signature in a row of results.
+func (p *Pipeline) newCitation(row binding) Citation {
+	// Wikidata anchors each statement on the page of its item by its ID.
+	anchor := statementID(row.Statement())
+	if anchor == "" {
+		anchor = row.Property()
+	}
+	if anchor == "" {
+		anchor = p.Config.Properties.Signature
+	}
+	citation := Citation{
+		SourceLabel: row.ReferenceLabel(),
+		Retrieved:   row.Date(),
+		Statement:   statementLink(row.URI(), anchor),
+	}
+	if reference := row.Reference(); reference != "" {
+		citation.Source = getID(reference)
+	}
+	return citation
+}
+
+// citationCell returns a citation as a TSV cell in the form
+// source;retrieved;statement.
+func citationCell(citation Citation) string {
+	return refineCell(strings.Join([]string{citation.Source, citation.Retrieved, citation.Statement}, ";"))
+}
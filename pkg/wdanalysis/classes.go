@@ -0,0 +1,107 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Formats are harvested as instances of any subclass of file format, e.g.
+// raster image format or archive format. Counting the records harvested
+// through each class they are an instance of validates the traversal of
+// the class tree, and counting those with signatures shows where coverage
+// is thin. A record that is an instance of several classes is counted for
+// each. The classes are not needed to build records so they are harvested
+// with a dedicated query.
+
+var classesQuery = `
+	SELECT DISTINCT ?format ?class ?classLabel WHERE
+	{
+	  ?format wdt:<<instanceOf>> ?class.
+	  ?class wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	  SERVICE wikibase:label { bd:serviceParam wikibase:language "[AUTO_LANGUAGE], en". }
+	}
+	order by ?class
+`
+
+// ClassStats describes the records harvested through a class.
+type ClassStats struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	URI            string  `json:"uri"`
+	Formats        int     `json:"formats"`        // Records that are instances of the class.
+	WithSignatures int     `json:"withSignatures"` // Records with at least one signature.
+	Coverage       float64 `json:"coverage"`       // Proportion of records with a signature.
+}
+
+// classStats counts the harvested records that are instances of each class,
+// most records first. Rows for items that were not harvested, e.g. when
+// the harvest was restricted, are ignored.
+func (p *Pipeline) classStats(results []map[string]spargo.Item) []ClassStats {
+	classes := make(map[string]*ClassStats)
+	counted := make(map[string]bool)
+	for _, row := range results {
+		wd, ok := p.records[p.id(row[formatField].Value)]
+		if !ok {
+			continue
+		}
+		uri := row["class"].Value
+		if classes[uri] == nil {
+			classes[uri] = &ClassStats{ID: p.id(uri), Name: row["classLabel"].Value, URI: uri}
+		}
+		if key := uri + "\x00" + wd.ID; !counted[key] {
+			counted[key] = true
+			classes[uri].Formats++
+			if len(wd.Signatures) > 0 {
+				classes[uri].WithSignatures++
+			}
+		}
+	}
+	stats := []ClassStats{}
+	for _, class := range classes {
+		class.Coverage = float64(class.WithSignatures) / float64(class.Formats)
+		stats = append(stats, *class)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Formats != stats[j].Formats {
+			return stats[i].Formats > stats[j].Formats
+		}
+		return stats[i].ID < stats[j].ID
+	})
+	return stats
+}
+
+// classesTSV returns the class statistics as TSV with a row for each class.
+func classesTSV(stats []ClassStats) string {
+	var out strings.Builder
+	out.WriteString("id\tname\turi\tformats\twith signatures\tcoverage\n")
+	for _, class := range stats {
+		fmt.Fprintf(&out, "%s\t%s\t%s\t%d\t%d\t%.2f\n",
+			class.ID,
+			refineCell(class.Name),
+			class.URI,
+			class.Formats,
+			class.WithSignatures,
+			class.Coverage,
+		)
+	}
+	return out.String()
+}
+
+// exportClasses writes the class statistics to path. A JSON document is
+// written if the path has a .json extension, otherwise TSV is written.
+func (p *Pipeline) exportClasses(path string, stats []ClassStats) error {
+	out := []byte(classesTSV(stats))
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		report, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = []byte(fmt.Sprintf("%s\n", report))
+	}
+	return p.writeExport(path, out)
+}
@@ -0,0 +1,128 @@
+package wdanalysis
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Other corpora of file format signatures can be compared with the
+// sequences harvested from Wikidata to find the sequences present in one
+// but not the other, and so prioritise adding them where they are missing.
+// Sequences are compared by their normalized pattern and relativity. Offsets
+// are recorded too inconsistently between corpora to compare but are
+// reported alongside.
+
+// externalSequence is a sequence from another corpus.
+type externalSequence struct {
+	Pattern     string
+	Relativity  wikidata.Relativity
+	Offset      string
+	Description string
+	Extensions  []string
+}
+
+// ComparedSequence describes a sequence found in either or both corpora.
+type ComparedSequence struct {
+	Pattern    string   `json:"pattern"`
+	Relativity string   `json:"relativity"`
+	Offsets    []string `json:"offsets"`
+	Wikidata   []string `json:"wikidata,omitempty"`   // Records with the sequence.
+	External   []string `json:"external,omitempty"`   // Descriptions of the formats with the sequence.
+	Extensions []string `json:"extensions,omitempty"` // Extensions of the formats with the sequence.
+}
+
+// Comparison describes the sequences found in both corpora, and in only one
+// or the other.
+type Comparison struct {
+	Both         []ComparedSequence `json:"both"`
+	OnlyWikidata []ComparedSequence `json:"onlyWikidata"`
+	OnlyExternal []ComparedSequence `json:"onlyExternal"`
+}
+
+// normalizeExternal returns the normalized form of a hexadecimal pattern
+// from another corpus. Patterns with wildcards cannot be normalized and
+// are compared in their canonical form.
+func normalizeExternal(pattern string) string {
+	if normalized, err := validateAndReturnSignature(pattern, encodingHex); err == nil {
+		return normalized
+	}
+	return canonicalPattern(pattern)
+}
+
+// comparisonKey identifies a sequence for comparison.
+func comparisonKey(pattern string, relativity wikidata.Relativity) string {
+	if relativity == wikidata.Unknown {
+		relativity = wikidata.BOF
+	}
+	return pattern + "\x00" + relativity.String()
+}
+
+// addUnique adds a value to a list if it is not already there.
+func addUnique(values []string, value string) []string {
+	if value == "" || contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// compareSequences compares the sequences of the processed records with
+// those of another corpus.
+func (p *Pipeline) compareSequences(external []externalSequence) Comparison {
+	sequences := make(map[string]*ComparedSequence)
+	get := func(pattern string, relativity wikidata.Relativity) *ComparedSequence {
+		key := comparisonKey(pattern, relativity)
+		if sequences[key] == nil {
+			if relativity == wikidata.Unknown {
+				relativity = wikidata.BOF
+			}
+			sequences[key] = &ComparedSequence{Pattern: pattern, Relativity: relativity.String()}
+		}
+		return sequences[key]
+	}
+	for _, wd := range p.Records() {
+		for _, signature := range wd.Signatures {
+			if signature.Normalized == "" {
+				continue
+			}
+			sequence := get(signature.Normalized, signature.relativity())
+			sequence.Offsets = addUnique(sequence.Offsets, noneIfEmpty(signature.Offset))
+			sequence.Wikidata = addUnique(sequence.Wikidata, wd.URI)
+		}
+	}
+	for _, ext := range external {
+		if ext.Pattern == "" {
+			continue
+		}
+		sequence := get(ext.Pattern, ext.Relativity)
+		sequence.Offsets = addUnique(sequence.Offsets, noneIfEmpty(ext.Offset))
+		sequence.External = addUnique(sequence.External, ext.Description)
+		for _, extension := range ext.Extensions {
+			sequence.Extensions = addUnique(sequence.Extensions, strings.ToLower(extension))
+		}
+	}
+	var keys []string
+	for key := range sequences {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	comparison := Comparison{
+		Both:         []ComparedSequence{},
+		OnlyWikidata: []ComparedSequence{},
+		OnlyExternal: []ComparedSequence{},
+	}
+	for _, key := range keys {
+		sequence := *sequences[key]
+		sequence.Pattern = formatHex(sequence.Pattern, p.HexStyle)
+		switch {
+		case len(sequence.Wikidata) > 0 && len(sequence.External) > 0:
+			comparison.Both = append(comparison.Both, sequence)
+		case len(sequence.Wikidata) > 0:
+			comparison.OnlyWikidata = append(comparison.OnlyWikidata, sequence)
+		default:
+			comparison.OnlyExternal = append(comparison.OnlyExternal, sequence)
+		}
+	}
+	return comparison
+}
@@ -0,0 +1,119 @@
+package wdanalysis
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Full result sets are tens of megabytes of JSON which compresses well, so
+// responses are requested with gzip or deflate compression. Go would ask
+// for gzip by itself but then hides how many bytes were transferred, so we
+// negotiate the encoding ourselves and count the bytes either side of
+// decompression for the summary.
+
+// acceptEncoding lists the content encodings we can decompress.
+const acceptEncoding = "gzip, deflate"
+
+// Transfer describes the size of the responses the pipeline received.
+type Transfer struct {
+	Transferred  int64 `json:"transferred"`  // Bytes received over the network.
+	Decompressed int64 `json:"decompressed"` // Bytes once decompressed.
+}
+
+// newDecoder returns a reader that decompresses r from a content encoding.
+// Servers disagree on whether deflate is wrapped in zlib so both are
+// accepted.
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		buffered := bufio.NewReader(r)
+		header, err := buffered.Peek(2)
+		if err != nil {
+			return nil, err
+		}
+		if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r     io.Reader
+	count *int64
+}
+
+// Read satisfies the io.Reader interface.
+func (c countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	atomic.AddInt64(c.count, int64(n))
+	return n, err
+}
+
+// decodedBody decompresses a response body as it is read. The decoder is
+// created on the first read as it reads the start of the body.
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	transfer *Transfer
+	decoder  io.Reader
+}
+
+// Read satisfies the io.Reader interface.
+func (d *decodedBody) Read(b []byte) (int, error) {
+	if d.decoder == nil {
+		raw := countingReader{r: d.body, count: &d.transfer.Transferred}
+		decoder, err := newDecoder(d.encoding, raw)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decompress response: %s", err)
+		}
+		d.decoder = countingReader{r: decoder, count: &d.transfer.Decompressed}
+	}
+	return d.decoder.Read(b)
+}
+
+// Close satisfies the io.Closer interface.
+func (d *decodedBody) Close() error {
+	return d.body.Close()
+}
+
+// compressTransport requests compressed responses and decompresses them.
+type compressTransport struct {
+	next     http.RoundTripper
+	transfer *Transfer
+}
+
+// RoundTrip satisfies the http.RoundTripper interface.
+func (t compressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead || resp.ContentLength == 0 {
+		return resp, err
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	resp.Body = &decodedBody{body: resp.Body, encoding: encoding, transfer: t.transfer}
+	if encoding != "" && encoding != "identity" {
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+	return resp, nil
+}
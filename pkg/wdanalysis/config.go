@@ -0,0 +1,78 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Config describes the settings that can be supplied via a JSON
+// configuration file. Settings that are likely to be fixed for
+// an institution, rather than changed from run to run, belong here.
+type Config struct {
+	Network    NetworkConfig `json:"network"`
+	Properties PropertyMap   `json:"properties"`
+	Notify     NotifyConfig  `json:"notify"`
+	Logging    LoggingConfig `json:"logging"`
+	IDs        IDConfig      `json:"ids"`
+
+	// Entities are the entities a Wikibase uses for the relativity of a
+	// signature, by default those of Wikidata.
+	Entities wikidata.Entities `json:"entities"`
+}
+
+// NetworkConfig describes how outbound connections are made.
+type NetworkConfig struct {
+	Proxy              string `json:"proxy"`              // Proxy URL. Overrides HTTP_PROXY and HTTPS_PROXY.
+	CABundle           string `json:"caBundle"`           // PEM file of additional certificate authorities to trust.
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"` // Skip TLS verification. For internal endpoints only.
+	Retries            int    `json:"retries"`            // Times to retry a request that fails transiently. Defaults to 3.
+	Backoff            string `json:"backoff"`            // Delay before the first retry, doubled for each retry. Defaults to 2s.
+	MaxBackoff         string `json:"maxBackoff"`         // Longest delay between retries. Defaults to 1m.
+	Jitter             bool   `json:"jitter"`             // Randomize delays so that clients do not retry in step. Defaults to true.
+	UserAgent          string `json:"userAgent"`          // User-Agent of every request. Defaults to the tool, its version, and Contact.
+	Contact            string `json:"contact"`            // Contact for the operators of an endpoint, e.g. an email address.
+	PageDelay          string `json:"pageDelay"`          // Delay between the pages of a harvest. Defaults to 1s.
+
+	// Headers are added to every request to the endpoint and API, e.g. for
+	// a private Wikibase behind a gateway. BearerToken is sent as an
+	// Authorization header to them too, and can be supplied in the
+	// WDANALYSIS_BEARER_TOKEN environment variable instead.
+	Headers     map[string]string `json:"headers"`
+	BearerToken string            `json:"bearerToken"`
+}
+
+// DefaultConfig returns the configuration used when none is supplied.
+func DefaultConfig() Config {
+	return Config{
+		Network: NetworkConfig{
+			Retries:    defaultRetries,
+			Backoff:    defaultBackoff,
+			MaxBackoff: defaultMaxBackoff,
+			Jitter:     true,
+			PageDelay:  defaultPageDelay,
+		},
+		Properties: defaultProperties,
+		Entities:   wikidata.DefaultEntities,
+	}
+}
+
+// LoadConfig reads the configuration file at path. Settings that are not
+// in the file keep their default values.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// LoggingConfig describes where operational logs are kept and for how long.
+type LoggingConfig struct {
+	Directory  string `json:"directory"`  // Directory for operational logs. No logs are written if empty.
+	Retain     int    `json:"retain"`     // Number of logs to keep. Defaults to 30.
+	MaxAgeDays int    `json:"maxAgeDays"` // Logs older than this are removed. Zero keeps logs of any age.
+}
@@ -0,0 +1,129 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Signatures are converted from the encoding they are recorded in to their
+// normalized form. The conversions audit pairs every raw value with its
+// normalized form and notes what was done to it so that conversions can be
+// spot-checked by people.
+
+// Conversion describes the conversion of a single sequence.
+type Conversion struct {
+	ID         string   `json:"id"`
+	URI        string   `json:"uri"`
+	Key        string   `json:"key"`
+	Encoding   string   `json:"encoding"`
+	Raw        string   `json:"raw"`
+	Normalized string   `json:"normalized"`
+	Notes      []string `json:"notes"`
+}
+
+// conversionNotes describes what was done to a raw value to normalize it.
+func conversionNotes(s Signature) []string {
+	if s.conversionErr != nil {
+		return []string{fmt.Sprintf("not converted: %s", s.conversionErr)}
+	}
+	var notes []string
+	switch normalizeEncoding(s.Encoding) {
+	case "":
+		notes = append(notes, "no encoding, assumed hexadecimal")
+		fallthrough
+	case encodingHex:
+		value := s.Signature
+		if strings.Contains(value, " ") {
+			notes = append(notes, "spaces removed")
+			value = strings.Replace(value, " ", "", -1)
+		}
+		if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+			notes = append(notes, "0x prefix removed")
+			value = value[2:]
+		}
+		if value != strings.ToUpper(value) {
+			notes = append(notes, "uppercased")
+		}
+	case encodingASCII:
+		notes = append(notes, "ASCII converted to hexadecimal")
+		if strings.Contains(s.Signature, `\`) {
+			notes = append(notes, "escapes converted to the bytes they name")
+		}
+	case encodingPRONOM:
+		notes = append(notes, "PRONOM syntax kept")
+		if strings.TrimSpace(s.Signature) != s.Signature {
+			notes = append(notes, "surrounding whitespace removed")
+		}
+		if s.Normalized != strings.TrimSpace(s.Signature) {
+			notes = append(notes, "hexadecimal uppercased and spaces removed")
+		}
+	}
+	if len(notes) == 0 {
+		notes = append(notes, "unchanged")
+	}
+	return notes
+}
+
+// conversions returns the conversion of every sequence of the records.
+func conversions(records []Wikidata) []Conversion {
+	audit := []Conversion{}
+	for _, wd := range records {
+		for _, signature := range wd.Signatures {
+			audit = append(audit, Conversion{
+				ID:         wd.ID,
+				URI:        wd.URI,
+				Key:        signature.Key,
+				Encoding:   signature.Encoding,
+				Raw:        signature.Signature,
+				Normalized: signature.Normalized,
+				Notes:      conversionNotes(signature),
+			})
+		}
+	}
+	return audit
+}
+
+// conversionsTSV returns the conversions audit as TSV.
+func conversionsTSV(audit []Conversion) string {
+	var out strings.Builder
+	out.WriteString("id\turi\tkey\tencoding\traw\tnormalized\tnotes\n")
+	for _, conversion := range audit {
+		fmt.Fprintf(&out, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			conversion.ID,
+			conversion.URI,
+			conversion.Key,
+			refineCell(noneIfEmpty(conversion.Encoding)),
+			refineCell(fmt.Sprintf("%q", conversion.Raw)),
+			conversion.Normalized,
+			strings.Join(conversion.Notes, "; "),
+		)
+	}
+	return out.String()
+}
+
+// ConversionsExporter writes the conversions audit to Path. A JSON document
+// is written if the path has a .json extension, otherwise TSV is written.
+// Raw values are quoted in TSV so that whitespace and control characters
+// can be seen. The audit describes the sequences as harvested, before any
+// export policies are applied.
+type ConversionsExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e ConversionsExporter) Export(p *Pipeline) error {
+	audit := conversions(styleRecords(p.Records(), p.HexStyle))
+	out := []byte(conversionsTSV(audit))
+	if !isText(e.Path) {
+		var err error
+		out, err = json.MarshalIndent(audit, "", "  ")
+		if err != nil {
+			return err
+		}
+	}
+	if err := p.writeExport(e.Path, out); err != nil {
+		return fmt.Errorf("cannot write conversions audit: %s", err)
+	}
+	return nil
+}
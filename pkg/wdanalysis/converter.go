@@ -0,0 +1,199 @@
+package wdanalysis
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Signatures in Wikidata are recorded in several encodings. The converter
+// normalizes them to a single form, uppercase hexadecimal, that can be
+// compared and used in identifiers. PRONOM internal signatures are already a
+// form of hexadecimal and are kept as they are once their syntax is
+// checked. GUIDs, e.g. the CLSIDs of OLE2 formats, are converted to the
+// bytes they are written as in a file.
+//
+// Each kind of failure needs a different fix, in Wikidata or in the
+// converter, so each is reported with its own lint code.
+
+// conversionError describes why a signature could not be converted.
+type conversionError struct {
+	code linting // Lint code of the failure.
+	msg  string
+}
+
+// Error satisfies the error interface.
+func (e conversionError) Error() string {
+	return e.msg
+}
+
+// conversionLint returns the lint code of a conversion failure. Failures
+// without a code of their own are reported as heuWDE02.
+func conversionLint(err error) linting {
+	if e, ok := err.(conversionError); ok {
+		return e.code
+	}
+	return heuWDE02
+}
+
+// validateAndReturnSignature validates a signature against its encoding and
+// returns its normalized form.
+func validateAndReturnSignature(value string, encoding string) (string, error) {
+	switch normalizeEncoding(encoding) {
+	case encodingHex, "":
+		signature := strings.Replace(value, " ", "", -1)
+		if strings.HasPrefix(signature, "0x") || strings.HasPrefix(signature, "0X") {
+			signature = signature[2:]
+		}
+		if !isHex(signature) {
+			return "", conversionError{cnvWDE01, fmt.Sprintf("invalid hexadecimal: '%s'", value)}
+		}
+		if len(signature)%2 != 0 {
+			return "", conversionError{cnvWDE02, fmt.Sprintf("odd number of hexadecimal digits: '%s'", value)}
+		}
+		return strings.ToUpper(signature), nil
+	case encodingASCII:
+		return strings.ToUpper(hex.EncodeToString(unescapeASCII(value))), nil
+	case encodingPRONOM:
+		pattern := canonicalPattern(value)
+		if err := validatePattern(pattern); err != nil {
+			return "", err
+		}
+		return pattern, nil
+	case encodingGUID:
+		return guidBytes(value)
+	}
+	return "", fmt.Errorf("unsupported encoding: '%s'", encoding)
+}
+
+// signatureKey identifies a raw signature for the normalization cache.
+type signatureKey struct {
+	value    string
+	encoding string
+}
+
+// signatureResult is the cached result of normalizing a signature.
+type signatureResult struct {
+	signature string
+	err       error
+}
+
+// normalizeSignature returns the normalized form of a signature using the
+// pipeline's cache where we have seen the signature before. The same raw
+// signature is returned in many rows of a SPARQL result so we only
+// normalize each once.
+func (p *Pipeline) normalizeSignature(value string, encoding string) (string, error) {
+	key := signatureKey{value: value, encoding: encoding}
+	if result, ok := p.signatureCache[key]; ok {
+		return result.signature, result.err
+	}
+	signature, err := validateAndReturnSignature(value, encoding)
+	p.signatureCache[key] = signatureResult{signature: signature, err: err}
+	return signature, err
+}
+
+// validatePattern checks that a canonical PRONOM pattern only uses the
+// constructs we support: byte pairs, ?? wildcards, quoted ASCII, * and
+// {n}, {n-m} or {n-*} gaps, [xx:yy] and [!xx] ranges, and (a|b)
+// alternatives.
+func validatePattern(pattern string) error {
+	unsupported := func(construct string) error {
+		return conversionError{cnvWDE03, fmt.Sprintf("unsupported PRONOM construct '%s' in '%s'", construct, pattern)}
+	}
+	if pattern == "" {
+		return unsupported("")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*':
+		case c == '\'':
+			end := strings.IndexByte(pattern[i+1:], '\'')
+			if end < 0 {
+				return unsupported(pattern[i:])
+			}
+			i += end + 1
+		case c == '?':
+			if i+1 >= len(pattern) || pattern[i+1] != '?' {
+				return unsupported(pattern[i:])
+			}
+			i++
+		case c == '{' || c == '[' || c == '(':
+			closing := map[byte]byte{'{': '}', '[': ']', '(': ')'}[c]
+			end := strings.IndexByte(pattern[i:], closing)
+			if end < 0 {
+				return unsupported(pattern[i:])
+			}
+			construct := pattern[i : i+end+1]
+			if !supportedConstruct(construct) {
+				return unsupported(construct)
+			}
+			i += end
+		case isHex(string(c)):
+			run := i
+			for run < len(pattern) && isHex(string(pattern[run])) {
+				run++
+			}
+			if (run-i)%2 != 0 {
+				return conversionError{cnvWDE02, fmt.Sprintf("odd number of hexadecimal digits: '%s'", pattern)}
+			}
+			i = run - 1
+		case c >= 'A' && c <= 'Z' || c >= '0' && c <= '9':
+			return conversionError{cnvWDE01, fmt.Sprintf("invalid hexadecimal: '%s'", pattern)}
+		default:
+			return unsupported(string(c))
+		}
+	}
+	return nil
+}
+
+var (
+	gapPattern   = regexp.MustCompile(`^\{[0-9]+(-([0-9]+|\*))?\}$`)
+	rangePattern = regexp.MustCompile(`^\[(![0-9A-F]{2}|[0-9A-F]{2}:[0-9A-F]{2})\]$`)
+	bytesPattern = regexp.MustCompile(`^([0-9A-F]{2}|'[^']*')+$`)
+)
+
+// supportedConstruct returns true if a bracketed construct of a PRONOM
+// pattern is one we support.
+func supportedConstruct(construct string) bool {
+	switch construct[0] {
+	case '{':
+		return gapPattern.MatchString(construct)
+	case '[':
+		return rangePattern.MatchString(construct)
+	}
+	for _, alternative := range strings.Split(construct[1:len(construct)-1], "|") {
+		if !bytesPattern.MatchString(alternative) {
+			return false
+		}
+	}
+	return true
+}
+
+// guidBytes returns the bytes of a GUID in the order they are written in a
+// file, e.g. {00020906-0000-0000-C000-000000000046} is written as
+// 0609020000000000C000000000000046. The first three groups are little
+// endian.
+func guidBytes(value string) (string, error) {
+	guid := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "{"), "}")
+	groups := strings.Split(guid, "-")
+	lengths := []int{8, 4, 4, 4, 12}
+	if len(groups) != len(lengths) {
+		return "", conversionError{cnvWDE04, fmt.Sprintf("malformed GUID: '%s'", value)}
+	}
+	var out strings.Builder
+	for i, group := range groups {
+		decoded, err := hex.DecodeString(group)
+		if err != nil || len(group) != lengths[i] {
+			return "", conversionError{cnvWDE04, fmt.Sprintf("malformed GUID: '%s'", value)}
+		}
+		if i < 3 {
+			for l, r := 0, len(decoded)-1; l < r; l, r = l+1, r-1 {
+				decoded[l], decoded[r] = decoded[r], decoded[l]
+			}
+		}
+		out.WriteString(strings.ToUpper(hex.EncodeToString(decoded)))
+	}
+	return out.String(), nil
+}
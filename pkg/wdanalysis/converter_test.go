@@ -0,0 +1,38 @@
+package wdanalysis
+
+import "testing"
+
+// TestConverterCases checks the patterns the doctor command checks the
+// converter with.
+func TestConverterCases(t *testing.T) {
+	if err := checkConverter(); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestConversionLints checks that each kind of conversion failure is
+// reported with its own lint code.
+func TestConversionLints(t *testing.T) {
+	tests := []struct {
+		value    string
+		encoding string
+		code     linting
+	}{
+		{"GIF89a", encodingHex, cnvWDE01},
+		{"ABC", encodingHex, cnvWDE02},
+		{"0xFFD", "", cnvWDE02},
+		{"FFD8<00>", encodingPRONOM, cnvWDE03},
+		{"{00020906-0000-0000-C000}", encodingGUID, cnvWDE04},
+		{"image/gif", "mimetype", heuWDE02},
+	}
+	for _, test := range tests {
+		_, err := validateAndReturnSignature(test.value, test.encoding)
+		if err == nil {
+			t.Errorf("'%s' (%s) was converted, want %s", test.value, test.encoding, test.code)
+			continue
+		}
+		if got := conversionLint(err); got != test.code {
+			t.Errorf("'%s' (%s): got %s, want %s", test.value, test.encoding, got, test.code)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// The debug output of -csv is a quick look at records with many signatures
+// and is written without quoting. The CSV export is a table of every
+// sequence with the columns asked for, quoted so that it can be opened in
+// a spreadsheet whatever the values contain. Values of multi-valued fields
+// are joined as they are in the OpenRefine export.
+
+// CSVColumns are the columns that can be selected for the CSV export, in
+// the order they are written by default.
+var CSVColumns = []string{
+	"uri",
+	"name",
+	"puid",
+	"sig",
+	"offset",
+	"relativity",
+	"encoding",
+	"provenance",
+	"date",
+}
+
+// ParseColumns parses a comma separated list of CSV columns. An empty list
+// selects every column.
+func ParseColumns(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return CSVColumns, nil
+	}
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !contains(CSVColumns, column) {
+			return nil, fmt.Errorf("unknown column '%s', expected one of: %v", column, CSVColumns)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// csvCell returns the value of a column for a sequence of a record.
+// Sequences are trimmed to trim characters if trim is greater than zero.
+func csvCell(column string, wd Wikidata, s Signature, trim int) string {
+	switch column {
+	case "uri":
+		return wd.URI
+	case "name":
+		return wd.Name
+	case "puid":
+		return strings.Join(nonEmpty(wd.PRONOM), refineSeparator)
+	case "sig":
+		if trim > 0 && len(s.Signature) > trim {
+			return s.Signature[:trim]
+		}
+		return s.Signature
+	case "offset":
+		return s.Offset
+	case "relativity":
+		return s.Relativity
+	case "encoding":
+		return s.Encoding
+	case "provenance":
+		return s.Provenance
+	case "date":
+		return s.Date
+	}
+	return ""
+}
+
+// CSVExporter writes the columns of every sequence to Path as CSV. Every
+// column is written if Columns is empty.
+type CSVExporter struct {
+	Path    string
+	Columns []string
+	Trim    int
+}
+
+// Export satisfies the Exporter interface.
+func (e CSVExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	columns := e.Columns
+	if len(columns) == 0 {
+		columns = CSVColumns
+	}
+	var out bytes.Buffer
+	w := csv.NewWriter(&out)
+	w.Write(columns)
+	for _, wd := range records {
+		for _, s := range wd.Signatures {
+			row := make([]string, len(columns))
+			for idx, column := range columns {
+				row[idx] = csvCell(column, wd, s, e.Trim)
+			}
+			w.Write(row)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if err := p.writeExport(e.Path, out.Bytes()); err != nil {
+		return fmt.Errorf("cannot write CSV export: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Workshops, and users evaluating the tool behind firewalls that block the
+// query service, need to see what a run produces without harvesting. The
+// demo corpus is a handful of well known formats written as the results
+// of the harvest query, binding every variable it projects. It is
+// simplified from Wikidata and not kept up to date with it, the statements
+// and the items of their sources are made up, and some values are
+// deliberately wrong so that the lint findings and reports have something
+// to show: a contradictory encoding label, missing provenance and
+// relativity, sequences whose statements were not harvested so can only
+// be grouped heuristically, and a format without a signature.
+
+// demoRow is a row of the demo corpus. Empty values are left unbound.
+type demoRow struct {
+	id         string
+	label      string
+	puid       string
+	ldd        string
+	extension  string
+	mimetype   string
+	version    string
+	snak       string
+	property   string
+	sig        string
+	statement  int // Number of the statement of the signature, 0 if not harvested.
+	reference  string
+	source     string
+	date       string
+	encoding   string
+	offset     string
+	relativity string
+	part       string
+}
+
+// demoModified is the date every format of the demo corpus was last edited.
+const demoModified = "2020-01-01T00:00:00Z"
+
+// demoSources are the made up items of the sources of the demo corpus.
+var demoSources = map[string]string{
+	"PRONOM":                               "Q99000001",
+	"Gary Kessler's File Signatures Table": "Q99000002",
+}
+
+// demoRelativities are the entities of the relativities of the demo corpus.
+var demoRelativities = map[string]string{
+	"beginning of file": wikidata.RelativeBOF,
+	"end of file":       wikidata.RelativeEOF,
+}
+
+var demoRows = []demoRow{
+	{id: "Q178051", label: "Portable Network Graphics", puid: "fmt/11", ldd: "fdd000153", extension: "png", mimetype: "image/png", version: "1.2",
+		property: "P4152", sig: "89504E470D0A1A0A", statement: 1, source: "PRONOM", date: "2019-10-01T00:00:00Z", encoding: "hexadecimal", offset: "0", relativity: "beginning of file"},
+	{id: "Q2192", label: "Graphics Interchange Format", puid: "fmt/4", ldd: "fdd000133", extension: "gif", mimetype: "image/gif", version: "89a",
+		property: "P4152", sig: "GIF89a", statement: 1, source: "PRONOM", date: "2019-10-01T00:00:00Z", encoding: "ASCII", offset: "0", relativity: "beginning of file"},
+	{id: "Q2192", label: "Graphics Interchange Format", puid: "fmt/4", ldd: "fdd000133", extension: "gif", mimetype: "image/gif", version: "89a",
+		property: "P4152", sig: "474946383761", statement: 2, source: "Gary Kessler's File Signatures Table", date: "2020-06-01T00:00:00Z", encoding: "hexadecimal", offset: "0", relativity: "beginning of file"},
+	{id: "Q2195", label: "JPEG File Interchange Format", puid: "fmt/43", ldd: "fdd000018", extension: "jpg", mimetype: "image/jpeg",
+		property: "P4152", sig: "FFD8FFE0", source: "PRONOM", encoding: "hexadecimal", offset: "0", relativity: "beginning of file"},
+	{id: "Q2195", label: "JPEG File Interchange Format", puid: "fmt/43", ldd: "fdd000018", extension: "jpg", mimetype: "image/jpeg",
+		property: "P4152", sig: "FFD9", source: "PRONOM", encoding: "hexadecimal", offset: "0", relativity: "end of file"},
+	{id: "Q42332", label: "Portable Document Format", puid: "fmt/276", ldd: "fdd000277", extension: "pdf", mimetype: "application/pdf", version: "1.7",
+		property: "P4152", sig: "%PDF-1.7", statement: 1, source: "PRONOM", date: "2019-10-01T00:00:00Z", encoding: "hexadecimal", offset: "0", relativity: "beginning of file", part: "PDF 1.7"},
+	{id: "Q136218", label: "ZIP", puid: "x-fmt/263", ldd: "fdd000354", extension: "zip", mimetype: "application/zip",
+		property: "P4152", sig: "504B0304", statement: 1, encoding: "hexadecimal", offset: "0"},
+	{id: "Q935809", label: "Comma-separated values", puid: "x-fmt/18", ldd: "fdd000323", extension: "csv", mimetype: "text/csv", snak: NoValue},
+	{id: "Q1193600", label: "Markdown", extension: ".MD", mimetype: "text/markdown"},
+}
+
+// demoVariables returns the variables the harvest query projects.
+func demoVariables() []interface{} {
+	var vars []interface{}
+	for _, v := range projectedVariables(DefaultQuery) {
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// demoResults returns the demo corpus as the results of the harvest query.
+func demoResults() spargo.SPARQLResult {
+	var res spargo.SPARQLResult
+	res.Head = map[string]interface{}{"vars": demoVariables()}
+	for _, row := range demoRows {
+		literals := map[string]string{
+			labelField:           row.label,
+			puidField:            row.puid,
+			locField:             row.ldd,
+			extField:             row.extension,
+			mimeField:            row.mimetype,
+			versionField:         row.version,
+			snakField:            row.snak,
+			propertyField:        row.property,
+			sigField:             row.sig,
+			referenceLabelField:  row.source,
+			dateField:            row.date,
+			encodingField:        row.encoding,
+			offsetField:          row.offset,
+			relativityLabelField: row.relativity,
+			partField:            row.part,
+			modifiedField:        demoModified,
+		}
+		uris := map[string]string{
+			formatField: "http://www.wikidata.org/entity/" + row.id,
+		}
+		if row.statement != 0 {
+			uris[statementField] = fmt.Sprintf("http://www.wikidata.org/entity/statement/%s-00000000-0000-4000-8000-%012d", row.id, row.statement)
+			uris[rankField] = rankPrefix + "NormalRank"
+		}
+		if source, ok := demoSources[row.source]; ok {
+			uris[referenceField] = "http://www.wikidata.org/entity/" + source
+		}
+		if relativity, ok := demoRelativities[row.relativity]; ok {
+			uris[relativityField] = relativity
+		}
+		result := map[string]spargo.Item{}
+		for field, value := range literals {
+			if value != "" {
+				result[field] = spargo.Item{Type: "literal", Value: value}
+			}
+		}
+		for field, value := range uris {
+			result[field] = spargo.Item{Type: "uri", Value: value}
+		}
+		res.Results.Bindings = append(res.Results.Bindings, result)
+	}
+	return res
+}
+
+// WriteDemoCorpus writes the demo corpus to path as cached SPARQL results
+// that can be processed with the pipeline's Input.
+func WriteDemoCorpus(path string) error {
+	human, err := sparqlJSON(demoResults())
+	if err != nil {
+		return err
+	}
+	if err := makeDir(path); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(human), 0644)
+}
@@ -0,0 +1,52 @@
+package wdanalysis
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDemoCorpus checks that the demo corpus binds every variable of the
+// harvest query, so that processing it is like processing a harvest.
+func TestDemoCorpus(t *testing.T) {
+	res := demoResults()
+	if got, want := res.Head["vars"], demoVariables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got variables %v, want %v", got, want)
+	}
+	p := newTestPipeline(t, DefaultEndpoint)
+	if err := p.Process(res.Results.Bindings); err != nil {
+		t.Fatal(err)
+	}
+	summary := p.Summary()
+	if unbound := summary.alwaysUnbound(); len(unbound) > 0 {
+		t.Errorf("no row of the demo corpus binds %s", strings.Join(unbound, ", "))
+	}
+	if _, ok := summary.SignatureProperties[""]; ok {
+		t.Errorf("got signatures without a property: %v", summary.SignatureProperties)
+	}
+}
+
+// TestDemoManifest checks that the manifest and report of the demo corpus
+// say that it is the demo corpus.
+func TestDemoManifest(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "demo-results.json")
+	if err := WriteDemoCorpus(input); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestPipeline(t, DefaultEndpoint)
+	p.Input = input
+	p.Demo = true
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	manifest := p.Manifest()
+	if !manifest.Demo || manifest.origin() != "the demo corpus" {
+		t.Errorf("got a manifest of %s, want the demo corpus", manifest.origin())
+	}
+	report := p.report(markdownSyntax)
+	if !strings.Contains(report, "bundled demo corpus") || strings.Contains(report, "Harvested from") {
+		t.Errorf("got a report that does not say it is of the demo corpus:\n%s", report)
+	}
+}
@@ -0,0 +1,77 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Registries and interest groups want to review new formats as they appear
+// in Wikidata rather than when identifiers are next rebuilt. Formats that
+// are not in a baseline and have at least one signature that can be used
+// for identification are discoveries. They are written as a JSON feed and
+// can be POSTed to a webhook.
+
+// Discoveries is the feed of formats that are new since a baseline.
+type Discoveries struct {
+	Manifest Manifest   `json:"manifest"`
+	Formats  []Wikidata `json:"formats"`
+}
+
+// viable returns true if a record has a signature that could be used for
+// identification.
+func viable(wd Wikidata) bool {
+	for _, signature := range wd.Signatures {
+		if signature.conversionErr == nil && signature.Normalized != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// discoveries returns the records with viable signatures that are not in
+// the baseline.
+func (b Baseline) discoveries(records []Wikidata) []Wikidata {
+	discovered := []Wikidata{}
+	for _, wd := range records {
+		if _, ok := b.records[wd.ID]; !ok && viable(wd) {
+			discovered = append(discovered, wd)
+		}
+	}
+	return discovered
+}
+
+// DiscoveriesExporter writes the formats with viable signatures that are
+// new since the pipeline's Baseline to Path as a JSON feed if set, and
+// POSTs the feed to Webhook if set and there are any.
+type DiscoveriesExporter struct {
+	Path    string
+	Webhook string
+}
+
+// Export satisfies the Exporter interface.
+func (e DiscoveriesExporter) Export(p *Pipeline) error {
+	if p.Baseline == nil {
+		return fmt.Errorf("new formats cannot be found without a baseline to compare with")
+	}
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	feed := Discoveries{Manifest: p.manifest, Formats: p.Baseline.discoveries(records)}
+	out, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return err
+	}
+	p.Logger.Printf("%d new formats with signatures since the baseline", len(feed.Formats))
+	if e.Path != "" {
+		if err := p.writeExport(e.Path, out); err != nil {
+			return fmt.Errorf("cannot write new formats: %s", err)
+		}
+	}
+	if e.Webhook != "" && len(feed.Formats) > 0 {
+		if err := notifyWebhook(p.client, e.Webhook, out); err != nil {
+			p.Logger.Printf("cannot send new formats to webhook: %s", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package wdanalysis analyzes the results of a Wikidata query to return
+// information about file formats for use in tools such as Siegfried.
+//
+// A Pipeline harvests the file format records of a SPARQL endpoint,
+// condenses them, lints their signatures, and hands them to its exporters.
+// A harvest writing every record as JSON:
+//
+//	p := &wdanalysis.Pipeline{
+//		Exporters: []wdanalysis.Exporter{
+//			wdanalysis.RecordsExporter{Writer: os.Stdout, Format: wdanalysis.FormatJSON},
+//		},
+//	}
+//	if err := p.Run(context.Background()); err != nil && err != wdanalysis.ErrNoSignatures {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(p.Summary().Digest())
+//
+// Lint findings are reported as records are processed. Results harvested
+// previously can be linted again without querying the endpoint:
+//
+//	results, err := wdanalysis.ReadResults(wdanalysis.ResultsCache())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	p := &wdanalysis.Pipeline{
+//		OnLint: func(finding wdanalysis.LintResult) {
+//			fmt.Printf("%s %s %s\n", finding.Severity, finding.Code, finding.URI)
+//		},
+//	}
+//	if err := p.Process(results); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Once processed, records can be exported with any Exporter, or read with
+// Records and ExportRecords:
+//
+//	registry := wdanalysis.RegistryExporter{Path: "registry.tsv"}
+//	if err := registry.Export(p); err != nil {
+//		log.Fatal(err)
+//	}
+package wdanalysis
@@ -0,0 +1,163 @@
+package wdanalysis
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Doctor mode checks that the environment and configuration are fit for a
+// harvest and reports anything that is wrong in terms that a user who is
+// not a developer can act on. Checks are independent of each other where
+// possible so that one failure does not hide another.
+
+// doctorQuery is the tiny query run to check the endpoint answers queries.
+var doctorQuery = `
+	SELECT ?format WHERE
+	{
+	  ?format wdt:<<instanceOf>> wd:<<fileFormat>>.
+	}
+	LIMIT 1
+`
+
+// converterCases are patterns with a known normalized form. An empty
+// normalized form means the pattern should be rejected.
+var converterCases = []struct {
+	value      string
+	encoding   string
+	normalized string
+}{
+	{"89504E470D0A1A0A", encodingHex, "89504E470D0A1A0A"},
+	{"0x25 50 44 46", encodingHex, "25504446"},
+	{"ff d8 ff", "", "FFD8FF"},
+	{"%PDF-", "ASCII", "255044462D"},
+	{"{4}FFD8[00:1F]", "PRONOM internal signature", "{4}FFD8[00:1F]"},
+	{"{4}ffd8 [00:1f] 'Ab c'", "PRONOM internal signature", "{4}FFD8[00:1F]'Ab c'"},
+	{"(FF|'ab')??*{2-*}[!00]", "PRONOM internal signature", "(FF|'ab')??*{2-*}[!00]"},
+	{"{00020906-0000-0000-C000-000000000046}", "globally unique identifier", "0609020000000000C000000000000046"},
+	{"GIF89a", encodingHex, ""},
+	{"FFD8<00>", "PRONOM internal signature", ""},
+	{"{00020906-0000-0000-C000}", "globally unique identifier", ""},
+	{"ABC", encodingHex, ""},
+}
+
+// Check is the outcome of a single check.
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// Diagnosis is the outcome of every check.
+type Diagnosis struct {
+	Ready  bool    `json:"ready"`
+	Checks []Check `json:"checks"`
+}
+
+// String will return the diagnosis as a readiness report to be printed.
+func (d Diagnosis) String() string {
+	var out strings.Builder
+	for _, check := range d.Checks {
+		status := "ok"
+		if !check.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&out, "[%-4s] %s: %s\n", status, check.Name, check.Detail)
+	}
+	if d.Ready {
+		out.WriteString("ready to harvest")
+	} else {
+		out.WriteString("not ready to harvest, fix the checks that failed above")
+	}
+	return out.String()
+}
+
+// add records the outcome of a check.
+func (d *Diagnosis) add(name string, err error, detail string) {
+	check := Check{Name: name, OK: err == nil, Detail: detail}
+	if err != nil {
+		check.Detail = err.Error()
+		d.Ready = false
+	}
+	d.Checks = append(d.Checks, check)
+}
+
+// checkCacheDir checks that harvests can be cached in dir.
+func checkCacheDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create %s: %s", dir, err)
+	}
+	f, err := ioutil.TempFile(dir, "doctor")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %s", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkConverter checks the normalized form of every converter case.
+func checkConverter() error {
+	for _, c := range converterCases {
+		normalized, err := validateAndReturnSignature(c.value, c.encoding)
+		if c.normalized == "" && err == nil {
+			return fmt.Errorf("'%s' (%s) should be rejected but was normalized to '%s'", c.value, c.encoding, normalized)
+		}
+		if c.normalized != "" && normalized != c.normalized {
+			return fmt.Errorf("'%s' (%s) normalized to '%s', expected '%s'", c.value, c.encoding, normalized, c.normalized)
+		}
+	}
+	return nil
+}
+
+// checkEndpoint checks that the endpoint can be reached. Any response
+// will do as an endpoint may not answer a request without a query.
+func (p *Pipeline) checkEndpoint(ctx context.Context) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, p.Endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", p.userAgent())
+	resp, err := p.contextClient(ctx).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach %s: %s", p.Endpoint, err)
+	}
+	resp.Body.Close()
+	return fmt.Sprintf("%s responded with %s", p.Endpoint, resp.Status), nil
+}
+
+// checkQuery checks that the endpoint answers a query for a file format.
+func (p *Pipeline) checkQuery(ctx context.Context) (string, error) {
+	results, err := p.runQuery(ctx, buildQuery(doctorQuery, p.Config.Properties))
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("no file formats were returned, check the properties in the configuration")
+	}
+	return fmt.Sprintf("found %s", results[0][formatField].Value), nil
+}
+
+// Doctor checks that the pipeline is ready to harvest.
+func (p *Pipeline) Doctor(ctx context.Context) Diagnosis {
+	d := Diagnosis{Ready: true}
+	d.add("converter", checkConverter(), fmt.Sprintf("%d known patterns converted as expected", len(converterCases)))
+	if err := p.init(); err != nil {
+		d.add("configuration", err, "")
+		return d
+	}
+	d.add("configuration", nil, "valid")
+	dir := filepath.Dir(p.Cache)
+	d.add("cache", checkCacheDir(dir), fmt.Sprintf("%s is writable", dir))
+	detail, err := p.checkEndpoint(ctx)
+	d.add("network", err, detail)
+	if err != nil {
+		return d
+	}
+	detail, err = p.checkQuery(ctx)
+	d.add("query", err, detail)
+	return d
+}
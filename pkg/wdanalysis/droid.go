@@ -0,0 +1,145 @@
+package wdanalysis
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// DROID users can trial the signatures in Wikidata with a DROID signature
+// file of them. Each record is a file format identified by its QID, as the
+// PUIDs recorded in Wikidata are shared by records and would be mistaken
+// for PRONOM's own. Sequences are grouped into signatures by statement, or
+// as a curated override groups them, and each is positioned from the
+// beginning or end of the file by its relativity, from the beginning where
+// none is recorded.
+
+// droidNamespace is the namespace of a DROID signature file.
+const droidNamespace = "http://www.nationalarchives.gov.uk/pronom/SignatureFile"
+
+// droidFile is a DROID signature file.
+type droidFile struct {
+	XMLName     xml.Name         `xml:"FFSignatureFile"`
+	Namespace   string           `xml:"xmlns,attr"`
+	Version     int              `xml:"Version,attr"`
+	DateCreated string           `xml:"DateCreated,attr"`
+	Signatures  []droidSignature `xml:"InternalSignatureCollection>InternalSignature"`
+	Formats     []droidFormat    `xml:"FileFormatCollection>FileFormat"`
+}
+
+// droidSignature is an internal signature of a DROID signature file.
+type droidSignature struct {
+	ID            int                 `xml:"ID,attr"`
+	Specificity   string              `xml:"Specificity,attr"`
+	ByteSequences []droidByteSequence `xml:"ByteSequence"`
+}
+
+// droidByteSequence is a byte sequence of an internal signature.
+type droidByteSequence struct {
+	Reference   string           `xml:"Reference,attr"`
+	SubSequence droidSubSequence `xml:"SubSequence"`
+}
+
+// droidSubSequence is the sequence of a byte sequence and its offset.
+type droidSubSequence struct {
+	Position  int    `xml:"Position,attr"`
+	MinOffset int    `xml:"SubSeqMinOffset,attr"`
+	MaxOffset int    `xml:"SubSeqMaxOffset,attr"`
+	Sequence  string `xml:"Sequence"`
+}
+
+// droidFormat is a file format of a DROID signature file.
+type droidFormat struct {
+	ID           int      `xml:"ID,attr"`
+	Name         string   `xml:"Name,attr"`
+	PUID         string   `xml:"PUID,attr"`
+	Version      string   `xml:"Version,attr"`
+	MIMEType     string   `xml:"MIMEType,attr,omitempty"`
+	SignatureIDs []int    `xml:"InternalSignatureID"`
+	Extensions   []string `xml:"Extension"`
+}
+
+// droidReference returns the position a relativity is measured from in a
+// DROID signature file.
+func droidReference(rel wikidata.Relativity) string {
+	if rel == wikidata.EOF {
+		return "EOFoffset"
+	}
+	return "BOFoffset"
+}
+
+// droidByteSequenceOf returns the byte sequence of a sequence.
+func droidByteSequenceOf(s Signature) droidByteSequence {
+	// Offsets that are not a number of bytes cannot be positioned so the
+	// sequence is anchored at the reference.
+	offset, err := strconv.Atoi(strings.TrimSpace(s.Offset))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return droidByteSequence{
+		Reference: droidReference(s.relativity()),
+		SubSequence: droidSubSequence{
+			Position:  1,
+			MinOffset: offset,
+			MaxOffset: offset,
+			Sequence:  canonicalHex(s.Normalized),
+		},
+	}
+}
+
+// droidSignatureFileOf returns a DROID signature file of the records.
+func (p *Pipeline) droidSignatureFileOf(records []Wikidata) droidFile {
+	file := droidFile{
+		Namespace:   droidNamespace,
+		Version:     1,
+		DateCreated: p.manifest.Harvested,
+	}
+	for idx, wd := range records {
+		format := droidFormat{
+			ID:         idx + 1,
+			Name:       wd.Name,
+			PUID:       wd.ID,
+			Version:    strings.Join(nonEmpty(wd.Version), ", "),
+			MIMEType:   strings.Join(nonEmpty(wd.Mimetype), ", "),
+			Extensions: nonEmpty(wd.Extension),
+		}
+		normalized := wd
+		normalized.Signatures = nil
+		for _, s := range wd.Signatures {
+			if s.Normalized != "" {
+				normalized.Signatures = append(normalized.Signatures, s)
+			}
+		}
+		for _, group := range p.Group(normalized, statementHeuristic) {
+			signature := droidSignature{ID: len(file.Signatures) + 1, Specificity: "Specific"}
+			for _, s := range group {
+				signature.ByteSequences = append(signature.ByteSequences, droidByteSequenceOf(s))
+			}
+			file.Signatures = append(file.Signatures, signature)
+			format.SignatureIDs = append(format.SignatureIDs, signature.ID)
+		}
+		file.Formats = append(file.Formats, format)
+	}
+	return file
+}
+
+// DROIDExporter writes the records as a DROID signature file to Path.
+type DROIDExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e DROIDExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	out, err := xml.MarshalIndent(p.droidSignatureFileOf(records), "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot write DROID signature file: %s", err)
+	}
+	return p.writeExport(e.Path, []byte(xml.Header+string(out)+"\n"))
+}
@@ -0,0 +1,38 @@
+package wdanalysis
+
+import (
+	"testing"
+)
+
+// TestDROIDOverrides checks that the signatures of a DROID signature file
+// are grouped as curated overrides group them.
+func TestDROIDOverrides(t *testing.T) {
+	tests := []struct {
+		name       string
+		overrides  Overrides
+		signatures int
+	}{
+		{"by statement", nil, 2},
+		{"overridden", Overrides{"Q2192": {{"Q2192$00000000-0000-4000-8000-000000000001", "Q2192$00000000-0000-4000-8000-000000000002"}}}, 1},
+	}
+	for _, test := range tests {
+		p := newTestPipeline(t, DefaultEndpoint)
+		p.Overrides = test.overrides
+		if err := p.Process(demoResults().Results.Bindings); err != nil {
+			t.Fatal(err)
+		}
+		file := p.droidSignatureFileOf(p.Records())
+		var gif *droidFormat
+		for idx := range file.Formats {
+			if file.Formats[idx].PUID == "Q2192" {
+				gif = &file.Formats[idx]
+			}
+		}
+		if gif == nil {
+			t.Fatalf("%s: got no format for Q2192", test.name)
+		}
+		if len(gif.SignatureIDs) != test.signatures {
+			t.Errorf("%s: got %d signatures, want %d", test.name, len(gif.SignatureIDs), test.signatures)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package wdanalysis
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// SPARQL limits how much can be harvested at once. Records can instead be
+// read from a Wikidata JSON dump, e.g. latest-all.json.gz. A dump is a
+// JSON array with one entity per line and is read twice. The first pass
+// collects the subclass hierarchy so that the classes of file formats are
+// known, and the items referred to by the qualifiers and references of
+// signatures. The second pass collects the file formats and the labels of
+// those items. Each file format is turned into rows of the same shape as
+// the harvest query returns so that dumps are processed like any harvest.
+// Like the query, only the best ranked statements of a property are used,
+// except for signatures whose statements of every rank are used with their
+// rank.
+
+// defaultConceptBase is the base of Wikidata's concept URIs.
+const defaultConceptBase = "http://www.wikidata.org/entity/"
+
+// dumpItem is the type of a value in a row that holds the ID of an item
+// whose label is filled in once the whole dump has been read.
+const dumpItem = "item"
+
+// dumpEntity is the part of an entity in a dump that we use.
+type dumpEntity struct {
+	ID       string                     `json:"id"`
+	Modified string                     `json:"modified"`
+	Labels   map[string]dumpText        `json:"labels"`
+	Claims   map[string][]dumpStatement `json:"claims"`
+}
+
+// dumpText is a label in a dump.
+type dumpText struct {
+	Value string `json:"value"`
+}
+
+// dumpStatement is a statement in a dump.
+type dumpStatement struct {
+	ID         string                `json:"id"`
+	Mainsnak   dumpSnak              `json:"mainsnak"`
+	Rank       string                `json:"rank"`
+	Qualifiers map[string][]dumpSnak `json:"qualifiers"`
+	References []dumpReference       `json:"references"`
+}
+
+// dumpReference is a reference of a statement in a dump.
+type dumpReference struct {
+	Snaks map[string][]dumpSnak `json:"snaks"`
+}
+
+// dumpSnak is a snak in a dump.
+type dumpSnak struct {
+	Snaktype  string `json:"snaktype"`
+	Datavalue struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"datavalue"`
+}
+
+// value returns the value of a snak as the query would return it. Items
+// are returned as their ID.
+func (s dumpSnak) value() string {
+	if s.Snaktype != "value" {
+		return ""
+	}
+	var value struct {
+		ID     string `json:"id"`
+		Time   string `json:"time"`
+		Amount string `json:"amount"`
+		Text   string `json:"text"`
+	}
+	switch s.Datavalue.Type {
+	case "string":
+		var text string
+		json.Unmarshal(s.Datavalue.Value, &text)
+		return text
+	case "wikibase-entityid":
+		json.Unmarshal(s.Datavalue.Value, &value)
+		return value.ID
+	case "time":
+		json.Unmarshal(s.Datavalue.Value, &value)
+		return strings.TrimPrefix(value.Time, "+")
+	case "quantity":
+		json.Unmarshal(s.Datavalue.Value, &value)
+		return strings.TrimPrefix(value.Amount, "+")
+	case "monolingualtext":
+		json.Unmarshal(s.Datavalue.Value, &value)
+		return value.Text
+	}
+	return ""
+}
+
+// best returns the best ranked statements of a property: the preferred
+// statements if there are any, otherwise the normal ones.
+func best(statements []dumpStatement) []dumpStatement {
+	var preferred, normal []dumpStatement
+	for _, statement := range statements {
+		switch statement.Rank {
+		case "preferred":
+			preferred = append(preferred, statement)
+		case "normal":
+			normal = append(normal, statement)
+		}
+	}
+	if len(preferred) > 0 {
+		return preferred
+	}
+	return normal
+}
+
+// values returns the values of the best ranked statements of a property.
+func (e dumpEntity) values(prop string) []string {
+	var values []string
+	for _, statement := range best(e.Claims[prop]) {
+		if value := statement.Mainsnak.value(); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// label returns the English label of an entity.
+func (e dumpEntity) label() string {
+	return e.Labels["en"].Value
+}
+
+// scanDump calls entity with every entity in the dump at path. Dumps
+// compressed with gzip or bzip2 are decompressed as they are read.
+func scanDump(path string, entity func(dumpEntity)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(path, ".bz2"):
+		r = bzip2.NewReader(f)
+	}
+	reader := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		line = bytes.TrimRight(bytes.TrimSpace(line), ",")
+		if len(line) > 0 && !bytes.Equal(line, []byte("[")) && !bytes.Equal(line, []byte("]")) {
+			var e dumpEntity
+			if err := json.Unmarshal(line, &e); err != nil {
+				return fmt.Errorf("cannot parse line %d: %s", lineNo, err)
+			}
+			entity(e)
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// dumpReader turns the file formats in a dump into rows of results.
+type dumpReader struct {
+	props    PropertyMap
+	base     string
+	children map[string][]string // Subclasses of each class.
+	classes  map[string]bool     // Classes of file formats.
+	labels   map[string]string   // Labels of the items referred to by signatures.
+	rows     []map[string]spargo.Item
+}
+
+// newDumpReader returns a reader for the entities of the pipeline's Wikibase.
+func (p *Pipeline) newDumpReader() *dumpReader {
+	d := &dumpReader{
+		props:    p.Config.Properties,
+		base:     defaultConceptBase,
+		children: make(map[string][]string),
+		classes:  make(map[string]bool),
+		labels:   make(map[string]string),
+	}
+	if p.Config.IDs.Prefix != "" {
+		d.base = p.Config.IDs.Prefix
+	}
+	return d
+}
+
+// collectHierarchy records the subclasses of an entity's classes and the
+// items its signatures refer to.
+func (d *dumpReader) collectHierarchy(e dumpEntity) {
+	for _, class := range e.values(d.props.SubclassOf) {
+		d.children[class] = append(d.children[class], e.ID)
+	}
+	d.collectLabels(e)
+}
+
+// collectLabels records the items the signatures of an entity refer to so
+// that their labels can be collected.
+func (d *dumpReader) collectLabels(e dumpEntity) {
+	for _, prop := range d.props.signatureProperties() {
+		for _, statement := range e.Claims[prop] {
+			for _, qualifier := range []string{d.props.Encoding, d.props.Relativity, d.props.Part} {
+				for _, snak := range statement.Qualifiers[qualifier] {
+					d.labels[snak.value()] = ""
+				}
+			}
+			for _, reference := range statement.References {
+				for _, snak := range reference.Snaks[d.props.Reference] {
+					d.labels[snak.value()] = ""
+				}
+			}
+		}
+	}
+}
+
+// collectClasses finds every subclass of the file format class.
+func (d *dumpReader) collectClasses() {
+	queue := []string{d.props.FileFormat}
+	d.classes[d.props.FileFormat] = true
+	for len(queue) > 0 {
+		class := queue[0]
+		queue = queue[1:]
+		for _, child := range d.children[class] {
+			if !d.classes[child] {
+				d.classes[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	d.children = nil
+}
+
+// collectFormat records the label of an entity if a signature refers to it
+// and adds the rows of an entity that is a file format.
+func (d *dumpReader) collectFormat(e dumpEntity) {
+	if _, ok := d.labels[e.ID]; ok {
+		d.labels[e.ID] = e.label()
+	}
+	format := false
+	for _, class := range e.values(d.props.InstanceOf) {
+		if d.classes[class] {
+			format = true
+			break
+		}
+	}
+	if format {
+		d.rows = append(d.rows, d.formatRows(e)...)
+	}
+}
+
+// uri returns the concept URI of an entity.
+func (d *dumpReader) uri(id string) string {
+	return d.base + id
+}
+
+// formatRows returns the rows of a file format. Each row holds the next
+// value of each property so that a format has as many rows as its property
+// with the most values.
+func (d *dumpReader) formatRows(e dumpEntity) []map[string]spargo.Item {
+	label := e.label()
+	if label == "" {
+		label = e.ID
+	}
+	base := map[string]spargo.Item{
+		formatField: {Type: "uri", Value: d.uri(e.ID)},
+		labelField:  {Type: "literal", Value: label},
+	}
+	if e.Modified != "" {
+		base[modifiedField] = spargo.Item{Type: "literal", Value: e.Modified}
+	}
+	for _, statement := range best(e.Claims[d.props.Signature]) {
+		if statement.Mainsnak.Snaktype == NoValue {
+			base[snakField] = spargo.Item{Type: "literal", Value: NoValue}
+		}
+	}
+	fields := map[string][]string{
+		puidField:    e.values(d.props.PUID),
+		locField:     e.values(d.props.LOC),
+		extField:     e.values(d.props.Extension),
+		mimeField:    e.values(d.props.MIMEType),
+		versionField: e.values(d.props.Version),
+	}
+	signatures := d.signatureRows(e)
+	count := len(signatures)
+	for _, values := range fields {
+		if len(values) > count {
+			count = len(values)
+		}
+	}
+	rows := []map[string]spargo.Item{}
+	for idx := 0; idx == 0 || idx < count; idx++ {
+		row := make(map[string]spargo.Item)
+		for key, value := range base {
+			row[key] = value
+		}
+		for field, values := range fields {
+			if idx < len(values) {
+				row[field] = spargo.Item{Type: "literal", Value: values[idx]}
+			}
+		}
+		if idx < len(signatures) {
+			for key, value := range signatures[idx] {
+				row[key] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// signatureRows returns the signature fields of the rows of a file format,
+// one for each signature. The labels of items are filled in once the whole
+// dump has been read.
+func (d *dumpReader) signatureRows(e dumpEntity) []map[string]spargo.Item {
+	var rows []map[string]spargo.Item
+	for _, prop := range d.props.signatureProperties() {
+		for _, statement := range e.Claims[prop] {
+			row := map[string]spargo.Item{
+				propertyField: {Type: "literal", Value: prop},
+				rankField:     {Type: "literal", Value: statement.Rank},
+			}
+			if statement.ID != "" {
+				row[statementField] = spargo.Item{Type: "uri", Value: statementIRI(d.base, statement.ID)}
+			}
+			switch statement.Mainsnak.Snaktype {
+			case "value":
+				row[sigField] = spargo.Item{Type: "literal", Value: statement.Mainsnak.value()}
+			case SomeValue:
+				row[sigField] = spargo.Item{Type: "bnode", Value: genidPrefix + e.ID}
+			default:
+				continue
+			}
+			qualifier := func(prop string) string {
+				for _, snak := range statement.Qualifiers[prop] {
+					if value := snak.value(); value != "" {
+						return value
+					}
+				}
+				return ""
+			}
+			if encoding := qualifier(d.props.Encoding); encoding != "" {
+				row[encodingField] = spargo.Item{Type: dumpItem, Value: encoding}
+			}
+			if offset := qualifier(d.props.Offset); offset != "" {
+				row[offsetField] = spargo.Item{Type: "literal", Value: offset}
+			}
+			if relativity := qualifier(d.props.Relativity); relativity != "" {
+				row[relativityField] = spargo.Item{Type: "uri", Value: d.uri(relativity)}
+				row[relativityLabelField] = spargo.Item{Type: dumpItem, Value: relativity}
+			}
+			if part := qualifier(d.props.Part); part != "" {
+				row[partField] = spargo.Item{Type: dumpItem, Value: part}
+			}
+			for _, reference := range statement.References {
+				var source, date string
+				for _, snak := range reference.Snaks[d.props.Reference] {
+					source = snak.value()
+				}
+				for _, snak := range reference.Snaks[d.props.Date] {
+					date = snak.value()
+				}
+				if source != "" && date != "" {
+					row[referenceField] = spargo.Item{Type: "uri", Value: d.uri(source)}
+					row[referenceLabelField] = spargo.Item{Type: dumpItem, Value: source}
+					row[dateField] = spargo.Item{Type: "literal", Value: date}
+					break
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// resolveLabels replaces the IDs of items in the rows with their labels.
+// Items without an English label are given their ID as the label service
+// of the query would.
+func (d *dumpReader) resolveLabels() {
+	for _, row := range d.rows {
+		for key, value := range row {
+			if value.Type != dumpItem {
+				continue
+			}
+			value.Type = "literal"
+			if label := d.labels[value.Value]; label != "" {
+				value.Value = label
+			}
+			row[key] = value
+		}
+	}
+}
+
+// readDump reads the rows of the file formats in the pipeline's dump.
+func (p *Pipeline) readDump() ([]map[string]spargo.Item, error) {
+	d := p.newDumpReader()
+	p.Logger.Printf("reading the class hierarchy from %s", p.Dump)
+	if err := scanDump(p.Dump, d.collectHierarchy); err != nil {
+		return nil, fmt.Errorf("cannot read dump: %s", err)
+	}
+	d.collectClasses()
+	p.Logger.Printf("reading the formats of %d classes from %s", len(d.classes), p.Dump)
+	if err := scanDump(p.Dump, d.collectFormat); err != nil {
+		return nil, fmt.Errorf("cannot read dump: %s", err)
+	}
+	d.resolveLabels()
+	return d.rows, nil
+}
+
+// processDump processes the file formats in the pipeline's dump. They are
+// taken to have been harvested when the dump was written.
+func (p *Pipeline) processDump() (time.Time, error) {
+	info, err := os.Stat(p.Dump)
+	if err != nil {
+		return time.Time{}, err
+	}
+	rows, err := p.readDump()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), p.Process(rows)
+}
@@ -0,0 +1,164 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Exact duplicate format identification pattern (e.g. P4152) statements are
+// collapsed when records are condensed. To report them we need to know the
+// individual statements so we run a dedicated query that returns each
+// statement alongside the qualifiers that make it unique.
+
+var duplicatesQuery = `
+	SELECT DISTINCT ?format ?formatLabel ?statement ?sigProperty ?sig ?encodingLabel ?offset ?relativityLabel WHERE
+	{
+	  ?format wdt:<<instanceOf>>/wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	  VALUES (?sigProperty ?sigDirect ?sigStatement ?sigValue) { <<signatureValues>> }
+	  ?format ?sigStatement ?statement.
+	  ?statement ?sigValue ?sig.
+	  OPTIONAL { ?statement pq:<<encoding>> ?encoding. }
+	  OPTIONAL { ?statement pq:<<offset>> ?offset. }
+	  OPTIONAL { ?statement pq:<<relativity>> ?relativity. }
+	  SERVICE wikibase:label { bd:serviceParam wikibase:language "[AUTO_LANGUAGE], en". }
+	}
+	order by ?format
+`
+
+// Duplicate describes a single sequence recorded in more than one P4152
+// statement of the same item.
+type Duplicate struct {
+	Key        string   `json:"key"`
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	URI        string   `json:"uri"`
+	Property   string   `json:"property"`
+	Signature  string   `json:"signature"`
+	Encoding   string   `json:"encoding"`
+	Offset     string   `json:"offset"`
+	Relativity string   `json:"relativity"`
+	Statements []string `json:"statements"`
+}
+
+// statementID converts a statement URI to the statement ID used by
+// Wikidata, e.g. .../entity/statement/Q12-ABC becomes Q12$ABC.
+func statementID(statementURI string) string {
+	return strings.Replace(getID(statementURI), "-", "$", 1)
+}
+
+// findDuplicates groups the statements of each item by their value and
+// qualifiers and returns the groups with more than one statement. Values
+// are grouped in their canonical form so that the same sequence written in
+// different styles is a duplicate.
+func (p *Pipeline) findDuplicates(results []map[string]spargo.Item) []Duplicate {
+	groups := make(map[string]*Duplicate)
+	var keys []string
+	for _, result := range results {
+		row := binding(result)
+		dupe := Duplicate{
+			ID:         p.id(row.URI()),
+			Name:       row.Label(),
+			URI:        row.URI(),
+			Property:   row.Property(),
+			Signature:  row.Signature(),
+			Encoding:   row.Encoding(),
+			Offset:     row.OffsetRaw(),
+			Relativity: row.RelativityLabel(),
+		}
+		value := dupe.Signature
+		if normalized, err := p.normalizeSignature(dupe.Signature, dupe.Encoding); err == nil {
+			value = normalized
+		}
+		key := strings.Join([]string{dupe.ID, dupe.Property, value, dupe.Encoding, dupe.Offset, dupe.Relativity}, "\x00")
+		if groups[key] == nil {
+			groups[key] = &dupe
+			keys = append(keys, key)
+		}
+		id := statementID(row.Statement())
+		if !contains(groups[key].Statements, id) {
+			groups[key].Statements = append(groups[key].Statements, id)
+		}
+	}
+	sort.Strings(keys)
+	duplicates := []Duplicate{}
+	for _, key := range keys {
+		if len(groups[key].Statements) > 1 {
+			sort.Strings(groups[key].Statements)
+			duplicates = append(duplicates, *groups[key])
+		}
+	}
+	return duplicates
+}
+
+// duplicateKey returns the sequence key of a duplicate so that it can be
+// joined with the sequences in other reports.
+func (p *Pipeline) duplicateKey(dupe Duplicate) string {
+	s := Signature{
+		Signature:  dupe.Signature,
+		Encoding:   dupe.Encoding,
+		Offset:     dupe.Offset,
+		Relativity: dupe.Relativity,
+		rel:        parseRelativity(p.Config.Entities, "", dupe.Relativity),
+	}
+	s.Normalized, _ = p.normalizeSignature(s.Signature, s.Encoding)
+	return s.key()
+}
+
+// lintDuplicates records a finding for every redundant statement.
+func lintDuplicates(summary *Summary, duplicates []Duplicate) {
+	for _, dupe := range duplicates {
+		for range dupe.Statements[1:] {
+			summary.lint(dupe.URI, seqWDE01)
+		}
+	}
+}
+
+// duplicatesWikitext returns the duplicates as wikitext that can be pasted
+// onto a Wikidata talk page.
+func duplicatesWikitext(duplicates []Duplicate) string {
+	var out strings.Builder
+	out.WriteString("== Duplicate format identification pattern statements ==\n")
+	for _, dupe := range duplicates {
+		fmt.Fprintf(&out, "\n=== [[%s|%s]] ===\n", dupe.ID, dupe.Name)
+		fmt.Fprintf(&out, "Value <code><nowiki>%s</nowiki></code> of %s (encoding: %s, offset: %s, relativity: %s) is recorded in %d statements:\n",
+			dupe.Signature,
+			noneIfEmpty(dupe.Property),
+			noneIfEmpty(dupe.Encoding),
+			noneIfEmpty(dupe.Offset),
+			noneIfEmpty(dupe.Relativity),
+			len(dupe.Statements),
+		)
+		for _, statement := range dupe.Statements {
+			fmt.Fprintf(&out, "* <code>%s</code>\n", statement)
+		}
+	}
+	return out.String()
+}
+
+// noneIfEmpty returns "None" for an empty value as in our CSV output.
+func noneIfEmpty(value string) string {
+	if value == "" {
+		return "None"
+	}
+	return value
+}
+
+// exportDuplicates writes the duplicate statement report to path. A JSON
+// document is written if the path has a .json extension, otherwise
+// wikitext is written.
+func (p *Pipeline) exportDuplicates(path string, duplicates []Duplicate) error {
+	out := duplicatesWikitext(duplicates)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		report, err := json.MarshalIndent(duplicates, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = fmt.Sprintf("%s\n", report)
+	}
+	return p.writeExport(path, []byte(out))
+}
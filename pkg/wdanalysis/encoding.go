@@ -0,0 +1,71 @@
+package wdanalysis
+
+import (
+	"strings"
+)
+
+// Encoding labels as returned by Wikidata for the encoding (P3294)
+// qualifier of a format identification pattern.
+const (
+	encodingHex    = "hexadecimal"
+	encodingASCII  = "ascii"
+	encodingPRONOM = "pronom internal signature"
+	encodingGUID   = "globally unique identifier"
+)
+
+// normalizeEncoding returns an encoding label in a form that can be compared
+// with the encoding constants above.
+func normalizeEncoding(encoding string) string {
+	return strings.ToLower(strings.TrimSpace(encoding))
+}
+
+// isHex returns true if a signature consists only of hexadecimal digits.
+// Spaces between bytes and a leading 0x are tolerated as they are common in
+// Wikidata and are not in themselves a contradiction of the label.
+func isHex(signature string) bool {
+	signature = strings.Replace(signature, " ", "", -1)
+	if strings.HasPrefix(signature, "0x") || strings.HasPrefix(signature, "0X") {
+		signature = signature[2:]
+	}
+	if signature == "" {
+		return false
+	}
+	for _, r := range signature {
+		switch {
+		case r >= '0' && r <= '9':
+		case r >= 'a' && r <= 'f':
+		case r >= 'A' && r <= 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isPrintableASCII returns true if a signature consists only of printable
+// ASCII characters. Tabs and line endings are allowed as they are part of
+// much ASCII magic.
+func isPrintableASCII(signature string) bool {
+	if signature == "" {
+		return false
+	}
+	for _, r := range signature {
+		if (r < 0x20 || r > 0x7E) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// contradictsEncoding returns true if the signature value contradicts the
+// encoding label it has been given in Wikidata. Encodings we know nothing
+// about are given the benefit of the doubt.
+func contradictsEncoding(signature string, encoding string) bool {
+	switch normalizeEncoding(encoding) {
+	case encodingHex:
+		return !isHex(signature)
+	case encodingASCII:
+		return !isPrintableASCII(signature)
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package wdanalysis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Mirrors of the Wikidata Query Service, such as QLever, answer faster but
+// can lag behind Wikidata or diverge from it. Running the same harvest
+// query against two endpoints and comparing their rows shows whether a
+// mirror is fit to build an identifier from. Endpoints may order their
+// results differently so rows are compared as a multiset, and differences
+// are reported by the format they describe.
+
+// EndpointRows describes the rows returned by one endpoint.
+type EndpointRows struct {
+	Endpoint string `json:"endpoint"`
+	Rows     int    `json:"rows"`
+	Formats  int    `json:"formats"`
+}
+
+// RowDifference describes a format whose rows differ between endpoints.
+type RowDifference struct {
+	ID  string              `json:"id"`
+	URI string              `json:"uri"`
+	A   []map[string]string `json:"a"` // Rows returned only by endpoint A.
+	B   []map[string]string `json:"b"` // Rows returned only by endpoint B.
+}
+
+// EndpointComparison describes the rows returned by two endpoints for the
+// same query and how they differ.
+type EndpointComparison struct {
+	A           EndpointRows    `json:"a"`
+	B           EndpointRows    `json:"b"`
+	Identical   bool            `json:"identical"`
+	Differences []RowDifference `json:"differences"`
+}
+
+// rowKey returns a key identifying a row by its variables and values.
+func rowKey(row map[string]spargo.Item) string {
+	var fields []string
+	for name, item := range row {
+		fields = append(fields, fmt.Sprintf("%s=%s@%s", name, item.Value, item.Lang))
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, "\t")
+}
+
+// rowValues returns the value of each variable of a row.
+func rowValues(row map[string]spargo.Item) map[string]string {
+	values := make(map[string]string)
+	for name, item := range row {
+		values[name] = item.Value
+	}
+	return values
+}
+
+// countRows returns the number of times each row occurs and the number of
+// formats described.
+func countRows(rows []map[string]spargo.Item) (map[string]int, int) {
+	counts := make(map[string]int)
+	formats := make(map[string]bool)
+	for _, row := range rows {
+		counts[rowKey(row)]++
+		formats[row[formatField].Value] = true
+	}
+	return counts, len(formats)
+}
+
+// CompareEndpoints runs the harvest query of a against the endpoints of a
+// and b and returns the rows returned by only one of them. The query, its
+// restrictions, and the properties are those of a.
+func CompareEndpoints(ctx context.Context, a *Pipeline, b *Pipeline) (EndpointComparison, error) {
+	var comparison EndpointComparison
+	if err := a.init(); err != nil {
+		return comparison, err
+	}
+	if err := b.init(); err != nil {
+		return comparison, err
+	}
+	query := a.harvestQuery()
+	a.Logger.Printf("querying %s", a.Endpoint)
+	rowsA, err := a.runQuery(ctx, query)
+	if err != nil {
+		return comparison, fmt.Errorf("cannot query %s: %s", a.Endpoint, err)
+	}
+	b.Logger.Printf("querying %s", b.Endpoint)
+	rowsB, err := b.runQuery(ctx, query)
+	if err != nil {
+		return comparison, fmt.Errorf("cannot query %s: %s", b.Endpoint, err)
+	}
+	countsA, formatsA := countRows(rowsA)
+	countsB, formatsB := countRows(rowsB)
+	comparison.A = EndpointRows{Endpoint: a.Endpoint, Rows: len(rowsA), Formats: formatsA}
+	comparison.B = EndpointRows{Endpoint: b.Endpoint, Rows: len(rowsB), Formats: formatsB}
+	differences := make(map[string]*RowDifference)
+	difference := func(row map[string]spargo.Item) *RowDifference {
+		uri := row[formatField].Value
+		if differences[uri] == nil {
+			differences[uri] = &RowDifference{ID: a.id(uri), URI: uri, A: []map[string]string{}, B: []map[string]string{}}
+		}
+		return differences[uri]
+	}
+	// A row repeated more often by one endpoint than the other is reported
+	// once for each extra time it is returned.
+	for _, row := range rowsA {
+		key := rowKey(row)
+		if countsB[key] > 0 {
+			countsB[key]--
+			continue
+		}
+		diff := difference(row)
+		diff.A = append(diff.A, rowValues(row))
+	}
+	for _, row := range rowsB {
+		key := rowKey(row)
+		if countsA[key] > 0 {
+			countsA[key]--
+			continue
+		}
+		diff := difference(row)
+		diff.B = append(diff.B, rowValues(row))
+	}
+	comparison.Differences = []RowDifference{}
+	for _, diff := range differences {
+		comparison.Differences = append(comparison.Differences, *diff)
+	}
+	sort.Slice(comparison.Differences, func(i, j int) bool {
+		return comparison.Differences[i].URI < comparison.Differences[j].URI
+	})
+	comparison.Identical = len(comparison.Differences) == 0
+	return comparison, nil
+}
@@ -0,0 +1,97 @@
+package wdanalysis
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Wikidata records PUIDs and Library of Congress FDD identifiers but not the
+// names the authorities give them. Enrichment looks the identifiers up
+// against PRONOM and the LoC so that exports can show human-readable labels
+// for external identifiers.
+
+const pronomURL = "https://www.nationalarchives.gov.uk/PRONOM/%s.xml"
+const locURL = "https://www.loc.gov/preservation/digital/formats/fddXML/%s.xml"
+
+// pronomReport describes the parts of a PRONOM XML report we need.
+type pronomReport struct {
+	Name    string `xml:"report_format_detail>FileFormat>FormatName"`
+	Version string `xml:"report_format_detail>FileFormat>FormatVersion"`
+}
+
+// locReport describes the parts of a LoC FDD XML description we need.
+type locReport struct {
+	Name string `xml:"titleName,attr"`
+}
+
+// fetchXML retrieves an XML document and decodes it into v.
+func fetchXML(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from server: %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(body, v)
+}
+
+// pronomLabel returns the PRONOM format name and version for a PUID.
+func pronomLabel(client *http.Client, puid string) (string, error) {
+	var report pronomReport
+	if err := fetchXML(client, fmt.Sprintf(pronomURL, puid), &report); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s", report.Name, report.Version)), nil
+}
+
+// locLabel returns the LoC FDD title for an FDD identifier.
+func locLabel(client *http.Client, fdd string) (string, error) {
+	var report locReport
+	if err := fetchXML(client, fmt.Sprintf(locURL, fdd), &report); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(report.Name), nil
+}
+
+// enrichRecords attaches authoritative labels for the PUIDs and LoC
+// identifiers of every record in the corpus. Each identifier is looked up
+// once only. Failed lookups are logged and otherwise ignored.
+func (p *Pipeline) enrichRecords(ctx context.Context) {
+	client := p.contextClient(ctx)
+	labels := make(map[string]string)
+	lookup := func(id string, fetch func(*http.Client, string) (string, error)) string {
+		if label, ok := labels[id]; ok {
+			return label
+		}
+		label, err := fetch(client, id)
+		if err != nil {
+			p.Logger.Printf("cannot retrieve label for '%s': %s", id, err)
+		}
+		labels[id] = label
+		return label
+	}
+	for id, wd := range p.records {
+		wd.ExternalLabels = make(map[string]string)
+		for _, puid := range nonEmpty(wd.PRONOM) {
+			if label := lookup(puid, pronomLabel); label != "" {
+				wd.ExternalLabels[puid] = label
+			}
+		}
+		for _, fdd := range nonEmpty(wd.LOC) {
+			if label := lookup(fdd, locLabel); label != "" {
+				wd.ExternalLabels[fdd] = label
+			}
+		}
+		p.records[id] = wd
+	}
+}
@@ -0,0 +1,150 @@
+package wdanalysis_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// demoCorpus writes the demo corpus to a temporary directory and returns
+// the directory and the path of the corpus in it.
+func demoCorpus() (string, string) {
+	dir, err := ioutil.TempDir("", "wdanalysis")
+	if err != nil {
+		log.Fatal(err)
+	}
+	corpus := filepath.Join(dir, "demo-results.json")
+	if err := wdanalysis.WriteDemoCorpus(corpus); err != nil {
+		log.Fatal(err)
+	}
+	return dir, corpus
+}
+
+// Harvest the formats of a SPARQL endpoint, here one that answers with the
+// demo corpus, and list the records condensed from the results.
+func Example_harvest() {
+	dir, corpus := demoCorpus()
+	defer os.RemoveAll(dir)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/sparql-results+json")
+		http.ServeFile(w, r, corpus)
+	}))
+	defer endpoint.Close()
+
+	p := &wdanalysis.Pipeline{
+		Endpoint: endpoint.URL,
+		Cache:    filepath.Join(dir, "results.json"),
+	}
+	if err := p.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	for _, wd := range p.Records() {
+		fmt.Printf("%s %s: %d signatures\n", wd.ID, wd.Name, len(wd.Signatures))
+	}
+	// Output:
+	// Q1193600 Markdown: 0 signatures
+	// Q136218 ZIP: 1 signatures
+	// Q178051 Portable Network Graphics: 1 signatures
+	// Q2192 Graphics Interchange Format: 2 signatures
+	// Q2195 JPEG File Interchange Format: 2 signatures
+	// Q42332 Portable Document Format: 1 signatures
+	// Q935809 Comma-separated values: 0 signatures
+}
+
+// Lint cached results and list the findings of each record as they are
+// made. A finding can be withdrawn once every signature of a record has
+// been seen, e.g. when a finding about each signature is replaced by one
+// about the record.
+func Example_lint() {
+	dir, corpus := demoCorpus()
+	defer os.RemoveAll(dir)
+
+	findings := make(map[string]wdanalysis.LintResult)
+	p := &wdanalysis.Pipeline{
+		Input: corpus,
+		Cache: filepath.Join(dir, "results.json"),
+		OnLint: func(result wdanalysis.LintResult) {
+			key := result.URI + " " + result.Code
+			if result.Withdrawn {
+				delete(findings, key)
+				return
+			}
+			findings[key] = result
+		},
+	}
+	if err := p.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	var keys []string
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		finding := findings[key]
+		fmt.Printf("%s %s (%s): %s\n", filepath.Base(finding.URI), finding.Code, finding.Severity, finding.Description)
+	}
+	// Output:
+	// Q1193600 extWDE01 (warning): extension is not lowercase without a leading dot
+	// Q136218 proWDE01 (warning): signature has no provenance
+	// Q136218 proWDE02 (warning): signature provenance has no date
+	// Q136218 relWDE02 (error): no signature in the record has a relativity, assuming BOF for all
+	// Q2195 heuWDE01 (error): record has multiple sequences which can only be grouped into signatures heuristically
+	// Q2195 proWDE02 (warning): signature provenance has no date
+	// Q42332 encWDE02 (critical): encoding label contradicts value
+}
+
+// Export the records as the SPARQL results that Siegfried's Roy builds a
+// Wikidata identifier from, and read them back.
+func Example_exportRoy() {
+	dir, corpus := demoCorpus()
+	defer os.RemoveAll(dir)
+
+	identifier := filepath.Join(dir, "identifier.json")
+	p := &wdanalysis.Pipeline{
+		Input:     corpus,
+		Cache:     filepath.Join(dir, "results.json"),
+		Exporters: []wdanalysis.Exporter{wdanalysis.IdentifierExporter{Path: identifier}},
+	}
+	if err := p.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	rows, err := wdanalysis.ReadResults(identifier)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, row := range rows {
+		id := filepath.Base(row["uri"].Value)
+		if row["sig"].Value == "" {
+			fmt.Printf("%s: no sequence\n", id)
+			continue
+		}
+		relativity := "unknown"
+		switch row["relativity"].Value {
+		case wikidata.RelativeBOF:
+			relativity = "BOF"
+		case wikidata.RelativeEOF:
+			relativity = "EOF"
+		}
+		fmt.Printf("%s: %s at %s from %s\n", id, row["sig"].Value, row["offset"].Value, relativity)
+	}
+	// Output:
+	// Q1193600: no sequence
+	// Q136218: 504B0304 at 0 from unknown
+	// Q178051: 89504E470D0A1A0A at 0 from BOF
+	// Q2192: 474946383961 at 0 from BOF
+	// Q2192: 474946383761 at 0 from BOF
+	// Q2195: FFD8FFE0 at 0 from BOF
+	// Q2195: FFD9 at 0 from EOF
+	// Q42332: no sequence
+	// Q935809: no sequence
+}
@@ -0,0 +1,49 @@
+package wdanalysis
+
+// Export policies can drop sequences from exports, e.g. scoped or
+// overlong sequences, or sequences already in DROID when writing a
+// supplement. Every sequence dropped is recorded in the summary with the
+// reason so that the effect of the policies can be seen.
+
+// Reasons a sequence is excluded from exports.
+const (
+	ExcludedScoped = "scoped"
+	ExcludedLength = "too long"
+	ExcludedDROID  = "in DROID"
+)
+
+// Exclusion describes a sequence excluded from exports.
+type Exclusion struct {
+	Signature string `json:"signature"`
+	Key       string `json:"key"`
+	Reason    string `json:"reason"`
+}
+
+// Exclusions describes the sequences excluded from exports.
+type Exclusions struct {
+	Total   int                    `json:"total"`
+	Reasons map[string]int         `json:"reasons"` // Sequences excluded for each reason.
+	Records map[string][]Exclusion `json:"records"` // Sequences excluded from each record by URI.
+}
+
+// newExclusions returns an empty record of exclusions.
+func newExclusions() *Exclusions {
+	return &Exclusions{
+		Reasons: make(map[string]int),
+		Records: make(map[string][]Exclusion),
+	}
+}
+
+// add records that a sequence of a record has been excluded.
+func (e *Exclusions) add(wd Wikidata, signature Signature, reason string) {
+	if e == nil {
+		return
+	}
+	e.Total++
+	e.Reasons[reason]++
+	e.Records[wd.URI] = append(e.Records[wd.URI], Exclusion{
+		Signature: signature.Signature,
+		Key:       signature.Key,
+		Reason:    reason,
+	})
+}
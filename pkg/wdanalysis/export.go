@@ -0,0 +1,101 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Exporter writes the results of a pipeline once its records have been
+// processed.
+type Exporter interface {
+	Export(p *Pipeline) error
+}
+
+// Export formats that can be written by RecordsExporter.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// ExportFormats lists the formats supported by RecordsExporter.
+var ExportFormats = []string{FormatJSON, FormatNDJSON}
+
+// RecordsExporter writes every record to Writer in an export format.
+type RecordsExporter struct {
+	Writer io.Writer
+	Format string
+}
+
+// Export satisfies the Exporter interface.
+func (e RecordsExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	if err := exportRecords(e.Writer, e.Format, records); err != nil {
+		return fmt.Errorf("cannot export records: %s", err)
+	}
+	return nil
+}
+
+// exportRecords writes the corpus to w in the given export format.
+func exportRecords(w io.Writer, format string, records []Wikidata) error {
+	switch format {
+	case FormatJSON:
+		out, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", out)
+		return err
+	case FormatNDJSON:
+		for _, wd := range records {
+			out, err := json.Marshal(wd)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown export format '%s', expected one of: %v", format, ExportFormats)
+}
+
+// RecordsVersion is the version of the RecordsDocument layout. It is
+// incremented whenever a field is renamed or removed so that analyses of
+// older documents can tell which layout they are reading.
+const RecordsVersion = 1
+
+// RecordsDocument is every processed record, with its signatures and their
+// provenance, and the manifest of the harvest it came from, so that the
+// condensed dataset can be analysed without querying the endpoint again.
+type RecordsDocument struct {
+	Version  int        `json:"version"`
+	Manifest Manifest   `json:"manifest"`
+	Records  []Wikidata `json:"records"`
+}
+
+// DocumentExporter writes every record to Path as a RecordsDocument.
+type DocumentExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e DocumentExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	document := RecordsDocument{Version: RecordsVersion, Manifest: p.manifest, Records: records}
+	out, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := p.writeExport(e.Path, append(out, '\n')); err != nil {
+		return fmt.Errorf("cannot write records: %s", err)
+	}
+	return nil
+}
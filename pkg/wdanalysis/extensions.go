@@ -0,0 +1,41 @@
+package wdanalysis
+
+import (
+	"strings"
+)
+
+// Wikidata records file extensions both with and without a leading dot,
+// and in upper and lower case, which defeats matching on extensions
+// downstream. Extensions are normalized to the convention of the file
+// extension property: lowercase without a leading dot, e.g. "png". The raw
+// values are kept alongside and values that deviate from the convention
+// are linted so that they can be corrected in Wikidata.
+
+// normalizeExtension returns an extension in the conventional form.
+func normalizeExtension(extension string) string {
+	extension = strings.TrimSpace(extension)
+	extension = strings.TrimPrefix(extension, "*")
+	extension = strings.TrimPrefix(extension, ".")
+	return strings.ToLower(extension)
+}
+
+// addExtension adds a raw extension and its conventional form to a record.
+func addExtension(wd *Wikidata, extension string) {
+	if extension != "" && !contains(wd.RawExtension, extension) {
+		wd.RawExtension = append(wd.RawExtension, extension)
+	}
+	normalized := normalizeExtension(extension)
+	if !contains(wd.Extension, normalized) {
+		wd.Extension = append(wd.Extension, normalized)
+	}
+}
+
+// lintExtensions records a finding for every extension of a record that
+// deviates from the convention.
+func lintExtensions(summary *Summary, wd Wikidata) {
+	for _, extension := range wd.RawExtension {
+		if extension != normalizeExtension(extension) {
+			summary.lint(wd.URI, extWDE01)
+		}
+	}
+}
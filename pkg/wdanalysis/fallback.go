@@ -0,0 +1,133 @@
+package wdanalysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// WDQS occasionally serves truncated JSON. When JSON results repeatedly fail
+// to parse we fall back to requesting the results as CSV, which is parsed
+// into the same structure as the JSON results.
+
+// jsonAttempts is the number of times we will try to parse JSON results
+// before falling back to CSV.
+const jsonAttempts = 3
+
+// csvAccept is the media type of SPARQL CSV results.
+const csvAccept = "text/csv"
+
+// parseError describes results that were received but could not be parsed.
+type parseError struct {
+	err error
+}
+
+// Error satisfies the error interface.
+func (e parseError) Error() string {
+	return fmt.Sprintf("cannot parse results: %s", e.err)
+}
+
+// isParseError returns true if spargo failed because the results could not
+// be parsed rather than because the request failed.
+func isParseError(r interface{}) bool {
+	switch r.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return true
+	}
+	return false
+}
+
+// itemType returns the SPARQL JSON type of a CSV value. CSV results do not
+// distinguish between URIs and literals so we guess.
+func itemType(value string) string {
+	if uri, err := neturl.Parse(value); err == nil && (uri.Scheme == "http" || uri.Scheme == "https") && uri.Host != "" {
+		return "uri"
+	}
+	return "literal"
+}
+
+// parseCSVResults parses SPARQL CSV results. Unbound values are empty in
+// CSV and are left out of the bindings as they are in JSON results.
+func parseCSVResults(data string) (spargo.SPARQLResult, error) {
+	var res spargo.SPARQLResult
+	rows, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return res, err
+	}
+	if len(rows) == 0 {
+		return res, fmt.Errorf("no header in CSV results")
+	}
+	vars := rows[0]
+	res.Head = map[string]interface{}{"vars": vars}
+	res.Results.Bindings = []map[string]spargo.Item{}
+	for _, row := range rows[1:] {
+		binding := make(map[string]spargo.Item)
+		for idx, value := range row {
+			if value == "" || idx >= len(vars) {
+				continue
+			}
+			binding[vars[idx]] = spargo.Item{Type: itemType(value), Value: value}
+		}
+		res.Results.Bindings = append(res.Results.Bindings, binding)
+	}
+	human, err := sparqlJSON(res)
+	if err != nil {
+		return res, err
+	}
+	res.Human = human
+	return res, nil
+}
+
+// sparqlJSON returns results as SPARQL JSON so that results from a CSV
+// fallback can be cached in the same form as any other.
+func sparqlJSON(res spargo.SPARQLResult) (string, error) {
+	type item struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	var bindings []map[string]item
+	for _, binding := range res.Results.Bindings {
+		converted := make(map[string]item)
+		for key, value := range binding {
+			converted[key] = item{Type: value.Type, Value: value.Value}
+		}
+		bindings = append(bindings, converted)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"head":    res.Head,
+		"results": map[string]interface{}{"bindings": bindings},
+	})
+	return string(data), err
+}
+
+// runCSV runs a query requesting CSV results.
+func (p *Pipeline) runCSV(client *http.Client, query string) (spargo.SPARQLResult, error) {
+	req, err := http.NewRequest(http.MethodGet, p.Endpoint, nil)
+	if err != nil {
+		return spargo.SPARQLResult{}, err
+	}
+	req.Header.Set("User-Agent", p.userAgent())
+	req.Header.Set("Accept", csvAccept)
+	values := req.URL.Query()
+	values.Add("query", query)
+	req.URL.RawQuery = values.Encode()
+	resp, err := client.Do(req)
+	if err != nil {
+		return spargo.SPARQLResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return spargo.SPARQLResult{}, fmt.Errorf("unexpected response from server: %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return spargo.SPARQLResult{}, err
+	}
+	return parseCSVResults(string(body))
+}
@@ -0,0 +1,100 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// TestParseCSVResults checks that SPARQL CSV results are parsed into the
+// same structure as JSON results.
+func TestParseCSVResults(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []map[string]spargo.Item
+		err  bool
+	}{
+		{
+			"bound and unbound values",
+			"format,formatLabel,puid\r\nhttp://www.wikidata.org/entity/Q2192,GIF,fmt/4\r\nhttp://www.wikidata.org/entity/Q2195,JPEG,\r\n",
+			[]map[string]spargo.Item{
+				{"format": {Type: "uri", Value: "http://www.wikidata.org/entity/Q2192"}, "formatLabel": {Type: "literal", Value: "GIF"}, "puid": {Type: "literal", Value: "fmt/4"}},
+				{"format": {Type: "uri", Value: "http://www.wikidata.org/entity/Q2195"}, "formatLabel": {Type: "literal", Value: "JPEG"}},
+			},
+			false,
+		},
+		{
+			"quoted values",
+			"format,formatLabel\nhttp://www.wikidata.org/entity/Q1,\"a, \"\"b\"\"\"\n",
+			[]map[string]spargo.Item{
+				{"format": {Type: "uri", Value: "http://www.wikidata.org/entity/Q1"}, "formatLabel": {Type: "literal", Value: `a, "b"`}},
+			},
+			false,
+		},
+		{"header only", "format,formatLabel\n", []map[string]spargo.Item{}, false},
+		{"empty", "", nil, true},
+		{"ragged", "format,formatLabel\nhttp://www.wikidata.org/entity/Q1\n", nil, true},
+	}
+	for _, test := range tests {
+		res, err := parseCSVResults(test.data)
+		if (err != nil) != test.err {
+			t.Errorf("%s: got error %v, want error %t", test.name, err, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		if !reflect.DeepEqual(res.Results.Bindings, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, res.Results.Bindings, test.want)
+		}
+		if res.Human == "" {
+			t.Errorf("%s: got no SPARQL JSON to cache", test.name)
+		}
+	}
+}
+
+// TestCSVFallback checks that results are requested as CSV once JSON
+// results have failed to parse jsonAttempts times.
+func TestCSVFallback(t *testing.T) {
+	var jsonRequests, csvRequests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == csvAccept {
+			atomic.AddInt32(&csvRequests, 1)
+			w.Header().Set("Content-Type", csvAccept)
+			w.Write([]byte("format,formatLabel\nhttp://www.wikidata.org/entity/Q2192,GIF\n"))
+			return
+		}
+		atomic.AddInt32(&jsonRequests, 1)
+		w.Header().Set("Content-Type", "application/sparql-results+json")
+		w.Write([]byte(`{"head":{"vars":["format","formatLabel"]},"results":{"bindings":[{"format":`))
+	}))
+	defer srv.Close()
+	var logged bytes.Buffer
+	p := newTestPipeline(t, srv.URL)
+	p.Logger = log.New(&logged, "", 0)
+	if err := p.init(); err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.runResponse(context.Background(), "SELECT ?format ?formatLabel WHERE {}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonRequests != jsonAttempts || csvRequests != 1 {
+		t.Errorf("got %d JSON and %d CSV requests, want %d and 1", jsonRequests, csvRequests, jsonAttempts)
+	}
+	if len(res.Results.Bindings) != 1 || res.Results.Bindings[0]["formatLabel"].Value != "GIF" {
+		t.Errorf("got %v, want the CSV results", res.Results.Bindings)
+	}
+	if !strings.Contains(logged.String(), "requesting results as CSV") {
+		t.Errorf("the fallback was not logged: %s", logged.String())
+	}
+}
@@ -0,0 +1,143 @@
+package wdanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// WDQS can lag behind edits to Wikidata by minutes or hours. Items can be
+// fetched from the MediaWiki API instead, which is always current, so that
+// an edit made to fix a finding can be verified straight away. Fetched
+// items are turned into rows in the same way as the entities of a dump and
+// replace the records harvested for them.
+
+// DefaultAPI is the MediaWiki API of Wikidata.
+const DefaultAPI = "https://www.wikidata.org/w/api.php"
+
+// fetchBatch is the most entities wbgetentities returns for one request.
+const fetchBatch = 50
+
+// fetchEntities returns the entities with the given IDs from the pipeline's
+// API. Only the parts of entities in props are returned.
+func (p *Pipeline) fetchEntities(client *http.Client, ids []string, props string) ([]dumpEntity, error) {
+	var entities []dumpEntity
+	for start := 0; start < len(ids); start += fetchBatch {
+		end := start + fetchBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		values := neturl.Values{}
+		values.Set("action", "wbgetentities")
+		values.Set("format", "json")
+		values.Set("ids", strings.Join(ids[start:end], "|"))
+		values.Set("props", props)
+		values.Set("languages", "en")
+		resp, err := client.Get(fmt.Sprintf("%s?%s", p.API, values.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected response from server: %s", resp.Status)
+		}
+		var res struct {
+			Entities map[string]json.RawMessage `json:"entities"`
+			Error    struct {
+				Info string `json:"info"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return nil, err
+		}
+		if res.Error.Info != "" {
+			return nil, fmt.Errorf("%s", res.Error.Info)
+		}
+		for _, id := range ids[start:end] {
+			var e struct {
+				dumpEntity
+				Missing *string `json:"missing"`
+			}
+			if err := json.Unmarshal(res.Entities[id], &e); err != nil || e.Missing != nil {
+				p.Logger.Printf("%s does not exist", id)
+				continue
+			}
+			entities = append(entities, e.dumpEntity)
+		}
+	}
+	return entities, nil
+}
+
+// fetchRows returns the rows of the pipeline's Fetch items from its API.
+func (p *Pipeline) fetchRows(ctx context.Context) ([]map[string]spargo.Item, error) {
+	client := p.contextClient(ctx)
+	entities, err := p.fetchEntities(client, p.Fetch, "labels|claims|info")
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch entities from %s: %s", p.API, err)
+	}
+	d := p.newDumpReader()
+	for _, e := range entities {
+		d.collectLabels(e)
+	}
+	var ids []string
+	for id := range d.labels {
+		if qidPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	labelled, err := p.fetchEntities(client, ids, "labels")
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch labels from %s: %s", p.API, err)
+	}
+	for _, e := range labelled {
+		d.labels[e.ID] = e.label()
+	}
+	for _, e := range entities {
+		d.rows = append(d.rows, d.formatRows(e)...)
+	}
+	d.resolveLabels()
+	p.Logger.Printf("fetched %d of %d items from %s", len(entities), len(p.Fetch), p.API)
+	return d.rows, nil
+}
+
+// isFetched returns true if the record with an ID is replaced by a record
+// condensed from fetched rows.
+func (p *Pipeline) isFetched(id string) bool {
+	for _, row := range p.fetched {
+		if p.id(row[formatField].Value) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFetched replaces the records of the fetched items with records
+// condensed from the fetched rows, streaming them if they are kept.
+func (p *Pipeline) mergeFetched() {
+	for _, row := range p.fetched {
+		delete(p.records, p.id(row[formatField].Value))
+	}
+	var ids []string
+	for _, row := range p.fetched {
+		id := p.id(row[formatField].Value)
+		if _, ok := p.records[id]; !ok {
+			ids = append(ids, id)
+		}
+		p.condenseRow(row)
+	}
+	for _, id := range ids {
+		if p.matchesPUIDs(p.records[id]) {
+			p.streamRecord(id)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package wdanalysis
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// demoBody returns the demo corpus as the body of a SPARQL response.
+func demoBody(t *testing.T) []byte {
+	t.Helper()
+	human, err := sparqlJSON(demoResults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(human)
+}
+
+// newTestEndpoint returns an endpoint that answers every query with the
+// body returned by respond for the nth request, counting from 1.
+func newTestEndpoint(t *testing.T, respond func(n int32) []byte) *httptest.Server {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/sparql-results+json")
+		w.Write(respond(atomic.AddInt32(&requests, 1)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestPipeline returns a pipeline harvesting from an endpoint that keeps
+// its cache in a temporary directory.
+func newTestPipeline(t *testing.T, endpoint string) *Pipeline {
+	t.Helper()
+	return &Pipeline{
+		Endpoint: endpoint,
+		Cache:    filepath.Join(t.TempDir(), "results.json"),
+	}
+}
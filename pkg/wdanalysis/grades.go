@@ -0,0 +1,128 @@
+package wdanalysis
+
+// Each record is given a quality grade from A to E so that the health of
+// the corpus can be summarised in a single figure. A record scores up to 25
+// points for each of completeness, provenance, freedom from lint findings,
+// and the strength of its signatures.
+
+// Points available for each part of the score.
+const (
+	gradePart = 25
+
+	// strongSequence is the length in bytes of a sequence at which it
+	// scores full points for strength.
+	strongSequence = 8
+)
+
+// gradeBoundaries are the minimum scores for each grade, best first.
+var gradeBoundaries = []struct {
+	grade string
+	score int
+}{
+	{"A", 90},
+	{"B", 75},
+	{"C", 60},
+	{"D", 40},
+	{"E", 0},
+}
+
+// lintPenalties are the points deducted for each lint code found in a
+// record by category. Limitations of this tool are not held against a
+// record.
+var lintPenalties = map[lintCategory]int{
+	dataEntry:      5,
+	modelling:      3,
+	toolLimitation: 0,
+}
+
+// completenessScore scores the identifiers and signatures of a record.
+func completenessScore(wd Wikidata) int {
+	score := 0
+	if len(wd.Signatures) > 0 || wd.SignatureSnak == NoValue {
+		score += 10
+	}
+	if len(nonEmpty(wd.PRONOM)) > 0 {
+		score += 5
+	}
+	if len(nonEmpty(wd.Extension)) > 0 {
+		score += 5
+	}
+	if len(nonEmpty(wd.Mimetype)) > 0 {
+		score += 5
+	}
+	return score
+}
+
+// provenanceScore scores the proportion of signatures that have a source
+// and a date. Records asserted to have no signature need no provenance.
+func provenanceScore(wd Wikidata) int {
+	if len(wd.Signatures) == 0 {
+		if wd.SignatureSnak == NoValue {
+			return gradePart
+		}
+		return 0
+	}
+	sourced, dated := 0, 0
+	for _, signature := range wd.Signatures {
+		if signature.Provenance != "" {
+			sourced++
+		}
+		if signature.Date != "" {
+			dated++
+		}
+	}
+	return (sourced*15 + dated*10) / len(wd.Signatures)
+}
+
+// lintScore scores a record by the lint codes found against it.
+func lintScore(lints map[linting]int) int {
+	score := gradePart
+	for code := range lints {
+		score -= lintPenalties[lintCategories[code]]
+	}
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// strengthScore scores the longest sequence of a record. Longer sequences
+// are less likely to match files of other formats.
+func strengthScore(wd Wikidata) int {
+	longest := 0
+	for _, signature := range wd.Signatures {
+		if length := sequenceLength(signature); length > longest {
+			longest = length
+		}
+	}
+	if longest > strongSequence {
+		longest = strongSequence
+	}
+	return longest * gradePart / strongSequence
+}
+
+// grade returns the grade of a score.
+func grade(score int) string {
+	for _, boundary := range gradeBoundaries {
+		if score >= boundary.score {
+			return boundary.grade
+		}
+	}
+	return gradeBoundaries[len(gradeBoundaries)-1].grade
+}
+
+// analyseGrades grades every record and adds the distribution of grades to
+// the summary. Grades depend on lint findings so are recalculated whenever
+// findings are added.
+func (p *Pipeline) analyseGrades() {
+	p.summary.Grades = make(map[string]int)
+	for id, wd := range p.records {
+		score := completenessScore(wd) +
+			provenanceScore(wd) +
+			lintScore(p.summary.Lints[wd.URI]) +
+			strengthScore(wd)
+		wd.Grade = grade(score)
+		p.records[id] = wd
+		p.summary.Grades[wd.Grade]++
+	}
+}
@@ -0,0 +1,141 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Results without the statement of each sequence do not tell us which
+// sequences belong together in a single signature so we have to use
+// heuristics to group them. Heuristics can be compared against the same
+// cached harvest to understand the effect each has on the signatures we
+// create.
+
+// Heuristic groups the sequences of a record into signatures.
+type Heuristic func(sequences []Signature) [][]Signature
+
+// Heuristics are the heuristics we support by name.
+var Heuristics = map[string]Heuristic{
+	"single":          singleHeuristic,
+	"provenance-pair": provenanceHeuristic,
+	"relativity-pair": relativityHeuristic,
+	"statement":       statementHeuristic,
+}
+
+// HeuristicNames returns the names of the heuristics we support.
+func HeuristicNames() []string {
+	var names []string
+	for name := range Heuristics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// singleHeuristic treats every sequence as a signature of its own.
+func singleHeuristic(sequences []Signature) [][]Signature {
+	var signatures [][]Signature
+	for _, sequence := range sequences {
+		signatures = append(signatures, []Signature{sequence})
+	}
+	return signatures
+}
+
+// provenanceHeuristic groups sequences that share the same provenance and
+// date on the basis that they were likely contributed together.
+func provenanceHeuristic(sequences []Signature) [][]Signature {
+	var signatures [][]Signature
+	groups := make(map[string]int)
+	for _, sequence := range sequences {
+		key := fmt.Sprintf("%s\x00%s", sequence.Provenance, sequence.Date)
+		if idx, ok := groups[key]; ok {
+			signatures[idx] = append(signatures[idx], sequence)
+			continue
+		}
+		groups[key] = len(signatures)
+		signatures = append(signatures, []Signature{sequence})
+	}
+	return signatures
+}
+
+// relativityHeuristic pairs each sequence anchored to the beginning of a
+// file with the next unpaired sequence anchored to the end of the file.
+// Sequences that cannot be paired are treated as signatures of their own.
+func relativityHeuristic(sequences []Signature) [][]Signature {
+	var signatures [][]Signature
+	var eof []Signature
+	for _, sequence := range sequences {
+		if sequence.relativity() == wikidata.EOF {
+			eof = append(eof, sequence)
+		}
+	}
+	for _, sequence := range sequences {
+		if sequence.relativity() == wikidata.EOF {
+			continue
+		}
+		signature := []Signature{sequence}
+		if len(eof) > 0 {
+			signature = append(signature, eof[0])
+			eof = eof[1:]
+		}
+		signatures = append(signatures, signature)
+	}
+	for _, sequence := range eof {
+		signatures = append(signatures, []Signature{sequence})
+	}
+	return signatures
+}
+
+// describeSignatures returns a comparable description of the signatures
+// created by a heuristic.
+func describeSignatures(signatures [][]Signature) []string {
+	var descriptions []string
+	for _, signature := range signatures {
+		var sequences []string
+		for _, sequence := range signature {
+			sequences = append(sequences, fmt.Sprintf("%s:%s@%s(%s)",
+				sequence.Key,
+				sequence.Signature,
+				noneIfEmpty(sequence.Offset),
+				noneIfEmpty(sequence.Relativity),
+			))
+		}
+		descriptions = append(descriptions, strings.Join(sequences, " + "))
+	}
+	sort.Strings(descriptions)
+	return descriptions
+}
+
+// HeuristicDifference describes a record for which two heuristics create
+// different signatures.
+type HeuristicDifference struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	URI  string   `json:"uri"`
+	A    []string `json:"a"`
+	B    []string `json:"b"`
+}
+
+// CompareHeuristics returns the records for which heuristics a and b create
+// different signatures. Overrides are honoured over both heuristics.
+func (p *Pipeline) CompareHeuristics(a Heuristic, b Heuristic) []HeuristicDifference {
+	differences := []HeuristicDifference{}
+	for _, wd := range p.Records() {
+		resA := describeSignatures(p.Group(wd, a))
+		resB := describeSignatures(p.Group(wd, b))
+		if strings.Join(resA, "\n") == strings.Join(resB, "\n") {
+			continue
+		}
+		differences = append(differences, HeuristicDifference{
+			ID:   wd.ID,
+			Name: wd.Name,
+			URI:  wd.URI,
+			A:    resA,
+			B:    resB,
+		})
+	}
+	return differences
+}
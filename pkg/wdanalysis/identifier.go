@@ -0,0 +1,128 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Siegfried's Roy builds its Wikidata identifier from the SPARQL results of
+// its own harvest query. Building from our condensed records instead gives
+// the identifier the benefit of our conversions and policies. The records
+// are written as SPARQL results with the variables of Roy's query: one row
+// for each normalized sequence, and as many further rows as it takes to
+// hold every PUID, extension and MIME type of a format. Sequences that
+// could not be normalized are left out as Roy could not use them either.
+
+// identifierVars are the variables of Roy's harvest query.
+var identifierVars = []string{
+	"uri",
+	"uriLabel",
+	"puid",
+	"extension",
+	"mimetype",
+	"encoding",
+	"referenceLabel",
+	"date",
+	"relativity",
+	"offset",
+	"sig",
+}
+
+// sequenceEncoding returns the entity of the encoding of a normalized
+// sequence.
+func sequenceEncoding(sequence string) string {
+	if isHex(sequence) {
+		return wikidata.EncodingHexadecimal
+	}
+	return wikidata.EncodingPRONOM
+}
+
+// identifierRows returns the rows of a record in the identifier.
+func (p *Pipeline) identifierRows(wd Wikidata) []map[string]spargo.Item {
+	var sequences []map[string]spargo.Item
+	for _, s := range wd.Signatures {
+		if s.Normalized == "" {
+			continue
+		}
+		row := map[string]spargo.Item{
+			"sig":      {Type: "literal", Value: s.Normalized},
+			"encoding": {Type: "uri", Value: sequenceEncoding(s.Normalized)},
+		}
+		if s.Offset != "" {
+			row["offset"] = spargo.Item{Type: "literal", Value: s.Offset}
+		}
+		if relativity := p.Config.Entities.URI(s.relativity()); relativity != "" {
+			row["relativity"] = spargo.Item{Type: "uri", Value: relativity}
+		}
+		if s.Provenance != "" {
+			row["referenceLabel"] = spargo.Item{Type: "literal", Value: s.Provenance}
+		}
+		if s.Date != "" {
+			row["date"] = spargo.Item{Type: "literal", Value: s.Date}
+		}
+		sequences = append(sequences, row)
+	}
+	fields := map[string][]string{
+		"puid":      nonEmpty(wd.PRONOM),
+		"extension": nonEmpty(wd.Extension),
+		"mimetype":  nonEmpty(wd.Mimetype),
+	}
+	count := len(sequences)
+	for _, values := range fields {
+		if len(values) > count {
+			count = len(values)
+		}
+	}
+	var rows []map[string]spargo.Item
+	for idx := 0; idx == 0 || idx < count; idx++ {
+		row := map[string]spargo.Item{
+			"uri":      {Type: "uri", Value: wd.URI},
+			"uriLabel": {Type: "literal", Value: wd.Name},
+		}
+		for field, values := range fields {
+			if idx < len(values) {
+				row[field] = spargo.Item{Type: "literal", Value: values[idx]}
+			}
+		}
+		if idx < len(sequences) {
+			for key, value := range sequences[idx] {
+				row[key] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// IdentifierExporter writes the records as SPARQL results that Roy can
+// build a Wikidata identifier from to Path.
+type IdentifierExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e IdentifierExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	var res spargo.SPARQLResult
+	res.Head = map[string]interface{}{"vars": identifierVars}
+	for _, wd := range records {
+		res.Results.Bindings = append(res.Results.Bindings, p.identifierRows(wd)...)
+	}
+	human, err := sparqlJSON(res)
+	if err != nil {
+		return fmt.Errorf("cannot build identifier: %s", err)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(human), "", "  "); err != nil {
+		return fmt.Errorf("cannot build identifier: %s", err)
+	}
+	out.WriteString("\n")
+	return p.writeExport(e.Path, out.Bytes())
+}
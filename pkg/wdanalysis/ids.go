@@ -0,0 +1,47 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Records are keyed by an ID taken from their concept URI. By default the ID
+// is the last segment of the URI, e.g. Q12345, which suits Wikidata. Private
+// Wikibase instances may use concept URIs of another shape so the ID can be
+// taken by stripping a prefix or with a regular expression instead.
+
+// IDConfig describes how IDs are taken from concept URIs. Pattern is used
+// if both are set. URIs that do not match fall back to the default.
+type IDConfig struct {
+	Prefix  string `json:"prefix"`  // Concept URI base to strip, e.g. https://wikibase.example.org/entity/.
+	Pattern string `json:"pattern"` // Regular expression whose first group is the ID.
+}
+
+// idScheme returns a function taking the ID from a concept URI.
+func (cfg IDConfig) idScheme() (func(string) string, error) {
+	if cfg.Pattern != "" {
+		re, err := regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID pattern '%s': %s", cfg.Pattern, err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("ID pattern '%s' has no group to capture the ID", cfg.Pattern)
+		}
+		return func(uri string) string {
+			if match := re.FindStringSubmatch(uri); match != nil && match[1] != "" {
+				return match[1]
+			}
+			return getID(uri)
+		}, nil
+	}
+	if cfg.Prefix != "" {
+		return func(uri string) string {
+			if strings.HasPrefix(uri, cfg.Prefix) && uri != cfg.Prefix {
+				return strings.TrimPrefix(uri, cfg.Prefix)
+			}
+			return getID(uri)
+		}, nil
+	}
+	return getID, nil
+}
@@ -0,0 +1,127 @@
+package wdanalysis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A full harvest is wasteful when it is run daily and few records change
+// between runs. An incremental harvest asks only for the records modified
+// since the newest record of a previous export and merges them into it.
+// Records that are no longer file formats, or have been deleted, are found
+// by asking for the IDs of every file format which is much cheaper than
+// harvesting them.
+
+// formatIDsQuery returns every file format.
+var formatIDsQuery = `
+	SELECT DISTINCT ?format WHERE
+	{
+	  ?format wdt:<<instanceOf>>/wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	}
+`
+
+// Incremental describes how an incremental harvest changed the records of
+// the previous export.
+type Incremental struct {
+	Since   string   `json:"since"`
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Removed []string `json:"removed"`
+}
+
+// since returns when the newest record of the baseline was modified.
+func (b Baseline) since() (time.Time, error) {
+	var newest time.Time
+	for _, wd := range b.wikidata {
+		modified, err := time.Parse(time.RFC3339, wd.Modified)
+		if err != nil {
+			continue
+		}
+		if modified.After(newest) {
+			newest = modified
+		}
+	}
+	if newest.IsZero() {
+		return newest, fmt.Errorf("no record of the baseline has a modification date")
+	}
+	return newest, nil
+}
+
+// sinceQuery restricts a harvest query to formats modified after since.
+func sinceQuery(query string, since time.Time) (string, error) {
+	filter := fmt.Sprintf("?format schema:dateModified ?modified. FILTER(?modified > \"%s\"^^xsd:dateTime)",
+		since.UTC().Format(time.RFC3339))
+	return insertWhere(query, filter)
+}
+
+// harvestFormatIDs returns the IDs of every file format in the Wikibase.
+func (p *Pipeline) harvestFormatIDs(ctx context.Context) (map[string]bool, error) {
+	results, err := p.runQuery(ctx, buildQuery(formatIDsQuery, p.Config.Properties))
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool)
+	for _, result := range results {
+		ids[p.id(result[formatField].Value)] = true
+	}
+	return ids, nil
+}
+
+// mergeIncremental merges the records of an incremental harvest into the
+// records of the previous export. Records of the previous export are kept
+// unless they are missing from current, the IDs of every file format, when
+// it is known.
+func (p *Pipeline) mergeIncremental(current map[string]bool) {
+	since, _ := p.Incremental.since()
+	report := &Incremental{
+		Since:   since.UTC().Format(time.RFC3339),
+		Added:   []string{},
+		Updated: []string{},
+		Removed: []string{},
+	}
+	var kept []string
+	for id := range p.records {
+		if _, ok := p.Incremental.wikidata[id]; ok {
+			report.Updated = append(report.Updated, id)
+		} else {
+			report.Added = append(report.Added, id)
+		}
+	}
+	for id, wd := range p.Incremental.wikidata {
+		if _, ok := p.records[id]; ok {
+			continue
+		}
+		if current != nil && !current[id] {
+			report.Removed = append(report.Removed, id)
+			continue
+		}
+		p.records[id] = p.restoreRecord(wd)
+		kept = append(kept, id)
+	}
+	// Records kept from the previous export were not harvested, so they are
+	// streamed once they are merged.
+	sort.Strings(kept)
+	for _, id := range kept {
+		p.streamRecord(id)
+	}
+	sort.Strings(report.Added)
+	sort.Strings(report.Updated)
+	sort.Strings(report.Removed)
+	p.summary.Incremental = report
+}
+
+// restoreRecord prepares a record of a previous export to be analysed
+// again. What is not exported about its signatures is derived once more.
+func (p *Pipeline) restoreRecord(wd Wikidata) Wikidata {
+	signatures := make([]Signature, len(wd.Signatures))
+	for idx, s := range wd.Signatures {
+		s.rel = parseRelativity(p.Config.Entities, s.RelativityURI, s.Relativity)
+		s.Normalized, s.conversionErr = p.normalizeSignature(s.Signature, s.Encoding)
+		s.Key = s.key()
+		signatures[idx] = s
+	}
+	wd.Signatures = signatures
+	return wd
+}
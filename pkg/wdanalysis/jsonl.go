@@ -0,0 +1,103 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Downstream pipelines, e.g. jq or Spark, can start on a big harvest long
+// before it ends if each record is written as a line of JSON as soon as it
+// is complete. The harvest query orders its results by format, so a record
+// is complete when the rows of the next format begin. Streamed records are
+// condensed from Wikidata and normalized but come before the analyses that
+// need every record, so they are not graded. Rows are never held, and
+// streamed records can be consumed without waiting for the rest.
+//
+// Streamed records are the records the run keeps. Records of items fetched
+// from the API are streamed in place of their harvested records, records
+// not mapped to the pipeline's PUIDs are not streamed, and the records of
+// a previous export kept by an incremental harvest are streamed once it
+// has been merged.
+//
+// Unlike the exports written at the end of a run, streaming depends on the
+// order of the results: a record whose rows are not together would be
+// streamed before it is complete. Records are only streamed from a query
+// ordered by ?format.
+//
+// If a streamed response cannot be parsed the results are requested again
+// and processed from the start. The record whose rows were being read when
+// parsing failed is not streamed, and records streamed before it, which
+// were complete, are not streamed a second time.
+
+// orderedByFormat matches a query whose results are ordered by ?format
+// before anything else.
+var orderedByFormat = regexp.MustCompile(`(?is)\border\s+by\s+(asc\s*\(\s*)?\?format\b`)
+
+// validateStream checks that the records of the pipeline's harvest query
+// can be streamed.
+func (p *Pipeline) validateStream() error {
+	query := p.harvestQuery()
+	if p.PageSize > 0 {
+		query = pageQuery(query, p.PageSize, 0)
+	}
+	if !orderedByFormat.MatchString(query) {
+		return fmt.Errorf("records can only be streamed from a query ordered by ?format")
+	}
+	return nil
+}
+
+// streamRow streams the record of the previous row if the row with an ID
+// begins another record.
+func (p *Pipeline) streamRow(id string) {
+	if p.Stream == nil || id == p.streaming {
+		return
+	}
+	if p.begun == nil {
+		p.begun = make(map[string]bool)
+	}
+	if p.begun[id] {
+		p.Logger.Printf("rows of %s are not together in the results, its streamed record is incomplete", id)
+	}
+	p.begun[id] = true
+	p.streamFlush()
+	p.streaming = id
+}
+
+// streamFlush streams the record whose rows are being condensed, if any,
+// unless it is replaced by a fetched record or is not mapped to the
+// pipeline's PUIDs.
+func (p *Pipeline) streamFlush() {
+	if p.Stream == nil || p.streaming == "" {
+		return
+	}
+	id := p.streaming
+	p.streaming = ""
+	if p.isFetched(id) || !p.matchesPUIDs(p.records[id]) {
+		return
+	}
+	p.streamRecord(id)
+}
+
+// streamRecord streams the record with an ID unless it has been streamed
+// already. Records that cannot be written are logged as the harvest can
+// continue.
+func (p *Pipeline) streamRecord(id string) {
+	if p.Stream == nil {
+		return
+	}
+	if p.streamed == nil {
+		p.streamed = make(map[string]bool)
+	}
+	if p.streamed[id] {
+		return
+	}
+	p.streamed[id] = true
+	out, err := json.Marshal(styleRecords([]Wikidata{p.records[id]}, p.HexStyle)[0])
+	if err == nil {
+		_, err = fmt.Fprintf(p.Stream, "%s\n", out)
+	}
+	if err != nil {
+		p.Logger.Printf("cannot stream %s: %s", id, err)
+	}
+}
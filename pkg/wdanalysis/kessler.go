@@ -0,0 +1,77 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Gary Kessler's File Signatures Table is a widely used corpus of file
+// signatures and a common source of Wikidata's. It is published as JSON,
+// file_sigs.json, with a header and, optionally, a trailer per format.
+
+// kesslerNull is the value used in the table where there is no trailer.
+const kesslerNull = "(null)"
+
+// kesslerTable describes the parts of the Kessler table we need.
+type kesslerTable struct {
+	Signatures []struct {
+		Description string `json:"File description"`
+		Header      string `json:"Header (hex)"`
+		Extensions  string `json:"File extension"`
+		Offset      string `json:"Header offset"`
+		Trailer     string `json:"Trailer (hex)"`
+	} `json:"filesigs"`
+}
+
+// Kessler holds the sequences of the Kessler table.
+type Kessler struct {
+	sequences []externalSequence
+}
+
+// LoadKessler reads the Kessler table from its JSON form.
+func LoadKessler(path string) (Kessler, error) {
+	var kessler Kessler
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return kessler, err
+	}
+	var table kesslerTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return kessler, err
+	}
+	for _, sig := range table.Signatures {
+		var extensions []string
+		for _, ext := range strings.Split(sig.Extensions, "|") {
+			if ext = strings.TrimSpace(ext); ext != "" && ext != kesslerNull && ext != "(none)" {
+				extensions = append(extensions, ext)
+			}
+		}
+		if header := strings.TrimSpace(sig.Header); header != "" && header != kesslerNull {
+			kessler.sequences = append(kessler.sequences, externalSequence{
+				Pattern:     normalizeExternal(header),
+				Relativity:  wikidata.BOF,
+				Offset:      strings.TrimSpace(sig.Offset),
+				Description: sig.Description,
+				Extensions:  extensions,
+			})
+		}
+		if trailer := strings.TrimSpace(sig.Trailer); trailer != "" && trailer != kesslerNull {
+			kessler.sequences = append(kessler.sequences, externalSequence{
+				Pattern:     normalizeExternal(trailer),
+				Relativity:  wikidata.EOF,
+				Description: sig.Description,
+				Extensions:  extensions,
+			})
+		}
+	}
+	return kessler, nil
+}
+
+// CompareKessler compares the sequences of the processed records with the
+// Kessler table.
+func (p *Pipeline) CompareKessler(kessler Kessler) Comparison {
+	return p.compareSequences(kessler.sequences)
+}
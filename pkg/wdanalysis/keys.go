@@ -0,0 +1,38 @@
+package wdanalysis
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Every sequence is given a key derived from its canonical pattern and
+// anchor so that sequence-level data in different reports, and from
+// different runs, can be joined reliably. Sequences without a relativity
+// are anchored to the beginning of the file as they are elsewhere.
+
+// keyLength is the number of hexadecimal characters in a sequence key.
+const keyLength = 16
+
+// sequenceKey returns the key of a sequence. Patterns that could not be
+// normalized are keyed on their value as recorded.
+func sequenceKey(pattern string, relativity wikidata.Relativity, offset string) string {
+	if relativity == wikidata.Unknown {
+		relativity = wikidata.BOF
+	}
+	if offset == "" {
+		offset = "0"
+	}
+	canonical := fmt.Sprintf("%s\x00%s\x00%s", pattern, relativity, offset)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(canonical)))[:keyLength]
+}
+
+// key returns the key of a signature's sequence.
+func (s Signature) key() string {
+	pattern := s.Normalized
+	if pattern == "" {
+		pattern = s.Signature
+	}
+	return sequenceKey(pattern, s.relativity(), s.Offset)
+}
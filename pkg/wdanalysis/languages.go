@@ -0,0 +1,74 @@
+package wdanalysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Consortia that span several countries want format names in each of
+// their languages. Labels are harvested for each language separately and
+// merged into the records of the main harvest, which is run once so that
+// the qualifiers of signatures, e.g. encodings, keep the English labels
+// the analysis relies upon.
+
+// labelsQuery returns the label and aliases of every file format in a
+// single language. Formats without a label in the language are returned
+// with their QID.
+var labelsQuery = `
+	SELECT DISTINCT ?format ?formatLabel ?alias WHERE
+	{
+	  ?format wdt:<<instanceOf>>/wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	  OPTIONAL { ?format skos:altLabel ?alias. FILTER(LANG(?alias) = "<<lang>>") }
+	  SERVICE wikibase:label { bd:serviceParam wikibase:language "<<lang>>". }
+	}
+`
+
+// ParseLanguages splits a comma separated list of language codes.
+func ParseLanguages(value string) []string {
+	var languages []string
+	for _, lang := range strings.Split(value, ",") {
+		lang = strings.TrimSpace(lang)
+		if lang != "" && !contains(languages, lang) {
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// harvestLabels adds the label and aliases of each record in every one of
+// the pipeline's languages. Records are matched on their ID so each appears
+// once however many languages are harvested.
+func (p *Pipeline) harvestLabels(ctx context.Context) error {
+	for _, lang := range p.Languages {
+		query := strings.Replace(buildQuery(labelsQuery, p.Config.Properties), "<<lang>>", lang, -1)
+		results, err := p.runQuery(ctx, query)
+		if err != nil {
+			return fmt.Errorf("cannot harvest '%s' labels: %s", lang, err)
+		}
+		labelled := 0
+		for _, result := range results {
+			id := p.id(result[formatField].Value)
+			wd, ok := p.records[id]
+			if !ok {
+				continue
+			}
+			if label := result[labelField].Value; label != "" && label != id && wd.Labels[lang] == "" {
+				if wd.Labels == nil {
+					wd.Labels = make(map[string]string)
+				}
+				wd.Labels[lang] = label
+				labelled++
+			}
+			if alias := result["alias"].Value; alias != "" && !contains(wd.Aliases[lang], alias) {
+				if wd.Aliases == nil {
+					wd.Aliases = make(map[string][]string)
+				}
+				wd.Aliases[lang] = append(wd.Aliases[lang], alias)
+			}
+			p.records[id] = wd
+		}
+		p.Logger.Printf("harvested %d '%s' labels", labelled, lang)
+	}
+	return nil
+}
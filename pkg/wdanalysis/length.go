@@ -0,0 +1,92 @@
+package wdanalysis
+
+import (
+	"fmt"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Some sequences in Wikidata are pathologically long, e.g. a dump of an
+// entire file header. Long sequences are costly for matchers and unlikely
+// to be intended so a maximum length can be set for exports. Sequences that
+// exceed it are truncated, which is the default, or excluded.
+
+// Length policies.
+const (
+	LengthTruncate = "truncate"
+	LengthExclude  = "exclude"
+)
+
+// LengthPolicies are the length policies we support.
+var LengthPolicies = []string{LengthTruncate, LengthExclude}
+
+// sequenceLength returns the length of a sequence in bytes. Sequences that
+// could not be normalized are measured as recorded.
+func sequenceLength(s Signature) int {
+	if s.Normalized != "" {
+		return len(s.Normalized) / 2
+	}
+	return len(s.Signature)
+}
+
+// lintLength records a finding for every sequence longer than max bytes.
+func lintLength(summary *Summary, wd Wikidata, max int) {
+	if max <= 0 {
+		return
+	}
+	for _, signature := range wd.Signatures {
+		if sequenceLength(signature) > max {
+			summary.lint(wd.URI, lenWDE01)
+		}
+	}
+}
+
+// truncateSequence returns a sequence cut to max bytes. Sequences anchored
+// to the end of a file keep their last bytes so that their offset is still
+// correct.
+func truncateSequence(s Signature, max int) Signature {
+	eof := s.relativity() == wikidata.EOF
+	if s.Normalized != "" {
+		if eof {
+			s.Normalized = s.Normalized[len(s.Normalized)-max*2:]
+		} else {
+			s.Normalized = s.Normalized[:max*2]
+		}
+		return s
+	}
+	if eof {
+		s.Signature = s.Signature[len(s.Signature)-max:]
+	} else {
+		s.Signature = s.Signature[:max]
+	}
+	return s
+}
+
+// applyLengthPolicy returns the records to export with the length policy
+// applied to sequences longer than max bytes. Excluded sequences are
+// recorded in excluded.
+func applyLengthPolicy(records []Wikidata, max int, policy string, excluded *Exclusions) ([]Wikidata, error) {
+	if policy != "" && !contains(LengthPolicies, policy) {
+		return nil, fmt.Errorf("unknown length policy '%s', expected one of: %v", policy, LengthPolicies)
+	}
+	if max <= 0 {
+		return records, nil
+	}
+	var capped []Wikidata
+	for _, wd := range records {
+		var signatures []Signature
+		for _, signature := range wd.Signatures {
+			if sequenceLength(signature) > max {
+				if policy == LengthExclude {
+					excluded.add(wd, signature, ExcludedLength)
+					continue
+				}
+				signature = truncateSequence(signature, max)
+			}
+			signatures = append(signatures, signature)
+		}
+		wd.Signatures = signatures
+		capped = append(capped, wd)
+	}
+	return capped, nil
+}
@@ -0,0 +1,185 @@
+package wdanalysis
+
+import (
+	"sort"
+)
+
+// Lint codes describe the problems we find in Wikidata records. They are
+// reported per record with a count of the number of times each was found.
+// The prefix describes the part of the record the lint relates to.
+//
+// Each code belongs to a category which describes who can fix the problem:
+// editors correcting data, the community changing how Wikidata models
+// signatures, or the maintainers of this tool.
+
+type linting string
+
+type lintCategory string
+
+const (
+	proWDE01 linting = "proWDE01"
+	proWDE02 linting = "proWDE02"
+	encWDE01 linting = "encWDE01"
+	encWDE02 linting = "encWDE02"
+	relWDE01 linting = "relWDE01"
+	relWDE02 linting = "relWDE02"
+	heuWDE01 linting = "heuWDE01"
+	heuWDE02 linting = "heuWDE02"
+	seqWDE01 linting = "seqWDE01"
+	lenWDE01 linting = "lenWDE01"
+	extWDE01 linting = "extWDE01"
+	cnvWDE01 linting = "cnvWDE01"
+	cnvWDE02 linting = "cnvWDE02"
+	cnvWDE03 linting = "cnvWDE03"
+	cnvWDE04 linting = "cnvWDE04"
+	cnvWDE05 linting = "cnvWDE05"
+	rnkWDE01 linting = "rnkWDE01"
+)
+
+var lintDescriptions = map[linting]string{
+	proWDE01: "signature has no provenance",
+	proWDE02: "signature provenance has no date",
+	encWDE01: "signature has no encoding",
+	encWDE02: "encoding label contradicts value",
+	relWDE01: "signature has no relativity, assuming BOF",
+	relWDE02: "no signature in the record has a relativity, assuming BOF for all",
+	heuWDE01: "record has multiple sequences which can only be grouped into signatures heuristically",
+	heuWDE02: "signature could not be converted",
+	seqWDE01: "sequence is duplicated in another statement",
+	lenWDE01: "sequence is longer than the maximum length for exports",
+	extWDE01: "extension is not lowercase without a leading dot",
+	cnvWDE01: "signature could not be converted: invalid hexadecimal digit",
+	cnvWDE02: "signature could not be converted: odd number of hexadecimal digits",
+	cnvWDE03: "signature could not be converted: unsupported PRONOM construct",
+	cnvWDE04: "signature could not be converted: malformed GUID",
+	cnvWDE05: "ASCII signature escapes whitespace, assumed to mean the bytes escaped",
+	rnkWDE01: "signature statement is deprecated",
+}
+
+const (
+	dataEntry      lintCategory = "data-entry"
+	modelling      lintCategory = "modelling"
+	toolLimitation lintCategory = "tool-limitation"
+)
+
+var lintCategories = map[linting]lintCategory{
+	proWDE01: dataEntry,
+	proWDE02: dataEntry,
+	encWDE01: dataEntry,
+	encWDE02: dataEntry,
+	relWDE01: dataEntry,
+	relWDE02: dataEntry,
+	seqWDE01: dataEntry,
+	lenWDE01: dataEntry,
+	extWDE01: dataEntry,
+	cnvWDE01: dataEntry,
+	cnvWDE02: dataEntry,
+	cnvWDE04: dataEntry,
+	cnvWDE05: dataEntry,
+	rnkWDE01: dataEntry,
+	heuWDE01: modelling,
+	heuWDE02: toolLimitation,
+	cnvWDE03: toolLimitation,
+}
+
+// LintResult describes a single finding against a record. Findings are
+// withdrawn when a better description of the problem is found, e.g. a
+// finding for every signature missing its relativity is replaced by a
+// single finding for the record.
+type LintResult struct {
+	URI         string `json:"uri"`
+	Code        string `json:"code"`
+	Category    string `json:"category"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	Withdrawn   bool   `json:"withdrawn"`
+}
+
+// newLintResult describes a finding.
+func newLintResult(uri string, code linting, withdrawn bool) LintResult {
+	return LintResult{
+		URI:         uri,
+		Code:        string(code),
+		Category:    string(lintCategories[code]),
+		Severity:    string(lintSeverities[code]),
+		Description: lintDescriptions[code],
+		Withdrawn:   withdrawn,
+	}
+}
+
+// worklists returns the records with findings in each lint category.
+func worklists(lints map[string]map[linting]int) map[lintCategory][]string {
+	lists := make(map[lintCategory][]string)
+	for uri, codes := range lints {
+		categories := make(map[lintCategory]bool)
+		for code := range codes {
+			categories[lintCategories[code]] = true
+		}
+		for category := range categories {
+			lists[category] = append(lists[category], uri)
+		}
+	}
+	for category := range lists {
+		sort.Strings(lists[category])
+	}
+	return lists
+}
+
+// lint records a finding against a record in the summary.
+func (summary *Summary) lint(uri string, code linting) {
+	if summary.Lints == nil {
+		summary.Lints = make(map[string]map[linting]int)
+		summary.LintRecords = make(map[linting]int)
+		summary.LintCodes = make(map[linting]string)
+	}
+	if summary.Lints[uri] == nil {
+		summary.Lints[uri] = make(map[linting]int)
+	}
+	if summary.Lints[uri][code] == 0 {
+		summary.LintRecords[code]++
+	}
+	summary.Lints[uri][code]++
+	summary.LintCodes[code] = lintDescriptions[code]
+	if summary.onLint != nil {
+		summary.onLint(newLintResult(uri, code, false))
+	}
+}
+
+// unlint removes all findings of a given code from a record.
+func (summary *Summary) unlint(uri string, code linting) {
+	if summary.Lints[uri][code] == 0 {
+		return
+	}
+	delete(summary.Lints[uri], code)
+	if summary.onLint != nil {
+		summary.onLint(newLintResult(uri, code, true))
+	}
+	summary.LintRecords[code]--
+	if summary.LintRecords[code] == 0 {
+		delete(summary.LintRecords, code)
+		delete(summary.LintCodes, code)
+	}
+}
+
+// lintRecord records findings that can only be made by looking at all of
+// the signatures of a record together. Where every signature in a record is
+// missing its relativity we report a single record-level finding instead of
+// one finding per signature. Records missing a relativity are counted once
+// however many of their signatures are missing it.
+func (summary *Summary) lintRecord(wd Wikidata) {
+	missing := 0
+	for _, signature := range wd.Signatures {
+		if signature.Relativity == "" {
+			missing++
+		}
+	}
+	if missing == 0 {
+		return
+	}
+	summary.ErrNoRelativity++
+	if missing < len(wd.Signatures) {
+		return
+	}
+	summary.unlint(wd.URI, relWDE01)
+	summary.lint(wd.URI, relWDE02)
+}
@@ -0,0 +1,44 @@
+package wdanalysis
+
+import (
+	"testing"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// TestNoRelativity checks that records missing a relativity are counted
+// once each and that a single record-level finding replaces the findings
+// of each signature when every signature of a record is missing it.
+func TestNoRelativity(t *testing.T) {
+	records := []Wikidata{
+		{URI: "all", Signatures: []Signature{{Signature: "00"}, {Signature: "01"}, {Signature: "02"}}},
+		{URI: "some", Signatures: []Signature{{Signature: "00", Relativity: wikidata.BOF.String()}, {Signature: "01"}}},
+		{URI: "none", Signatures: []Signature{{Signature: "00", Relativity: wikidata.BOF.String()}}},
+	}
+	var summary Summary
+	for _, wd := range records {
+		for _, signature := range wd.Signatures {
+			signature.analyseSignature(&summary, wd.URI)
+		}
+		summary.lintRecord(wd)
+	}
+	if summary.ErrNoRelativity != 2 {
+		t.Errorf("got %d records missing a relativity, want 2", summary.ErrNoRelativity)
+	}
+	tests := []struct {
+		uri    string
+		code   linting
+		events int
+	}{
+		{"all", relWDE01, 0},
+		{"all", relWDE02, 1},
+		{"some", relWDE01, 1},
+		{"some", relWDE02, 0},
+		{"none", relWDE01, 0},
+	}
+	for _, test := range tests {
+		if got := summary.Lints[test.uri][test.code]; got != test.events {
+			t.Errorf("%s: got %d %s findings, want %d", test.uri, got, test.code, test.events)
+		}
+	}
+}
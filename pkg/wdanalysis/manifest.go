@@ -0,0 +1,77 @@
+package wdanalysis
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// The harvest manifest describes where and when the data in an export came
+// from, including the exact query so that a consumer can state precisely
+// what built it. Harvests in pages or partitions record the query before it
+// was split. The manifest of records read from a dump names the dump in
+// place of the endpoint and has no query. Replayed results, e.g. with
+// -input or -offline, may have come from anywhere, so their manifest names
+// the file and its hash in place of the endpoint and query, and records
+// when the file was last written as the time they were harvested.
+
+// Manifest describes a harvest.
+type Manifest struct {
+	Tool         string `json:"tool"`
+	Endpoint     string `json:"endpoint,omitempty"`
+	Query        string `json:"query,omitempty"`
+	QuerySHA256  string `json:"querySHA256,omitempty"`
+	Source       string `json:"source,omitempty"`       // Path of the replayed results.
+	SourceSHA256 string `json:"sourceSHA256,omitempty"` // Hash of the replayed results.
+	Demo         bool   `json:"demo,omitempty"`         // The replayed results are the demo corpus, not Wikidata.
+	Harvested    string `json:"harvested"`
+	Records      int    `json:"records"`
+	Signatures   int    `json:"signatures"`
+}
+
+// origin returns where the records of a manifest came from, the endpoint,
+// dump, replayed file, or demo corpus.
+func (m Manifest) origin() string {
+	if m.Demo {
+		return "the demo corpus"
+	}
+	if m.Source != "" {
+		return m.Source
+	}
+	return m.Endpoint
+}
+
+// newManifest describes the harvest of the pipeline's records.
+func (p *Pipeline) newManifest(query string, harvested time.Time) Manifest {
+	signatures := 0
+	for _, wd := range p.records {
+		signatures += len(wd.Signatures)
+	}
+	manifest := Manifest{
+		Tool:       p.Tool,
+		Endpoint:   p.Endpoint,
+		Query:      query,
+		Harvested:  harvested.UTC().Format(time.RFC3339),
+		Records:    len(p.records),
+		Signatures: signatures,
+	}
+	switch {
+	case p.Input != "":
+		manifest.Endpoint = ""
+		manifest.Query = ""
+		manifest.Source = p.Input
+		if path, err := filepath.Abs(p.Input); err == nil {
+			manifest.Source = path
+		}
+		manifest.SourceSHA256 = p.inputSHA256
+		manifest.Demo = p.Demo
+	case p.Dump != "":
+		manifest.Endpoint = filepath.Base(p.Dump)
+		manifest.Query = ""
+	}
+	if manifest.Query != "" {
+		manifest.QuerySHA256 = fmt.Sprintf("%x", sha256.Sum256([]byte(manifest.Query)))
+	}
+	return manifest
+}
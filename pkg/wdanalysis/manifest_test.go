@@ -0,0 +1,57 @@
+package wdanalysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestReplayManifest checks that the manifest of replayed results names
+// the file replayed rather than an endpoint and query it may not have come
+// from.
+func TestReplayManifest(t *testing.T) {
+	body := demoBody(t)
+	input := filepath.Join(t.TempDir(), "results.json")
+	if err := ioutil.WriteFile(input, body, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestPipeline(t, DefaultEndpoint)
+	p.Input = input
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	manifest := p.Manifest()
+	if manifest.Endpoint != "" || manifest.Query != "" || manifest.QuerySHA256 != "" {
+		t.Errorf("got endpoint %q and query %q (%s), want neither", manifest.Endpoint, manifest.Query, manifest.QuerySHA256)
+	}
+	if manifest.Source != input {
+		t.Errorf("got source %q, want %q", manifest.Source, input)
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256(body)); manifest.SourceSHA256 != want {
+		t.Errorf("got source hash %s, want %s", manifest.SourceSHA256, want)
+	}
+}
+
+// TestHarvestManifest checks that the manifest of a harvest names the
+// endpoint and query.
+func TestHarvestManifest(t *testing.T) {
+	body := demoBody(t)
+	srv := newTestEndpoint(t, func(int32) []byte { return body })
+	p := newTestPipeline(t, srv.URL)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	manifest := p.Manifest()
+	if manifest.Endpoint != srv.URL {
+		t.Errorf("got endpoint %q, want %q", manifest.Endpoint, srv.URL)
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte(p.harvestQuery()))); manifest.Query == "" || manifest.QuerySHA256 != want {
+		t.Errorf("got query hash %s, want %s", manifest.QuerySHA256, want)
+	}
+	if manifest.Source != "" || manifest.SourceSHA256 != "" {
+		t.Errorf("got source %q (%s), want none", manifest.Source, manifest.SourceSHA256)
+	}
+}
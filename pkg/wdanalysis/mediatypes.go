@@ -0,0 +1,67 @@
+package wdanalysis
+
+import (
+	"strings"
+)
+
+// Archives often collect in one domain, e.g. images or audio, and want to
+// know how well it is covered without reading every record. Records are
+// grouped by the top-level types of their MIME types, e.g. image for
+// image/png, and the summary counts the records of each type, those with
+// signatures, and their signatures. A record with MIME types of several
+// top-level types is counted under each, and one without a MIME type is
+// counted under none.
+
+// noMediaType is the top-level type records without a MIME type are
+// counted under.
+const noMediaType = "none"
+
+// MediaTypeStats describes the records of a top-level MIME type.
+type MediaTypeStats struct {
+	Records        int     `json:"records"`
+	WithSignatures int     `json:"withSignatures"` // Records with at least one signature.
+	Signatures     int     `json:"signatures"`
+	Coverage       float64 `json:"coverage"` // Proportion of records with a signature.
+}
+
+// topLevelType returns the top-level type of a MIME type, e.g. image for
+// image/png, or an empty string if it is not a MIME type.
+func topLevelType(mimetype string) string {
+	parts := strings.SplitN(strings.TrimSpace(mimetype), "/", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return ""
+	}
+	return strings.ToLower(parts[0])
+}
+
+// analyseMediaTypes adds the records and signatures of each top-level MIME
+// type to the summary.
+func analyseMediaTypes(summary *Summary, records []Wikidata) {
+	stats := make(map[string]*MediaTypeStats)
+	for _, wd := range records {
+		types := make(map[string]bool)
+		for _, mimetype := range nonEmpty(wd.Mimetype) {
+			if t := topLevelType(mimetype); t != "" {
+				types[t] = true
+			}
+		}
+		if len(types) == 0 {
+			types[noMediaType] = true
+		}
+		for t := range types {
+			if stats[t] == nil {
+				stats[t] = &MediaTypeStats{}
+			}
+			stats[t].Records++
+			stats[t].Signatures += len(wd.Signatures)
+			if len(wd.Signatures) > 0 {
+				stats[t].WithSignatures++
+			}
+		}
+	}
+	summary.MediaTypes = make(map[string]MediaTypeStats)
+	for t, s := range stats {
+		s.Coverage = float64(s.WithSignatures) / float64(s.Records)
+		summary.MediaTypes[t] = *s
+	}
+}
@@ -0,0 +1,218 @@
+package wdanalysis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// requestTimeout is the maximum time we will wait on any one request.
+const requestTimeout = 5 * time.Minute
+
+// The Wikidata Query Service asks clients to identify themselves with a
+// descriptive User-Agent and to be considerate of its capacity. Every
+// request names the tool, its version, and how to contact whoever is
+// running it, and the pages of a harvest are requested with a delay
+// between them. spargo sets its own User-Agent on each request so ours is
+// set by the transport.
+
+// Private Wikibase instances can require extra headers, e.g. a bearer
+// token. These are only sent to the hosts of the endpoint and API so that
+// credentials are not leaked to the other services we request from.
+
+// bearerTokenEnv can be used to supply the bearer token instead of storing
+// it in the configuration file.
+const bearerTokenEnv = "WDANALYSIS_BEARER_TOKEN"
+
+// defaultContact is used in the User-Agent if no contact is configured.
+const defaultContact = "https://github.com/ross-spencer/wdanalysis"
+
+// defaultPageDelay is the delay between the pages of a harvest.
+const defaultPageDelay = "1s"
+
+// userAgent returns the User-Agent of the pipeline's requests.
+func (p *Pipeline) userAgent() string {
+	cfg := p.Config.Network
+	if cfg.UserAgent != "" {
+		return cfg.UserAgent
+	}
+	contact := cfg.Contact
+	if contact == "" {
+		contact = defaultContact
+	}
+	return fmt.Sprintf("%s (%s) spargo", p.Tool, contact)
+}
+
+// agentTransport sets the User-Agent of every request.
+type agentTransport struct {
+	next  http.RoundTripper
+	agent string
+}
+
+// RoundTrip satisfies the http.RoundTripper interface.
+func (t agentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.agent)
+	return t.next.RoundTrip(req)
+}
+
+// headerTransport sets extra headers on every request to a set of hosts.
+type headerTransport struct {
+	next    http.RoundTripper
+	hosts   []string
+	headers map[string]string
+}
+
+// RoundTrip satisfies the http.RoundTripper interface.
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !contains(t.hosts, req.URL.Host) {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// ParseHeader splits a header given in the form "Name: value".
+func ParseHeader(header string) (string, string, error) {
+	parts := strings.SplitN(header, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", "", fmt.Errorf("invalid header '%s', expected 'Name: value'", header)
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+// privateHeaders returns the headers sent to the endpoint and API.
+func (p *Pipeline) privateHeaders() map[string]string {
+	headers := make(map[string]string)
+	for name, value := range p.Config.Network.Headers {
+		headers[name] = value
+	}
+	token := p.Config.Network.BearerToken
+	if env := os.Getenv(bearerTokenEnv); env != "" {
+		token = env
+	}
+	if token != "" {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", token)
+	}
+	return headers
+}
+
+// privateHosts returns the hosts of the endpoint and API.
+func (p *Pipeline) privateHosts() []string {
+	var hosts []string
+	for _, endpoint := range []string{p.Endpoint, p.API} {
+		if uri, err := neturl.Parse(endpoint); err == nil && uri.Host != "" {
+			hosts = append(hosts, uri.Host)
+		}
+	}
+	return hosts
+}
+
+// newTransport creates a transport from our network configuration. Proxies
+// are taken from HTTP_PROXY and HTTPS_PROXY unless configured explicitly.
+func newTransport(cfg NetworkConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxy, err := neturl.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy '%s': %s", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	tlsConfig := &tls.Config{}
+	if cfg.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA bundle: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle '%s'", cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	tlsConfig.InsecureSkipVerify = cfg.InsecureSkipVerify
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// parseDuration parses a duration from the configuration, using the default
+// if none is set.
+func parseDuration(value string, def string) (time.Duration, error) {
+	if value == "" {
+		value = def
+	}
+	return time.ParseDuration(value)
+}
+
+// newClient creates the client used for all of the pipeline's outbound
+// requests so that network behaviour is configured in one place.
+func (p *Pipeline) newClient() (*http.Client, error) {
+	transport, err := newTransport(p.Config.Network)
+	if err != nil {
+		return nil, err
+	}
+	if p.Config.Network.InsecureSkipVerify {
+		p.Logger.Println("warning: TLS certificate verification is disabled")
+	}
+	var roundTripper http.RoundTripper = compressTransport{next: transport, transfer: p.transfer}
+	roundTripper = agentTransport{next: roundTripper, agent: p.userAgent()}
+	if headers := p.privateHeaders(); len(headers) > 0 {
+		roundTripper = headerTransport{next: roundTripper, hosts: p.privateHosts(), headers: headers}
+	}
+	if p.audit != nil {
+		roundTripper = auditTransport{next: roundTripper, audit: p.audit}
+	}
+	cfg := p.Config.Network
+	retry := retryTransport{
+		next:    roundTripper,
+		retries: cfg.Retries,
+		jitter:  cfg.Jitter,
+		count:   &p.retries,
+	}
+	if retry.backoff, err = parseDuration(cfg.Backoff, defaultBackoff); err != nil {
+		return nil, fmt.Errorf("invalid backoff: %s", err)
+	}
+	if retry.maxBackoff, err = parseDuration(cfg.MaxBackoff, defaultMaxBackoff); err != nil {
+		return nil, fmt.Errorf("invalid maximum backoff: %s", err)
+	}
+	roundTripper = retry
+	return &http.Client{
+		Transport: roundTripper,
+		Timeout:   requestTimeout,
+	}, nil
+}
+
+// contextTransport makes every request with the context of a run so that
+// requests are cancelled along with it.
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+// RoundTrip satisfies the http.RoundTripper interface.
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(t.ctx))
+}
+
+// contextClient returns the pipeline's client bound to a context.
+func (p *Pipeline) contextClient(ctx context.Context) *http.Client {
+	return &http.Client{
+		Transport: contextTransport{ctx: ctx, next: p.client.Transport},
+		Timeout:   p.client.Timeout,
+	}
+}
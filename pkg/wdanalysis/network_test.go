@@ -0,0 +1,93 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// TestTransportChain checks that every request made through the
+// pipeline's client is identified, compressed, audited and retried, and
+// that private headers are only sent to the endpoint.
+func TestTransportChain(t *testing.T) {
+	body := demoBody(t)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(body)
+	gz.Close()
+
+	var requests int32
+	var headers []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/sparql-results+json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer srv.Close()
+	var other http.Header
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		other = r.Header.Clone()
+	}))
+	defer elsewhere.Close()
+
+	var log bytes.Buffer
+	p := newTestPipeline(t, srv.URL)
+	p.Config = DefaultConfig()
+	p.Config.Network.Backoff = "1ms"
+	p.Config.Network.MaxBackoff = "1ms"
+	p.Config.Network.Headers = map[string]string{"X-Gateway": "secret"}
+	p.AuditLog = &log
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(headers) != 2 {
+		t.Fatalf("got %d requests, want the first retried once", len(headers))
+	}
+	for i, header := range headers {
+		if got := header.Get("User-Agent"); got != p.userAgent() {
+			t.Errorf("request %d: got User-Agent %q, want %q", i+1, got, p.userAgent())
+		}
+		if got := header.Get("Accept-Encoding"); got != acceptEncoding {
+			t.Errorf("request %d: got Accept-Encoding %q, want %q", i+1, got, acceptEncoding)
+		}
+		if got := header.Get("X-Gateway"); got != "secret" {
+			t.Errorf("request %d: got X-Gateway %q, want the private header", i+1, got)
+		}
+	}
+	if got := p.Summary().Retries; got != 1 {
+		t.Errorf("got %d retries, want 1", got)
+	}
+	if got := len(p.Records()); got == 0 {
+		t.Errorf("got no records from the decompressed response")
+	}
+	entries := auditEntries(t, &log)
+	if len(entries) != 2 || entries[0].Status != http.StatusServiceUnavailable || entries[1].Status != http.StatusOK {
+		t.Errorf("got audit entries %+v, want the failed request and its retry", entries)
+	}
+	if transfer := p.transfer; transfer.Transferred != int64(compressed.Len()) || transfer.Decompressed != int64(len(body)) {
+		t.Errorf("got %d bytes transferred and %d decompressed, want %d and %d", transfer.Transferred, transfer.Decompressed, compressed.Len(), len(body))
+	}
+
+	resp, err := p.client.Get(elsewhere.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := other.Get("X-Gateway"); got != "" {
+		t.Errorf("got X-Gateway %q sent to another host, want none", got)
+	}
+	if other.Get("User-Agent") != p.userAgent() {
+		t.Errorf("got User-Agent %q from another host, want %q", other.Get("User-Agent"), p.userAgent())
+	}
+}
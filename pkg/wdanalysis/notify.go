@@ -0,0 +1,153 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheduled harvests run unattended. Notifications report the result of a
+// run by webhook, email, or both, so that the summary can be seen without
+// scraping logs. Notifications are configured in the configuration file.
+
+// smtpPasswordEnv can be used to supply the SMTP password instead of
+// storing it in the configuration file.
+const smtpPasswordEnv = "WDANALYSIS_SMTP_PASSWORD"
+
+// NotifyConfig describes where notifications are sent.
+type NotifyConfig struct {
+	Webhook     string     `json:"webhook"`     // URL the summary is POSTed to as JSON.
+	Discoveries string     `json:"discoveries"` // URL new formats since a baseline are POSTed to as JSON.
+	SMTP        SMTPConfig `json:"smtp"`
+}
+
+// SMTPConfig describes how notifications are sent by email.
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"`
+}
+
+// notification is the content of a notification.
+type notification struct {
+	Manifest Manifest `json:"manifest"`
+	Summary  Summary  `json:"summary"`
+}
+
+// notify sends the summary of a run to each configured destination.
+// Failures are logged and do not affect the result of the run.
+func (p *Pipeline) notify(cfg NotifyConfig) {
+	if cfg.Webhook == "" && cfg.SMTP.Host == "" {
+		return
+	}
+	body, err := json.MarshalIndent(notification{Manifest: p.manifest, Summary: p.Summary()}, "", "  ")
+	if err != nil {
+		p.Logger.Printf("cannot create notification: %s", err)
+		return
+	}
+	if cfg.Webhook != "" {
+		if err := notifyWebhook(p.client, cfg.Webhook, body); err != nil {
+			p.Logger.Printf("cannot send webhook notification: %s", err)
+		}
+	}
+	if cfg.SMTP.Host != "" {
+		if err := notifyEmail(cfg.SMTP, p.manifest, body); err != nil {
+			p.Logger.Printf("cannot send email notification: %s", err)
+		}
+	}
+}
+
+// notifyWebhook POSTs the notification to a webhook.
+func notifyWebhook(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response from server: %s", resp.Status)
+	}
+	return nil
+}
+
+// emailMessage returns an email with the notification attached as
+// summary.json.
+func emailMessage(cfg SMTPConfig, manifest Manifest, body []byte, now time.Time) ([]byte, error) {
+	var msg bytes.Buffer
+	writer := multipart.NewWriter(&msg)
+	subject := cfg.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("%s harvest complete", manifest.Tool)
+	}
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+	text, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(text, "Harvest of %s complete: %d records, %d signatures.\r\n",
+		manifest.origin(), manifest.Records, manifest.Signatures)
+	attachment, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/json"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {"attachment; filename=\"summary.json\""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(body)
+	for len(encoded) > 76 {
+		fmt.Fprintf(attachment, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(attachment, "%s\r\n", encoded)
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// notifyEmail sends the notification by email.
+func notifyEmail(cfg SMTPConfig, manifest Manifest, body []byte) error {
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("no recipients configured")
+	}
+	msg, err := emailMessage(cfg, manifest, body, time.Now())
+	if err != nil {
+		return err
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		password := cfg.Password
+		if env := os.Getenv(smtpPasswordEnv); env != "" {
+			password = env
+		}
+		auth = smtp.PlainAuth("", cfg.Username, password, cfg.Host)
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	return smtp.SendMail(addr, auth, cfg.From, cfg.To, msg)
+}
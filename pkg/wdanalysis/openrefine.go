@@ -0,0 +1,168 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OpenRefine exports are shaped so that an institution can load their own
+// format registry alongside this file and reconcile it against Wikidata using
+// OpenRefine's reconciliation tooling. Multi-value cells are joined using
+// refineSeparator which can be split back out again in OpenRefine using
+// "Split multi-valued cells".
+
+const refineSeparator = "|"
+
+// refineHeader returns the columns of the TSV export. Property columns are
+// labelled with the number of the property they were harvested from so
+// that OpenRefine can map them directly when reconciling.
+func refineHeader(props PropertyMap) []string {
+	return []string{
+		"id",
+		"name",
+		"uri",
+		fmt.Sprintf("%s (PRONOM)", props.PUID),
+		fmt.Sprintf("%s (LoC)", props.LOC),
+		fmt.Sprintf("%s (extension)", props.Extension),
+		fmt.Sprintf("%s (MIME type)", props.MIMEType),
+		"labels",
+		"signatures",
+		"grade",
+	}
+}
+
+// refineTypeRecord describes the type of a reconciliation candidate.
+type refineTypeRecord struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// refineType returns the type of the candidates, the root class the
+// formats were harvested from. Only file format is known by name; any
+// other root is named by its QID.
+func refineType(props PropertyMap) refineTypeRecord {
+	if props.FileFormat == defaultProperties.FileFormat {
+		return refineTypeRecord{ID: props.FileFormat, Name: "file format"}
+	}
+	return refineTypeRecord{ID: props.FileFormat, Name: props.FileFormat}
+}
+
+// refineRecord describes a single reconciliation candidate in the JSON
+// export.
+type refineRecord struct {
+	ID         string              `json:"id"`
+	Name       string              `json:"name"`
+	URI        string              `json:"uri"`
+	Type       []refineTypeRecord  `json:"type"`
+	Properties map[string][]string `json:"properties"`
+	Labels     map[string]string   `json:"labels,omitempty"`
+	Signatures int                 `json:"signatures"`
+	Grade      string              `json:"grade"`
+}
+
+// refineCell sanitizes a value for use in a TSV cell. Tabs and newlines
+// would otherwise break the row structure of the export.
+func refineCell(value string) string {
+	value = strings.Replace(value, "\t", " ", -1)
+	value = strings.Replace(value, "\r", " ", -1)
+	value = strings.Replace(value, "\n", " ", -1)
+	return value
+}
+
+// refineJoin joins a multi-value field into a single TSV cell.
+func refineJoin(values []string) string {
+	var cells []string
+	for _, value := range nonEmpty(values) {
+		cells = append(cells, refineCell(value))
+	}
+	return strings.Join(cells, refineSeparator)
+}
+
+// refineLabels joins the labels of external identifiers into a single TSV
+// cell in the form "identifier: label".
+func refineLabels(labels map[string]string) string {
+	var cells []string
+	for id, label := range labels {
+		cells = append(cells, refineCell(fmt.Sprintf("%s: %s", id, label)))
+	}
+	sort.Strings(cells)
+	return strings.Join(cells, refineSeparator)
+}
+
+// refineTSV returns the corpus as an OpenRefine compatible TSV document.
+func refineTSV(records []Wikidata, props PropertyMap) string {
+	var rows []string
+	rows = append(rows, strings.Join(refineHeader(props), "\t"))
+	for _, wd := range records {
+		row := []string{
+			refineCell(wd.ID),
+			refineCell(wd.Name),
+			refineCell(wd.URI),
+			refineJoin(wd.PRONOM),
+			refineJoin(wd.LOC),
+			refineJoin(wd.Extension),
+			refineJoin(wd.Mimetype),
+			refineLabels(wd.ExternalLabels),
+			fmt.Sprintf("%d", len(wd.Signatures)),
+			wd.Grade,
+		}
+		rows = append(rows, strings.Join(row, "\t"))
+	}
+	return strings.Join(rows, "\n") + "\n"
+}
+
+// refineJSON returns the corpus as a list of OpenRefine reconciliation
+// candidates.
+func refineJSON(records []Wikidata, props PropertyMap) (string, error) {
+	candidates := []refineRecord{}
+	for _, wd := range records {
+		candidates = append(candidates, refineRecord{
+			ID:   wd.ID,
+			Name: wd.Name,
+			URI:  wd.URI,
+			Type: []refineTypeRecord{refineType(props)},
+			Properties: map[string][]string{
+				props.PUID:      nonEmpty(wd.PRONOM),
+				props.LOC:       nonEmpty(wd.LOC),
+				props.Extension: nonEmpty(wd.Extension),
+				props.MIMEType:  nonEmpty(wd.Mimetype),
+			},
+			Labels:     wd.ExternalLabels,
+			Signatures: len(wd.Signatures),
+			Grade:      wd.Grade,
+		})
+	}
+	report, err := json.MarshalIndent(candidates, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n", report), nil
+}
+
+// OpenRefineExporter writes the corpus to Path. A JSON document is written
+// if the path has a .json extension, otherwise TSV is written.
+type OpenRefineExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e OpenRefineExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	out := refineTSV(records, p.Config.Properties)
+	if strings.ToLower(filepath.Ext(e.Path)) == ".json" {
+		out, err = refineJSON(records, p.Config.Properties)
+		if err != nil {
+			return err
+		}
+	}
+	if err := p.writeExport(e.Path, []byte(out)); err != nil {
+		return fmt.Errorf("cannot write OpenRefine export: %s", err)
+	}
+	return nil
+}
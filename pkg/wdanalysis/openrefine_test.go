@@ -0,0 +1,68 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestRefineProperties checks that the property columns of the OpenRefine
+// export are labelled with the properties of the pipeline's property map.
+func TestRefineProperties(t *testing.T) {
+	props := DefaultConfig().Properties
+	props.PUID = "P9001"
+	props.Extension = "P9002"
+	records := []Wikidata{{ID: "Q2192", Name: "GIF", PRONOM: []string{"fmt/4"}, Extension: []string{"gif"}}}
+
+	header := strings.SplitN(refineTSV(records, props), "\n", 2)[0]
+	for _, want := range []string{"P9001 (PRONOM)", "P9002 (extension)", "P3266 (LoC)"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("got header %q, want a column %q", header, want)
+		}
+	}
+	if strings.Contains(header, "P2748") {
+		t.Errorf("got header %q, want no default PUID property", header)
+	}
+
+	out, err := refineJSON(records, props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var candidates []refineRecord
+	if err := json.Unmarshal([]byte(out), &candidates); err != nil {
+		t.Fatal(err)
+	}
+	if got := candidates[0].Properties["P9001"]; len(got) != 1 || got[0] != "fmt/4" {
+		t.Errorf("got %v for P9001, want [fmt/4]", got)
+	}
+	if got := candidates[0].Properties["P9002"]; len(got) != 1 || got[0] != "gif" {
+		t.Errorf("got %v for P9002, want [gif]", got)
+	}
+}
+
+// TestRefineType checks that the candidates of the OpenRefine export are
+// typed with the root class the formats were harvested from.
+func TestRefineType(t *testing.T) {
+	tests := []struct {
+		root string
+		want refineTypeRecord
+	}{
+		{"Q235557", refineTypeRecord{ID: "Q235557", Name: "file format"}},
+		{"Q1331420", refineTypeRecord{ID: "Q1331420", Name: "Q1331420"}},
+	}
+	for _, test := range tests {
+		props := DefaultConfig().Properties
+		props.FileFormat = test.root
+		out, err := refineJSON([]Wikidata{{ID: "Q2192", Name: "GIF"}}, props)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var candidates []refineRecord
+		if err := json.Unmarshal([]byte(out), &candidates); err != nil {
+			t.Fatal(err)
+		}
+		if got := candidates[0].Type; len(got) != 1 || got[0] != test.want {
+			t.Errorf("root %s: got type %v, want %v", test.root, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package wdanalysis
+
+import (
+	"sort"
+	"time"
+)
+
+// Some Wikidata items are structured in ways that multiply the rows of the
+// SPARQL results, e.g. many signatures each with many qualifiers, which
+// blows up processing. Recording the rows and time spent on each record
+// lets us report the items that need curation.
+
+// outlierFactor is the number of interquartile ranges above the third
+// quartile a record must be to be reported as an outlier.
+const outlierFactor = 3
+
+// recordCost describes the work needed to process a record.
+type recordCost struct {
+	rows     int
+	duration time.Duration
+}
+
+// Outlier describes a record that is expensive to process.
+type Outlier struct {
+	URI      string `json:"uri"`
+	Rows     int    `json:"rows"`
+	Duration string `json:"duration"`
+}
+
+// cost adds the work done on a record.
+func (p *Pipeline) cost(id string, rows int, duration time.Duration) {
+	cost := p.costs[id]
+	cost.rows += rows
+	cost.duration += duration
+	p.costs[id] = cost
+}
+
+// outlierThreshold returns the value above which a value in a sample is an
+// outlier.
+func outlierThreshold(values []float64) float64 {
+	sort.Float64s(values)
+	q1 := values[len(values)/4]
+	q3 := values[len(values)*3/4]
+	return q3 + outlierFactor*(q3-q1)
+}
+
+// analyseOutliers adds the records whose rows or processing time are
+// outliers to the summary, most expensive first.
+func (p *Pipeline) analyseOutliers() {
+	if len(p.costs) == 0 {
+		return
+	}
+	var rows, durations []float64
+	for _, cost := range p.costs {
+		rows = append(rows, float64(cost.rows))
+		durations = append(durations, float64(cost.duration))
+	}
+	maxRows := outlierThreshold(rows)
+	maxDuration := outlierThreshold(durations)
+	var ids []string
+	for id, cost := range p.costs {
+		if float64(cost.rows) > maxRows || float64(cost.duration) > maxDuration {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return p.costs[ids[i]].duration > p.costs[ids[j]].duration
+	})
+	p.summary.Outliers = []Outlier{}
+	for _, id := range ids {
+		p.summary.Outliers = append(p.summary.Outliers, Outlier{
+			URI:      p.records[id].URI,
+			Rows:     p.costs[id].rows,
+			Duration: p.costs[id].duration.String(),
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files are written using platform-appropriate paths. Text exports, e.g.
+// TSV and wikitext, can optionally be written with a UTF-8 byte order mark
+// and CRLF line endings for applications on Windows that expect them. JSON
+// is always written as plain UTF-8 as a byte order mark makes it invalid.
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ResultsCache returns where the results of the most recent harvest are
+// kept so that they can be processed again without querying Wikidata.
+func ResultsCache() string {
+	entries, err := ListCache(CacheDir())
+	if err != nil || len(entries) == 0 {
+		return filepath.Join(CacheDir(), "res.json")
+	}
+	return entries[0].Path
+}
+
+// isText returns true if a file is a text export rather than JSON.
+func isText(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) != ".json"
+}
+
+// textPolicy applies the line ending and byte order mark settings to the
+// content of a text export.
+func (p *Pipeline) textPolicy(path string, content []byte) []byte {
+	if !isText(path) {
+		return content
+	}
+	if p.CRLF {
+		content = bytes.Replace(content, []byte("\r\n"), []byte("\n"), -1)
+		content = bytes.Replace(content, []byte("\n"), []byte("\r\n"), -1)
+	}
+	if p.BOM && !bytes.HasPrefix(content, utf8BOM) {
+		content = append(append([]byte{}, utf8BOM...), content...)
+	}
+	return content
+}
+
+// makeDir creates the directory of a file if needed.
+func makeDir(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		return os.MkdirAll(dir, 0755)
+	}
+	return nil
+}
+
+// writeFile writes a file, creating its directory if needed.
+func writeFile(path string, content []byte) error {
+	path = filepath.Clean(path)
+	if err := makeDir(path); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// createFile creates a file to write to, creating its directory if needed.
+func createFile(path string) (*os.File, error) {
+	path = filepath.Clean(path)
+	if err := makeDir(path); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// writeExport writes an exported file and, if a signing key has been
+// supplied, its attestation alongside it. Tabular exports with more rows
+// than the pipeline's ChunkRows are written in parts.
+func (p *Pipeline) writeExport(path string, content []byte) error {
+	if p.ChunkRows > 0 && isTabular(path) {
+		if header, rows := tableRows(content); len(rows) > p.ChunkRows {
+			return p.writeParts(path, header, rows, p.ChunkRows)
+		}
+	}
+	return p.writeSigned(path, content)
+}
+
+// writeSigned writes a file and, if a signing key has been supplied, its
+// attestation alongside it.
+func (p *Pipeline) writeSigned(path string, content []byte) error {
+	content = p.textPolicy(path, content)
+	if err := writeFile(path, content); err != nil {
+		return err
+	}
+	if p.SigningKey == nil {
+		return nil
+	}
+	attestation, err := attest(filepath.Base(path), content, p.SigningKey, p.manifest)
+	if err != nil {
+		return fmt.Errorf("cannot sign '%s': %s", path, err)
+	}
+	return writeFile(path+attestationSuffix, attestation)
+}
@@ -0,0 +1,118 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// Curators sometimes know how the sequences of a record belong together
+// better than our heuristics can guess. Overrides record that knowledge in a
+// local file so that it survives re-harvests. An override is only honoured
+// while the sequences in Wikidata match it exactly. Once Wikidata has been
+// restructured the override is reported as stale and the heuristic is used
+// instead.
+
+// Overrides map the ID of a record, e.g. Q12345, to the signatures its
+// sequences belong to. Each signature lists the IDs of the statements of
+// its sequences, e.g.
+//
+//	{"Q2195": [["Q2195$5F3E1A22-0F4B-4D6E-9C1A-1B2C3D4E5F60", "Q2195$9A8B7C6D-5E4F-4A3B-8C2D-1E0F9A8B7C6D"]]}
+//
+// Sequences harvested without their statement are listed by their value as
+// it is recorded in Wikidata instead.
+type Overrides map[string][][]string
+
+// LoadOverrides reads an overrides file.
+func LoadOverrides(path string) (Overrides, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides Overrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// overrideKey returns how a sequence is listed in an override: by the ID of
+// its statement, or by its value if its statement was not harvested.
+func overrideKey(sequence Signature) string {
+	if sequence.Statement != "" {
+		return sequence.Statement
+	}
+	return sequence.Signature
+}
+
+// override returns the signatures of a record described by its override.
+// An error is returned if the override does not match the sequences of the
+// record exactly.
+func (o Overrides) override(wd Wikidata) ([][]Signature, error) {
+	groups, ok := o[wd.ID]
+	if !ok {
+		return nil, nil
+	}
+	sequences := make(map[string]Signature)
+	for _, sequence := range wd.Signatures {
+		sequences[overrideKey(sequence)] = sequence
+	}
+	var signatures [][]Signature
+	used := make(map[string]bool)
+	for _, group := range groups {
+		var signature []Signature
+		for _, key := range group {
+			sequence, ok := sequences[key]
+			if !ok {
+				return nil, fmt.Errorf("sequence '%s' is not recorded in Wikidata", key)
+			}
+			if used[key] {
+				return nil, fmt.Errorf("sequence '%s' is assigned more than once", key)
+			}
+			used[key] = true
+			signature = append(signature, sequence)
+		}
+		signatures = append(signatures, signature)
+	}
+	if len(used) != len(wd.Signatures) {
+		return nil, fmt.Errorf("%d sequences recorded in Wikidata are not assigned", len(wd.Signatures)-len(used))
+	}
+	return signatures, nil
+}
+
+// Group returns the signatures of a record, using its override if it has a
+// valid one, and the heuristic otherwise.
+func (p *Pipeline) Group(wd Wikidata, h Heuristic) [][]Signature {
+	if signatures, err := p.Overrides.override(wd); err == nil && signatures != nil {
+		return signatures
+	}
+	return h(wd.Signatures)
+}
+
+// analyseOverrides adds the overrides that are honoured, and those that no
+// longer match Wikidata, to the summary.
+func (p *Pipeline) analyseOverrides() {
+	var ids []string
+	for id := range p.Overrides {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		wd, ok := p.records[id]
+		if !ok {
+			p.Logger.Printf("stale override for '%s': record not found", id)
+			p.summary.StaleOverrides = append(p.summary.StaleOverrides, id)
+			continue
+		}
+		if _, err := p.Overrides.override(wd); err != nil {
+			p.Logger.Printf("stale override for '%s': %s", id, err)
+			p.summary.StaleOverrides = append(p.summary.StaleOverrides, id)
+			continue
+		}
+		p.summary.Overridden = append(p.summary.Overridden, wd.URI)
+		// The signatures of the record are known so there is nothing to
+		// guess.
+		p.summary.unlint(wd.URI, heuWDE01)
+	}
+}
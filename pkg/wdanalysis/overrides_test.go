@@ -0,0 +1,78 @@
+package wdanalysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestOverride checks which overrides are honoured when grouping the
+// sequences of a record.
+func TestOverride(t *testing.T) {
+	gif := Wikidata{ID: "Q2192", Signatures: []Signature{
+		{Signature: "474946383961", Statement: "Q2192$A"},
+		{Signature: "474946383761", Statement: "Q2192$B"},
+	}}
+	// Two statements with the same value.
+	twice := Wikidata{ID: "Q2192", Signatures: []Signature{
+		{Signature: "474946383961", Statement: "Q2192$A"},
+		{Signature: "474946383961", Statement: "Q2192$B"},
+	}}
+	// Sequences harvested without their statements.
+	values := Wikidata{ID: "Q2192", Signatures: []Signature{
+		{Signature: "474946383961"},
+		{Signature: "474946383761"},
+	}}
+	tests := []struct {
+		name      string
+		wd        Wikidata
+		overrides Overrides
+		want      [][]string // Statements, or values, of each signature.
+	}{
+		{"honoured", gif, Overrides{"Q2192": {{"Q2192$A", "Q2192$B"}}}, [][]string{{"Q2192$A", "Q2192$B"}}},
+		{"no override", gif, Overrides{"Q42": {{"Q42$A"}}}, [][]string{{"Q2192$A"}, {"Q2192$B"}}},
+		{"stale statement", gif, Overrides{"Q2192": {{"Q2192$A", "Q2192$C"}}}, [][]string{{"Q2192$A"}, {"Q2192$B"}}},
+		{"listed by value", gif, Overrides{"Q2192": {{"474946383961", "474946383761"}}}, [][]string{{"Q2192$A"}, {"Q2192$B"}}},
+		{"assigned twice", gif, Overrides{"Q2192": {{"Q2192$A"}, {"Q2192$A", "Q2192$B"}}}, [][]string{{"Q2192$A"}, {"Q2192$B"}}},
+		{"duplicate values honoured", twice, Overrides{"Q2192": {{"Q2192$B", "Q2192$A"}}}, [][]string{{"Q2192$B", "Q2192$A"}}},
+		{"duplicate values incomplete", twice, Overrides{"Q2192": {{"Q2192$A"}}}, [][]string{{"Q2192$A"}, {"Q2192$B"}}},
+		{"without statements", values, Overrides{"Q2192": {{"474946383761", "474946383961"}}}, [][]string{{"474946383761", "474946383961"}}},
+	}
+	for _, test := range tests {
+		p := &Pipeline{Overrides: test.overrides}
+		var got [][]string
+		for _, signature := range p.Group(test.wd, statementHeuristic) {
+			var keys []string
+			for _, sequence := range signature {
+				keys = append(keys, overrideKey(sequence))
+			}
+			got = append(got, keys)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got signatures %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// TestAnalyseOverrides checks that honoured and stale overrides are
+// reported in the summary.
+func TestAnalyseOverrides(t *testing.T) {
+	p := newTestPipeline(t, DefaultEndpoint)
+	p.Overrides = Overrides{
+		"Q2192":  {{"Q2192$00000000-0000-4000-8000-000000000001", "Q2192$00000000-0000-4000-8000-000000000002"}},
+		"Q42332": {{"Q42332$00000000-0000-4000-8000-000000000009"}},
+		"Q42":    {{"Q42$00000000-0000-4000-8000-000000000001"}},
+	}
+	if err := p.Process(demoResults().Results.Bindings); err != nil {
+		t.Fatal(err)
+	}
+	summary := p.Summary()
+	if want := []string{"http://www.wikidata.org/entity/Q2192"}; !reflect.DeepEqual(summary.Overridden, want) {
+		t.Errorf("got overridden %v, want %v", summary.Overridden, want)
+	}
+	if want := []string{"Q42", "Q42332"}; !reflect.DeepEqual(summary.StaleOverrides, want) {
+		t.Errorf("got stale overrides %v, want %v", summary.StaleOverrides, want)
+	}
+	if summary.Lints["http://www.wikidata.org/entity/Q2192"][heuWDE01] != 0 {
+		t.Errorf("got %s for an overridden record, want none", heuWDE01)
+	}
+}
@@ -0,0 +1,97 @@
+package wdanalysis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// A monolithic harvest query can time out on a busy endpoint. The harvest
+// can instead be split into several smaller queries that are run
+// concurrently. Items are partitioned by the last digit of their QID, which
+// is spread evenly and needs no arithmetic that SPARQL lacks, so there can
+// be at most ten partitions. Partitions are merged in the order of their
+// formats so that the results do not depend on which finished first.
+
+// maxPartitions is the number of digits an item can be partitioned by.
+const maxPartitions = 10
+
+// defaultWorkers is the number of partitions queried at once by default.
+const defaultWorkers = 4
+
+// partitionFilter returns a FILTER restricting a query to the items in
+// partition i of n.
+func partitionFilter(i int, n int) string {
+	var digits strings.Builder
+	for digit := i; digit < maxPartitions; digit += n {
+		digits.WriteString(strconv.Itoa(digit))
+	}
+	return fmt.Sprintf("FILTER(REGEX(STR(?%s), \"[%s]$\"))", formatField, digits.String())
+}
+
+// harvestPartitions runs a query in the pipeline's partitions, at most
+// Workers at a time, and merges their results.
+func (p *Pipeline) harvestPartitions(ctx context.Context, query string) (spargo.SPARQLResult, error) {
+	var res spargo.SPARQLResult
+	partitionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	partitions := make([]spargo.SPARQLResult, p.Partitions)
+	workers := make(chan struct{}, p.Workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failure error
+	for i := range partitions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case workers <- struct{}{}:
+			case <-partitionCtx.Done():
+				return
+			}
+			defer func() { <-workers }()
+			// The query was validated when the pipeline was initialized.
+			q, _ := insertWhere(query, partitionFilter(i, p.Partitions))
+			partition, err := p.runResponse(partitionCtx, q)
+			if err != nil {
+				// One failed partition fails the harvest so the others
+				// are cancelled. Only the first failure is the cause.
+				mu.Lock()
+				if failure == nil {
+					failure = fmt.Errorf("cannot harvest partition %d: %s", i+1, err)
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+			p.Logger.Printf("partition %d of %d: received %d results", i+1, p.Partitions, len(partition.Results.Bindings))
+			partitions[i] = partition
+		}(i)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	if failure != nil {
+		return res, failure
+	}
+	res.Head = partitions[0].Head
+	for _, partition := range partitions {
+		res.Results.Bindings = append(res.Results.Bindings, partition.Results.Bindings...)
+	}
+	sort.SliceStable(res.Results.Bindings, func(i, j int) bool {
+		return res.Results.Bindings[i][formatField].Value < res.Results.Bindings[j][formatField].Value
+	})
+	p.summary.Pages = p.Partitions
+	human, err := sparqlJSON(res)
+	if err != nil {
+		return res, err
+	}
+	res.Human = human
+	return res, nil
+}
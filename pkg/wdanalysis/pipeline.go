@@ -0,0 +1,564 @@
+package wdanalysis
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// DefaultEndpoint is the Wikidata Query Service.
+const DefaultEndpoint = "https://query.wikidata.org/sparql"
+
+// ErrNoSignatures is returned by Run when a harvest produces no signatures
+// and exports are skipped as a result.
+var ErrNoSignatures = errors.New("no signatures were harvested")
+
+// DefaultTool names the tool in manifests and notifications when a pipeline
+// does not set its own.
+const DefaultTool = "wdanalysis"
+
+// Pipeline harvests file format records from a Wikibase, analyses them, and
+// exports the results. A Pipeline holds all of the state of a harvest so
+// that several can be run concurrently in one process, e.g. against
+// different Wikibase instances. A Pipeline should be used for a single run.
+type Pipeline struct {
+	Tool        string             // Name and version of the tool recorded in manifests.
+	Endpoint    string             // SPARQL endpoint. Defaults to DefaultEndpoint.
+	Query       string             // Harvest query template. Defaults to DefaultQuery.
+	QIDs        []string           // Harvest and process only these items if set.
+	PUIDs       []string           // Harvest and process only the items mapped to these PUIDs if set.
+	Shard       *Shard             // Harvest and process only the items in this shard of a harvest spread across machines if set.
+	PageSize    int                // Harvest in pages of this many results if greater than zero.
+	Resume      bool               // Continue a paged harvest from its checkpoint instead of starting again.
+	Partitions  int                // Harvest in this many queries, partitioned by the last digit of each QID, if greater than one.
+	Workers     int                // Partitions queried at once. Defaults to 4.
+	Config      Config             // Configuration. Defaults to DefaultConfig().
+	Enrich      bool               // Retrieve PRONOM and LoC labels for external identifiers.
+	Languages   []string           // Harvest the names of each format in these languages.
+	StaleYears  int                // Report stale records if greater than zero.
+	Duplicates  string             // Write the duplicate statement report to this path if set.
+	Versions    string             // Write the version chains of formats to this path if set.
+	Classes     string             // Write the number of records harvested through each class to this path if set.
+	Deprecated  bool               // Harvest the signatures of deprecated statements, reporting each.
+	ShareStats  string             // POST anonymised statistics of the run to this URL if set.
+	Scope       string             // Policy for scoped signatures in exports.
+	MaxLength   int                // Maximum length of sequences in exports in bytes. No maximum if zero.
+	Length      string             // Policy for sequences longer than MaxLength.
+	HexStyle    string             // Style sequences are written in. Defaults to HexContinuous.
+	Supplement  *DROID             // Export only what Wikidata adds to this DROID signature file if set.
+	Baseline    *Baseline          // Records of a previous export to compare with if set.
+	Changed     bool               // Export only records that are new or differ from Baseline.
+	Thresholds  []Threshold        // Withhold identifiers if the changes since Baseline exceed any of these.
+	Incremental *Baseline          // Harvest only records modified since this previous export and merge them into it.
+	Overrides   Overrides          // Curated signature groupings honoured over heuristics.
+	Force       bool               // Run exporters even if no signatures were harvested.
+	Outliers    bool               // Report records that are expensive to process.
+	Exporters   []Exporter         // Exporters run once records have been processed.
+	Cache       string             // Where SPARQL results are cached. Defaults to a path in CacheDir() keyed by endpoint and query.
+	Input       string             // Replay SPARQL results cached at this path instead of harvesting.
+	Demo        bool               // Input is the demo corpus written by WriteDemoCorpus.
+	Offline     bool               // Replay the cached results of the same endpoint and query instead of harvesting.
+	Dump        string             // Read the records from this Wikidata JSON dump instead of harvesting if set.
+	Fetch       []string           // Fetch these items from API in place of their harvested records if set.
+	API         string             // MediaWiki API items are fetched from. Defaults to DefaultAPI.
+	SigningKey  ed25519.PrivateKey // Sign exported files with this key if set.
+	BOM         bool               // Write a UTF-8 byte order mark in text exports.
+	CRLF        bool               // Write text exports with CRLF line endings.
+	ChunkRows   int                // Split TSV and CSV exports with more rows than this into parts if greater than zero.
+	AuditLog    io.Writer          // Record every outbound request here if set.
+	Logger      *log.Logger        // Progress messages. Discarded if nil.
+	OnLint      func(LintResult)   // Called for every lint finding as records are processed if set.
+	Progress    io.Writer          // Draw a progress bar here while results are processed if set.
+	Stream      io.Writer          // Write each record here as a line of JSON as soon as its rows are condensed if set.
+
+	client         *http.Client
+	audit          *auditLog
+	signatureCache map[signatureKey]signatureResult
+	records        map[string]Wikidata
+	retries        int32
+	transfer       *Transfer
+	formatIDs      map[string]bool
+	streaming      string
+	begun          map[string]bool
+	streamed       map[string]bool
+	fetched        []map[string]spargo.Item
+	progress       *progress
+	id             func(string) string
+	costs          map[string]recordCost
+	summary        Summary
+	manifest       Manifest
+	inputSHA256    string
+}
+
+// init sets the defaults of a pipeline and the state it needs for a run.
+func (p *Pipeline) init() error {
+	if p.records != nil {
+		return nil
+	}
+	if p.Tool == "" {
+		p.Tool = DefaultTool
+	}
+	if p.Endpoint == "" {
+		p.Endpoint = DefaultEndpoint
+	}
+	if p.Query == "" {
+		p.Query = DefaultQuery
+	}
+	if err := validateQuery(p.Query); err != nil {
+		return err
+	}
+	if p.Config.Properties.Signature == "" {
+		p.Config.Properties = DefaultConfig().Properties
+	}
+	if !qidPattern.MatchString(p.Config.Properties.FileFormat) {
+		return fmt.Errorf("invalid root class '%s', expected a QID", p.Config.Properties.FileFormat)
+	}
+	if p.Config.Entities == (wikidata.Entities{}) {
+		p.Config.Entities = wikidata.DefaultEntities
+	}
+	if p.Cache == "" {
+		p.Cache = p.cachePath()
+	}
+	for _, qid := range p.QIDs {
+		if !qidPattern.MatchString(qid) {
+			return fmt.Errorf("invalid QID '%s'", qid)
+		}
+	}
+	for _, qid := range p.Fetch {
+		if !qidPattern.MatchString(qid) {
+			return fmt.Errorf("invalid QID '%s'", qid)
+		}
+	}
+	if p.API == "" {
+		p.API = DefaultAPI
+	}
+	for _, puid := range p.PUIDs {
+		if !puidPattern.MatchString(puid) {
+			return fmt.Errorf("invalid PUID '%s'", puid)
+		}
+	}
+	if p.Shard != nil {
+		if err := p.Shard.validate(); err != nil {
+			return err
+		}
+	}
+	if len(p.QIDs) > 0 || len(p.PUIDs) > 0 || p.Shard != nil || p.Incremental != nil {
+		if _, err := insertWhere(buildQuery(p.Query, p.Config.Properties), ""); err != nil {
+			return err
+		}
+	}
+	if p.Incremental != nil {
+		if _, err := p.Incremental.since(); err != nil {
+			return fmt.Errorf("cannot harvest incrementally: %s", err)
+		}
+	}
+	if p.Dump != "" && (p.Input != "" || p.Offline || p.Incremental != nil) {
+		return fmt.Errorf("a dump cannot be read with cached results or incrementally")
+	}
+	if p.PageSize > 0 {
+		if _, err := totalOrder(p.harvestQuery()); err != nil {
+			return err
+		}
+	}
+	if p.Stream != nil && p.Dump == "" {
+		if err := p.validateStream(); err != nil {
+			return err
+		}
+	}
+	if p.Resume && p.PageSize <= 0 {
+		return fmt.Errorf("only harvests in pages can be resumed")
+	}
+	if p.Partitions > maxPartitions {
+		return fmt.Errorf("a harvest can be split into at most %d partitions", maxPartitions)
+	}
+	if p.Partitions > 1 && p.PageSize > 0 {
+		return fmt.Errorf("a harvest cannot be both partitioned and in pages")
+	}
+	if p.Workers <= 0 {
+		p.Workers = defaultWorkers
+	}
+	if p.Offline && p.Input == "" {
+		p.Input = p.Cache
+	}
+	if p.Logger == nil {
+		p.Logger = log.New(ioutil.Discard, "", 0)
+	}
+	if !contains(ScopePolicies, p.Scope) && p.Scope != "" {
+		return fmt.Errorf("unknown scope policy '%s', expected one of: %v", p.Scope, ScopePolicies)
+	}
+	if !contains(LengthPolicies, p.Length) && p.Length != "" {
+		return fmt.Errorf("unknown length policy '%s', expected one of: %v", p.Length, LengthPolicies)
+	}
+	if p.HexStyle == "" {
+		p.HexStyle = HexContinuous
+	}
+	if !contains(HexStyles, p.HexStyle) {
+		return fmt.Errorf("unknown hexadecimal style '%s', expected one of: %v", p.HexStyle, HexStyles)
+	}
+	if p.AuditLog != nil {
+		p.audit = &auditLog{writer: p.AuditLog}
+	}
+	id, err := p.Config.IDs.idScheme()
+	if err != nil {
+		return err
+	}
+	p.id = id
+	p.transfer = &Transfer{}
+	client, err := p.newClient()
+	if err != nil {
+		return fmt.Errorf("cannot configure network: %s", err)
+	}
+	p.client = client
+	p.signatureCache = make(map[signatureKey]signatureResult)
+	p.records = make(map[string]Wikidata)
+	p.costs = make(map[string]recordCost)
+	p.summary.onLint = p.OnLint
+	return nil
+}
+
+// Run harvests, analyses, and exports the records of a Wikibase.
+func (p *Pipeline) Run(ctx context.Context) error {
+	if err := p.init(); err != nil {
+		return err
+	}
+	var harvested time.Time
+	var err error
+	if len(p.Fetch) > 0 && p.Offline {
+		p.Logger.Printf("items cannot be fetched offline")
+	} else if len(p.Fetch) > 0 {
+		if p.fetched, err = p.fetchRows(ctx); err != nil {
+			return err
+		}
+	}
+	if p.Input != "" {
+		p.Logger.Printf("replaying %s", p.Input)
+		harvested, err = p.replay()
+		if os.IsNotExist(err) && p.Offline {
+			return fmt.Errorf("no harvest of %s with this query is cached", p.Endpoint)
+		}
+	} else if p.Dump != "" {
+		harvested, err = p.processDump()
+	} else {
+		// The IDs of every format are needed to merge the harvest into
+		// the previous export as soon as it has been processed.
+		if p.Incremental != nil {
+			p.formatIDs, err = p.harvestFormatIDs(ctx)
+		}
+		if err == nil {
+			p.Logger.Printf("querying %s", p.Endpoint)
+			harvested = time.Now()
+			err = p.harvest(ctx, harvested)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	p.Logger.Printf("received %d results", p.summary.AllSparqlResults)
+	if unbound := p.summary.alwaysUnbound(); len(unbound) > 0 && p.summary.AllSparqlResults > 0 {
+		p.Logger.Printf("no result bound %s, check the variables of the query", strings.Join(unbound, ", "))
+	}
+	p.manifest = p.newManifest(p.harvestQuery(), harvested)
+	if p.Duplicates != "" && p.Input != "" {
+		p.Logger.Printf("duplicate statements cannot be reported from cached results")
+	} else if p.Duplicates != "" {
+		results, err := p.runQuery(ctx, buildQuery(duplicatesQuery, p.Config.Properties))
+		if err != nil {
+			return err
+		}
+		duplicates := p.findDuplicates(results)
+		for idx := range duplicates {
+			duplicates[idx].Key = p.duplicateKey(duplicates[idx])
+		}
+		lintDuplicates(&p.summary, duplicates)
+		p.analyseGrades()
+		if err := p.exportDuplicates(p.Duplicates, duplicates); err != nil {
+			return fmt.Errorf("cannot write duplicates report: %s", err)
+		}
+	}
+	if p.Versions != "" && p.Input != "" {
+		p.Logger.Printf("version chains cannot be reported from cached results")
+	} else if p.Versions != "" {
+		results, err := p.runQuery(ctx, buildQuery(versionsQuery, p.Config.Properties))
+		if err != nil {
+			return err
+		}
+		if err := p.exportVersions(p.Versions, p.versionChains(results)); err != nil {
+			return fmt.Errorf("cannot write version chains: %s", err)
+		}
+	}
+	if p.Classes != "" && p.Input != "" {
+		p.Logger.Printf("class statistics cannot be reported from cached results")
+	} else if p.Classes != "" {
+		results, err := p.runQuery(ctx, buildQuery(classesQuery, p.Config.Properties))
+		if err != nil {
+			return err
+		}
+		if err := p.exportClasses(p.Classes, p.classStats(results)); err != nil {
+			return fmt.Errorf("cannot write class statistics: %s", err)
+		}
+	}
+	if len(p.Languages) > 0 && p.Input != "" {
+		p.Logger.Printf("labels cannot be harvested from cached results")
+	} else if len(p.Languages) > 0 {
+		if err := p.harvestLabels(ctx); err != nil {
+			return err
+		}
+	}
+	if p.Enrich && p.Input != "" {
+		p.Logger.Printf("records cannot be enriched from cached results")
+	} else if p.Enrich {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.enrichRecords(ctx)
+	}
+	// An endpoint glitch or query drift can leave us with no signatures at
+	// all. Exporting would then overwrite good identifier outputs with an
+	// empty identifier.
+	if p.manifest.Signatures == 0 && !p.Force {
+		p.Logger.Printf("no signatures were harvested, exports are not written")
+		p.notify(p.Config.Notify)
+		p.shareStats()
+		return ErrNoSignatures
+	}
+	suspicious, err := p.checkThresholds()
+	if err != nil {
+		return err
+	}
+	for _, exporter := range p.Exporters {
+		if _, ok := exporter.(publisher); ok && suspicious {
+			p.Logger.Printf("the run is suspicious, %T is not written", exporter)
+			continue
+		}
+		if err := exporter.Export(p); err != nil {
+			return err
+		}
+	}
+	p.notify(p.Config.Notify)
+	p.shareStats()
+	if suspicious {
+		return ErrSuspicious
+	}
+	return nil
+}
+
+// Process condenses SPARQL results into records and analyses them. It is
+// used by Run and can be used to process results harvested previously.
+func (p *Pipeline) Process(results []map[string]spargo.Item) error {
+	if err := p.init(); err != nil {
+		return err
+	}
+	if len(p.QIDs) > 0 {
+		results = p.filterQIDs(results)
+	}
+	if p.Shard != nil {
+		results = p.filterShard(results)
+	}
+	if p.Progress != nil {
+		p.progress = newProgress(p.Progress, len(results))
+	}
+	p.condense(results)
+	p.progress.done()
+	p.analyse(len(results))
+	return nil
+}
+
+// analyse analyses the records condensed from rows of SPARQL results.
+func (p *Pipeline) analyse(rows int) {
+	if len(p.fetched) > 0 {
+		p.mergeFetched()
+	}
+	if len(p.PUIDs) > 0 {
+		p.filterPUIDs()
+	}
+	p.summary.AllSparqlResults = rows
+	p.summary.CondensedSparqlResults = len(p.records)
+	if p.Incremental != nil {
+		p.mergeIncremental(p.formatIDs)
+	}
+	p.analyseWikidataRecords()
+	analyseSources(&p.summary, p.Records())
+	analyseMediaTypes(&p.summary, p.Records())
+	if p.StaleYears > 0 {
+		analyseStaleness(&p.summary, p.Records(), p.StaleYears, time.Now())
+	}
+	if len(p.Overrides) > 0 {
+		p.analyseOverrides()
+	}
+	if p.Outliers {
+		p.analyseOutliers()
+	}
+	p.analyseGrades()
+}
+
+// Records returns the processed records ordered by ID so that exports are
+// deterministic between runs.
+func (p *Pipeline) Records() []Wikidata {
+	var records []Wikidata
+	for _, wd := range p.records {
+		records = append(records, wd)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+// ExportRecords returns the processed records with the scope and length
+// policies applied ready to be exported. If the pipeline creates a
+// supplement to PRONOM only the signatures Wikidata adds are returned, and
+// if Changed is set only the records that differ from the baseline. The
+// sequences that are excluded are recorded in the summary, and sequences
+// are written in the pipeline's HexStyle.
+func (p *Pipeline) ExportRecords() ([]Wikidata, error) {
+	// Every exporter applies the policies to the same records so the
+	// exclusions are recorded afresh each time.
+	excluded := newExclusions()
+	p.summary.Exclusions = excluded
+	records := p.Records()
+	if p.Supplement != nil {
+		records = p.Supplement.supplement(records, excluded)
+	}
+	records, err := applyScopePolicy(records, p.Scope, excluded)
+	if err != nil {
+		return nil, err
+	}
+	records, err = applyLengthPolicy(records, p.MaxLength, p.Length, excluded)
+	if err != nil || !p.Changed || p.Baseline == nil {
+		return styleRecords(records, p.HexStyle), err
+	}
+	records, removed, err := p.Baseline.changed(records)
+	if err != nil {
+		return nil, err
+	}
+	p.Logger.Printf("exporting %d records changed since the baseline, %d baseline records are no longer present", len(records), removed)
+	return styleRecords(records, p.HexStyle), nil
+}
+
+// Summary returns the summary of the processed records.
+func (p *Pipeline) Summary() Summary {
+	summary := p.summary
+	summary.Worklists = worklists(summary.Lints)
+	summary.Severities = severityCounts(summary.Lints)
+	if p.manifest.Harvested != "" {
+		manifest := p.manifest
+		summary.Manifest = &manifest
+	}
+	summary.Retries = int(atomic.LoadInt32(&p.retries))
+	if p.transfer != nil {
+		summary.Transfer = Transfer{
+			Transferred:  atomic.LoadInt64(&p.transfer.Transferred),
+			Decompressed: atomic.LoadInt64(&p.transfer.Decompressed),
+		}
+	}
+	return summary
+}
+
+// Manifest returns the description of the harvest.
+func (p *Pipeline) Manifest() Manifest {
+	return p.manifest
+}
+
+// runQuery runs a query against the pipeline's endpoint.
+func (p *Pipeline) runQuery(ctx context.Context, query string) ([]map[string]spargo.Item, error) {
+	res, err := p.runResponse(ctx, query)
+	return res.Results.Bindings, err
+}
+
+// runResponse runs a query against the pipeline's endpoint and returns the
+// complete response. If the results repeatedly cannot be parsed they are
+// requested as CSV instead.
+func (p *Pipeline) runResponse(ctx context.Context, query string) (spargo.SPARQLResult, error) {
+	for attempt := 1; attempt <= jsonAttempts; attempt++ {
+		res, err := p.runJSON(ctx, query)
+		if _, ok := err.(parseError); !ok {
+			return res, err
+		}
+		p.Logger.Printf("attempt %d of %d: %s", attempt, jsonAttempts, err)
+	}
+	p.Logger.Printf("requesting results as CSV instead")
+	res, err := p.runCSV(p.contextClient(ctx), query)
+	if err != nil {
+		return res, fmt.Errorf("query to %s failed: %s", p.Endpoint, err)
+	}
+	return res, nil
+}
+
+// runJSON runs a query requesting JSON results. spargo panics when a
+// request fails so the panic is recovered and returned as an error.
+func (p *Pipeline) runJSON(ctx context.Context, query string) (res spargo.SPARQLResult, err error) {
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			if isParseError(r) {
+				err = parseError{err: r.(error)}
+				return
+			}
+			err = fmt.Errorf("query to %s failed: %v", p.Endpoint, r)
+		}
+	}()
+	sparqlMe := spargo.SPARQLClient{}
+	sparqlMe.Client = p.contextClient(ctx)
+	sparqlMe.ClientInit(p.Endpoint, query)
+	res = sparqlMe.SPARQLGo()
+	// spargo returns an empty result rather than an error when the
+	// endpoint responds with an error status.
+	if res.Head == nil {
+		return res, fmt.Errorf("query to %s failed: no results from server", p.Endpoint)
+	}
+	return res, nil
+}
+
+// harvest runs the harvest query, processes the results, and caches the
+// response. A single request is processed as the results are received. If
+// they cannot be parsed they are requested again and processed once they
+// have been received in full.
+func (p *Pipeline) harvest(ctx context.Context, harvested time.Time) error {
+	q := p.harvestQuery()
+	var res spargo.SPARQLResult
+	var err error
+	if p.PageSize > 0 {
+		res, err = p.harvestPages(ctx, q)
+	} else if p.Partitions > 1 {
+		res, err = p.harvestPartitions(ctx, q)
+	} else {
+		p.summary.Pages = 1
+		err = p.streamHarvest(ctx, q, harvested)
+		if _, ok := err.(parseError); !ok {
+			return err
+		}
+		p.Logger.Printf("streaming results failed: %s", err)
+		p.resetHarvest()
+		res, err = p.runResponse(ctx, q)
+	}
+	if err != nil {
+		return err
+	}
+	if err := validateResults(res); err != nil {
+		return err
+	}
+	if err := writeFile(p.Cache, []byte(res.Human)); err != nil {
+		p.Logger.Printf("cannot cache results: %s", err)
+	} else if err := p.writeCacheEntry(len(res.Results.Bindings), harvested); err != nil {
+		p.Logger.Printf("cannot describe cached results: %s", err)
+	}
+	return p.Process(res.Results.Bindings)
+}
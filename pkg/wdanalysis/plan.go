@@ -0,0 +1,116 @@
+package wdanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plan mode runs count-only queries before a full harvest and estimates the
+// resources the harvest will need so that users on constrained machines know
+// what they are in for. Estimates are deliberately rough.
+
+// Figures used to estimate resource use from the number of rows a harvest
+// is expected to return. A row of SPARQL JSON is typically around 1KB and
+// is held in memory more than once while records are condensed.
+const (
+	planBytesPerRow   = 4096
+	planRowsPerSecond = 2500
+)
+
+var formatsCountQuery = `
+	SELECT (COUNT(DISTINCT ?format) AS ?count) WHERE
+	{
+	  ?format wdt:<<instanceOf>>/wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	}
+`
+
+var signaturesCountQuery = `
+	SELECT (COUNT(DISTINCT ?format) AS ?count) WHERE
+	{
+	  ?format wdt:<<instanceOf>>/wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	  VALUES (?sigProperty ?sigDirect ?sigStatement ?sigValue) { <<signatureValues>> }
+	  ?format ?sigDirect ?sig.
+	}
+`
+
+// Plan describes the expected size of a harvest.
+type Plan struct {
+	Formats               int    `json:"formats"`
+	FormatsWithSignatures int    `json:"formatsWithSignatures"`
+	ExpectedRows          int    `json:"expectedRows"`
+	EstimatedRuntime      string `json:"estimatedRuntime"`
+	EstimatedMemory       string `json:"estimatedMemory"`
+	PlanningTime          string `json:"planningTime"`
+}
+
+// String will return the plan to be printed.
+func (p Plan) String() string {
+	report, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s", report)
+}
+
+// rowsCountQuery wraps a query so that it returns only the number of rows
+// it would otherwise return.
+func rowsCountQuery(query string) string {
+	if idx := strings.LastIndex(strings.ToLower(query), "order by"); idx != -1 {
+		query = query[:idx]
+	}
+	return fmt.Sprintf("SELECT (COUNT(*) AS ?count) WHERE {\n%s\n}", query)
+}
+
+// runCount runs a count query and returns the count.
+func (p *Pipeline) runCount(ctx context.Context, query string) (int, error) {
+	results, err := p.runQuery(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) != 1 {
+		return 0, fmt.Errorf("expected a single count, received %d results", len(results))
+	}
+	return strconv.Atoi(results[0]["count"].Value)
+}
+
+// formatBytes returns a number of bytes in a human-readable form.
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// Plan runs the count queries for a harvest and estimates its resource use.
+func (p *Pipeline) Plan(ctx context.Context) (Plan, error) {
+	var plan Plan
+	if err := p.init(); err != nil {
+		return plan, err
+	}
+	var err error
+	start := time.Now()
+	if plan.Formats, err = p.runCount(ctx, buildQuery(formatsCountQuery, p.Config.Properties)); err != nil {
+		return plan, fmt.Errorf("cannot count formats: %s", err)
+	}
+	if plan.FormatsWithSignatures, err = p.runCount(ctx, buildQuery(signaturesCountQuery, p.Config.Properties)); err != nil {
+		return plan, fmt.Errorf("cannot count formats with signatures: %s", err)
+	}
+	if plan.ExpectedRows, err = p.runCount(ctx, rowsCountQuery(p.harvestQuery())); err != nil {
+		return plan, fmt.Errorf("cannot count rows: %s", err)
+	}
+	runtime := time.Duration(plan.ExpectedRows/planRowsPerSecond) * time.Second
+	plan.EstimatedRuntime = runtime.String()
+	plan.EstimatedMemory = formatBytes(int64(plan.ExpectedRows) * planBytesPerRow)
+	plan.PlanningTime = time.Since(start).Round(time.Millisecond).String()
+	return plan, nil
+}
@@ -0,0 +1,91 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Processing the full file format class takes long enough that interactive
+// users want to know how far along it is. Progress is reported as a bar
+// with the rows processed, the records they have been condensed into, and
+// an estimate of the time remaining. Updates are safe to make from several
+// goroutines and are drawn at most every progressInterval.
+
+// progressInterval is the shortest time between redraws of the bar.
+const progressInterval = 100 * time.Millisecond
+
+// progressWidth is the width of the bar in characters.
+const progressWidth = 30
+
+// progress draws a progress bar.
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	total   int
+	rows    int
+	records int
+	start   time.Time
+	drawn   time.Time
+	ended   bool
+}
+
+// newProgress returns a progress bar for total rows drawn to w. A total of
+// zero means the total is not known.
+func newProgress(w io.Writer, total int) *progress {
+	return &progress{w: w, total: total, start: time.Now()}
+}
+
+// add records rows that have been processed and the number of new records
+// they created. Rows added once the bar has ended are not drawn.
+func (pr *progress) add(rows int, records int) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if pr.ended {
+		return
+	}
+	pr.rows += rows
+	pr.records += records
+	if time.Since(pr.drawn) >= progressInterval || pr.rows == pr.total {
+		pr.draw()
+	}
+}
+
+// done draws the bar for the last time and ends its line.
+func (pr *progress) done() {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.draw()
+	fmt.Fprintln(pr.w)
+	pr.ended = true
+}
+
+// draw draws the bar. The caller must hold the lock. When the total is not
+// known, as when results are processed as they are received, only the
+// counts are drawn.
+func (pr *progress) draw() {
+	pr.drawn = time.Now()
+	if pr.total == 0 {
+		fmt.Fprintf(pr.w, "\r%d rows, %d formats ", pr.rows, pr.records)
+		return
+	}
+	fraction := float64(pr.rows) / float64(pr.total)
+	filled := int(fraction * progressWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+	eta := "?"
+	if pr.rows > 0 {
+		elapsed := time.Since(pr.start)
+		remaining := time.Duration(float64(elapsed) / float64(pr.rows) * float64(pr.total-pr.rows))
+		eta = remaining.Round(time.Second).String()
+	}
+	fmt.Fprintf(pr.w, "\r[%s] %3.0f%% %d/%d rows, %d formats, ETA %s ",
+		bar, fraction*100, pr.rows, pr.total, pr.records, eta)
+}
@@ -0,0 +1,112 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The property map describes the Wikidata properties we harvest. Queries
+// are written using <<placeholders>> for each property which are replaced
+// with the values from the property map before a query is sent. Other
+// Wikibase instances number their properties differently so the map can be
+// replaced via configuration to harvest from them.
+
+// PropertyMap lists the properties used to describe a file format.
+type PropertyMap struct {
+	InstanceOf string `json:"instanceOf"` // Instance of a class.
+	SubclassOf string `json:"subclassOf"` // Subclass of a class.
+	FileFormat string `json:"fileFormat"` // Root class of the formats harvested.
+	PUID       string `json:"puid"`       // PRONOM unique identifier.
+	LOC        string `json:"loc"`        // Library of Congress FDD identifier.
+	Extension  string `json:"extension"`  // File extension.
+	MIMEType   string `json:"mimetype"`   // MIME type.
+	Version    string `json:"version"`    // Software version identifier.
+	Signature  string `json:"signature"`  // Format identification pattern.
+	Reference  string `json:"reference"`  // Reference (stated in) for a signature.
+	Date       string `json:"date"`       // Date a reference was retrieved.
+	Encoding   string `json:"encoding"`   // Encoding qualifier of a signature.
+	Offset     string `json:"offset"`     // Offset qualifier of a signature.
+	Relativity string `json:"relativity"` // Relativity qualifier of a signature.
+	Part       string `json:"part"`       // Applies to part qualifier of a signature.
+	Follows    string `json:"follows"`    // Version a format follows.
+	FollowedBy string `json:"followedBy"` // Version a format is followed by.
+
+	// AdditionalSignatures lists properties beyond Signature that record
+	// identification bytes. Their values are harvested alongside those of
+	// Signature and are marked with the property that supplied them.
+	AdditionalSignatures []string `json:"additionalSignatures"`
+}
+
+var defaultProperties = PropertyMap{
+	InstanceOf: "P31",
+	SubclassOf: "P279",
+	FileFormat: "Q235557",
+	PUID:       "P2748",
+	LOC:        "P3266",
+	Extension:  "P1195",
+	MIMEType:   "P1163",
+	Version:    "P348",
+	Signature:  "P4152",
+	Reference:  "P248",
+	Date:       "P813",
+	Encoding:   "P3294",
+	Offset:     "P4153",
+	Relativity: "P2210",
+	Part:       "P518",
+	Follows:    "P155",
+	FollowedBy: "P156",
+}
+
+// signatureProperties returns every property that records identification
+// bytes.
+func (props PropertyMap) signatureProperties() []string {
+	return append([]string{props.Signature}, props.AdditionalSignatures...)
+}
+
+// signatureValues returns a SPARQL VALUES body pairing the name of each
+// signature property with the prefixed properties used to retrieve it,
+// e.g. ("P4152" wdt:P4152 p:P4152 ps:P4152).
+func (props PropertyMap) signatureValues() string {
+	var values []string
+	for _, prop := range props.signatureProperties() {
+		values = append(values, fmt.Sprintf("(\"%s\" wdt:%s p:%s ps:%s)", prop, prop, prop, prop))
+	}
+	return strings.Join(values, " ")
+}
+
+// signatureStatements returns a SPARQL property path matching the statement
+// of any signature property, e.g. p:P4152|p:P9999.
+func (props PropertyMap) signatureStatements() string {
+	var paths []string
+	for _, prop := range props.signatureProperties() {
+		paths = append(paths, fmt.Sprintf("p:%s", prop))
+	}
+	return strings.Join(paths, "|")
+}
+
+// buildQuery replaces the placeholders in a query template with values from
+// the property map.
+func buildQuery(template string, props PropertyMap) string {
+	replacer := strings.NewReplacer(
+		"<<instanceOf>>", props.InstanceOf,
+		"<<subclassOf>>", props.SubclassOf,
+		"<<fileFormat>>", props.FileFormat,
+		"<<puid>>", props.PUID,
+		"<<loc>>", props.LOC,
+		"<<extension>>", props.Extension,
+		"<<mimetype>>", props.MIMEType,
+		"<<version>>", props.Version,
+		"<<reference>>", props.Reference,
+		"<<date>>", props.Date,
+		"<<encoding>>", props.Encoding,
+		"<<offset>>", props.Offset,
+		"<<relativity>>", props.Relativity,
+		"<<part>>", props.Part,
+		"<<follows>>", props.Follows,
+		"<<followedBy>>", props.FollowedBy,
+		"<<signature>>", props.Signature,
+		"<<signatureValues>>", props.signatureValues(),
+		"<<signatureStatements>>", props.signatureStatements(),
+	)
+	return replacer.Replace(template)
+}
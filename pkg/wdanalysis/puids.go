@@ -0,0 +1,69 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Institutions that identify formats with PRONOM often only care about the
+// Wikidata records of the formats they hold. The harvest can be restricted
+// to the records mapped to a list of PUIDs. Records keep all of their
+// PUIDs, not only the ones asked for, and results replayed from the cache
+// are restricted in the same way.
+
+// puidPattern matches a PRONOM unique identifier, e.g. fmt/43 or x-fmt/111.
+var puidPattern = regexp.MustCompile(`^[a-z][a-z-]*/[0-9]+$`)
+
+// ParsePUIDs splits a comma separated list of PUIDs.
+func ParsePUIDs(value string) ([]string, error) {
+	var puids []string
+	for _, puid := range strings.Split(value, ",") {
+		puid = strings.ToLower(strings.TrimSpace(puid))
+		if puid == "" {
+			continue
+		}
+		if !puidPattern.MatchString(puid) {
+			return nil, fmt.Errorf("invalid PUID '%s'", puid)
+		}
+		if !contains(puids, puid) {
+			puids = append(puids, puid)
+		}
+	}
+	return puids, nil
+}
+
+// puidValues returns a clause matching formats mapped to any of the PUIDs.
+// A variable of its own is used so that ?puid still returns every PUID of
+// a format.
+func puidValues(puids []string, props PropertyMap) string {
+	var values []string
+	for _, puid := range puids {
+		values = append(values, fmt.Sprintf("%q", puid))
+	}
+	return fmt.Sprintf("VALUES ?puidFilter { %s } ?format wdt:%s ?puidFilter.", strings.Join(values, " "), props.PUID)
+}
+
+// matchesPUIDs returns true if a record is mapped to any of the pipeline's
+// PUIDs, or the pipeline has none.
+func (p *Pipeline) matchesPUIDs(wd Wikidata) bool {
+	if len(p.PUIDs) == 0 {
+		return true
+	}
+	for _, puid := range wd.PRONOM {
+		if contains(p.PUIDs, puid) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterPUIDs removes the records that are not mapped to any of the
+// pipeline's PUIDs.
+func (p *Pipeline) filterPUIDs() {
+	for id, wd := range p.records {
+		if !p.matchesPUIDs(wd) {
+			delete(p.records, id)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// A problem with a single record is easier to investigate when it is
+// harvested and processed on its own. The harvest can be restricted to a
+// list of QIDs, and results replayed from the cache are restricted to them
+// too, so that the findings for just those records can be reported.
+
+// qidPattern matches the QID of an item.
+var qidPattern = regexp.MustCompile(`^Q[0-9]+$`)
+
+// ParseQIDs splits a comma separated list of QIDs.
+func ParseQIDs(value string) ([]string, error) {
+	var qids []string
+	for _, qid := range strings.Split(value, ",") {
+		qid = strings.ToUpper(strings.TrimSpace(qid))
+		if qid == "" {
+			continue
+		}
+		if !qidPattern.MatchString(qid) {
+			return nil, fmt.Errorf("invalid QID '%s'", qid)
+		}
+		if !contains(qids, qid) {
+			qids = append(qids, qid)
+		}
+	}
+	return qids, nil
+}
+
+// qidValues returns a VALUES clause binding ?format to each QID.
+func qidValues(qids []string) string {
+	var items []string
+	for _, qid := range qids {
+		items = append(items, fmt.Sprintf("wd:%s", qid))
+	}
+	return fmt.Sprintf("VALUES ?format { %s }", strings.Join(items, " "))
+}
+
+// filterQIDs returns the results for the pipeline's QIDs.
+func (p *Pipeline) filterQIDs(results []map[string]spargo.Item) []map[string]spargo.Item {
+	filtered := []map[string]spargo.Item{}
+	for _, result := range results {
+		if contains(p.QIDs, p.id(result[formatField].Value)) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// RecordReport describes a record and every finding against it.
+type RecordReport struct {
+	Record Wikidata     `json:"record"`
+	Lints  []LintResult `json:"lints"`
+}
+
+// RecordReports returns every record along with its findings.
+func (p *Pipeline) RecordReports() []RecordReport {
+	reports := []RecordReport{}
+	for _, wd := range p.Records() {
+		report := RecordReport{Record: wd, Lints: []LintResult{}}
+		for code := range p.summary.Lints[wd.URI] {
+			report.Lints = append(report.Lints, newLintResult(wd.URI, code, false))
+		}
+		sort.Slice(report.Lints, func(i, j int) bool {
+			return report.Lints[i].Code < report.Lints[j].Code
+		})
+		reports = append(reports, report)
+	}
+	return reports
+}
@@ -0,0 +1,299 @@
+package wdanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// The harvest query can be replaced so that it can be developed without
+// rebuilding the tool. A custom query must return the variables we need to
+// create records. Large harvests can time out on WDQS so the query can also
+// be run a page at a time.
+
+// RequiredVariables are the variables a harvest query must return.
+var RequiredVariables = []string{
+	formatField,
+	labelField,
+	sigField,
+	encodingField,
+	offsetField,
+	relativityLabelField,
+}
+
+// projection matches the projection of a SELECT query.
+var projection = regexp.MustCompile(`(?is)\bSELECT\s+(?:DISTINCT\s+|REDUCED\s+)?(.*?)(?:\bWHERE\b|\{)`)
+
+// variable matches a variable in a query.
+var variable = regexp.MustCompile(`[?$](\w+)`)
+
+// alias matches the variable an expression in a projection is bound to.
+var alias = regexp.MustCompile(`(?i)\bAS\s+[?$](\w+)\s*\)$`)
+
+// projectedVariables returns the names of the variables projected by a
+// SELECT query, including those bound to expressions. None are returned if
+// the query projects every variable with *.
+func projectedVariables(query string) []string {
+	match := projection.FindStringSubmatch(query)
+	if match == nil {
+		return nil
+	}
+	var vars []string
+	depth, start := 0, 0
+	for i, r := range match[1] {
+		switch r {
+		case '(':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				if bound := alias.FindStringSubmatch(match[1][start : i+1]); bound != nil {
+					vars = append(vars, bound[1])
+				}
+			}
+		case '?', '$':
+			if depth == 0 {
+				if v := variable.FindStringSubmatch(match[1][i:]); v != nil {
+					vars = append(vars, v[1])
+				}
+			}
+		}
+	}
+	return vars
+}
+
+// missingVariables returns the required variables that are not in vars.
+func missingVariables(vars []string) []string {
+	var missing []string
+	for _, required := range RequiredVariables {
+		if !contains(vars, required) {
+			missing = append(missing, required)
+		}
+	}
+	return missing
+}
+
+// validateQuery checks that a query template selects the required
+// variables. Queries selecting * are checked when results are received.
+func validateQuery(query string) error {
+	match := projection.FindStringSubmatch(query)
+	if match == nil {
+		return fmt.Errorf("harvest query is not a SELECT query")
+	}
+	if strings.Contains(match[1], "*") {
+		return nil
+	}
+	if missing := missingVariables(projectedVariables(query)); len(missing) > 0 {
+		return fmt.Errorf("harvest query does not select required variables: %v", missing)
+	}
+	return nil
+}
+
+// validateResults checks that the results of a harvest query describe the
+// required variables.
+func validateResults(res spargo.SPARQLResult) error {
+	head, ok := res.Head["vars"].([]interface{})
+	if !ok {
+		if vars, ok := res.Head["vars"].([]string); ok {
+			for _, v := range vars {
+				head = append(head, v)
+			}
+		}
+	}
+	var vars []string
+	for _, v := range head {
+		vars = append(vars, fmt.Sprintf("%s", v))
+	}
+	return checkVariables(vars)
+}
+
+// variablesError describes results that do not include the required
+// variables.
+type variablesError struct {
+	missing []string
+}
+
+// Error satisfies the error interface.
+func (e variablesError) Error() string {
+	return fmt.Sprintf("harvest results do not include required variables: %v", e.missing)
+}
+
+// checkVariables checks that the variables of results include the required
+// variables.
+func checkVariables(vars []string) error {
+	if missing := missingVariables(vars); len(missing) > 0 {
+		return variablesError{missing: missing}
+	}
+	return nil
+}
+
+// limitOffset matches a LIMIT or OFFSET in the solution modifiers of a
+// query.
+var limitOffset = regexp.MustCompile(`(?i)\b(?:LIMIT|OFFSET)\s+\d+`)
+
+// orderBy matches an ORDER BY in the solution modifiers of a query.
+var orderBy = regexp.MustCompile(`(?i)\bORDER\s+BY\b`)
+
+// totalOrder orders the results of a query by every variable it projects,
+// after any order it already has, so that no two rows can be ordered
+// differently by separate runs of the query. A query with its own LIMIT or
+// OFFSET cannot be run in pages.
+func totalOrder(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	// The solution modifiers of a query follow its WHERE clause, so those
+	// of subqueries are left alone.
+	modifiers := query[strings.LastIndex(query, "}")+1:]
+	if limitOffset.MatchString(modifiers) {
+		return "", fmt.Errorf("a query with a LIMIT or OFFSET cannot be harvested in pages")
+	}
+	order := orderBy.FindStringIndex(modifiers)
+	ordered := make(map[string]bool)
+	if order != nil {
+		for _, v := range variable.FindAllStringSubmatch(modifiers[order[1]:], -1) {
+			ordered[v[1]] = true
+		}
+	}
+	var keys []string
+	for _, v := range projectedVariables(query) {
+		if !ordered[v] {
+			ordered[v] = true
+			keys = append(keys, "?"+v)
+		}
+	}
+	if len(keys) == 0 {
+		return query, nil
+	}
+	if order == nil {
+		return fmt.Sprintf("%s\nORDER BY %s", query, strings.Join(keys, " ")), nil
+	}
+	return fmt.Sprintf("%s %s", query, strings.Join(keys, " ")), nil
+}
+
+// pageQuery returns the query for a single page of results. Pages are only
+// consistent if every run of the query returns its rows in the same order,
+// so the results are ordered by every projected variable.
+func pageQuery(query string, size int, page int) string {
+	// The query was checked when the pipeline was initialized.
+	ordered, _ := totalOrder(query)
+	return fmt.Sprintf("%s\nLIMIT %d OFFSET %d", ordered, size, page*size)
+}
+
+// harvestPages runs a query a page at a time and stitches the pages
+// together into a single response. Pages are recorded in a checkpoint as
+// they are received and, if the pipeline resumes a harvest, the pages in
+// its checkpoint are not requested again.
+func (p *Pipeline) harvestPages(ctx context.Context, query string) (spargo.SPARQLResult, error) {
+	var res spargo.SPARQLResult
+	delay, err := parseDuration(p.Config.Network.PageDelay, defaultPageDelay)
+	if err != nil {
+		return res, fmt.Errorf("invalid page delay: %s", err)
+	}
+	var pages []checkpointPage
+	if p.Resume {
+		start := time.Now()
+		if pages, err = p.readCheckpoint(query); err != nil {
+			return res, fmt.Errorf("cannot read checkpoint: %s", err)
+		}
+		p.Logger.Printf("resuming harvest after %d pages", len(pages))
+		for _, page := range pages {
+			// The page is read back as the line it was written as.
+			line, _ := json.Marshal(page)
+			p.auditCacheHit(pageQuery(query, p.PageSize, page.Page), p.checkpointPath(), int64(len(line)+1), start)
+		}
+	}
+	complete := false
+	for _, page := range pages {
+		if page.Page == 0 {
+			res.Head = page.Head
+		}
+		res.Results.Bindings = append(res.Results.Bindings, page.Bindings...)
+		p.summary.Pages++
+		complete = len(page.Bindings) < p.PageSize
+	}
+	cp, err := p.openCheckpoint(query, pages)
+	if err != nil {
+		p.Logger.Printf("cannot write checkpoint: %s", err)
+	}
+	defer cp.close()
+	for page := len(pages); !complete; page++ {
+		if page > 0 {
+			select {
+			case <-ctx.Done():
+				return res, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		pageRes, err := p.runResponse(ctx, pageQuery(query, p.PageSize, page))
+		if err != nil {
+			return res, fmt.Errorf("cannot harvest page %d: %s", page+1, err)
+		}
+		if page == 0 {
+			res.Head = pageRes.Head
+		}
+		res.Results.Bindings = append(res.Results.Bindings, pageRes.Results.Bindings...)
+		p.summary.Pages++
+		p.Logger.Printf("page %d: received %d results", page+1, len(pageRes.Results.Bindings))
+		if cp != nil {
+			if err := cp.write(checkpointPage{Page: page, Head: pageRes.Head, Bindings: pageRes.Results.Bindings}); err != nil {
+				p.Logger.Printf("cannot write checkpoint: %s", err)
+				cp.close()
+				cp = nil
+			}
+		}
+		complete = len(pageRes.Results.Bindings) < p.PageSize
+	}
+	human, err := sparqlJSON(res)
+	if err != nil {
+		return res, err
+	}
+	res.Human = human
+	if err := cp.remove(); err != nil {
+		p.Logger.Printf("cannot remove checkpoint: %s", err)
+	}
+	return res, nil
+}
+
+// insertWhere inserts a clause at the start of the WHERE clause of a query.
+func insertWhere(query string, clause string) (string, error) {
+	where := strings.Index(strings.ToUpper(query), "WHERE")
+	if where < 0 {
+		return "", fmt.Errorf("query has no WHERE clause")
+	}
+	open := strings.Index(query[where:], "{")
+	if open < 0 {
+		return "", fmt.Errorf("query has no WHERE clause")
+	}
+	open += where + 1
+	return query[:open] + "\n\t  " + clause + query[open:], nil
+}
+
+// harvestQuery returns the query the pipeline harvests with, restricted to
+// the pipeline's QIDs and PUIDs and, when harvesting incrementally, to the
+// records modified since the previous export.
+func (p *Pipeline) harvestQuery() string {
+	// The query and its restrictions were validated when the pipeline was
+	// initialized.
+	query := buildQuery(p.Query, p.Config.Properties)
+	if len(p.QIDs) > 0 {
+		query, _ = insertWhere(query, qidValues(p.QIDs))
+	}
+	if len(p.PUIDs) > 0 {
+		query, _ = insertWhere(query, puidValues(p.PUIDs, p.Config.Properties))
+	}
+	if p.Shard != nil {
+		query, _ = insertWhere(query, p.Shard.filter())
+	}
+	if p.Incremental != nil {
+		since, _ := p.Incremental.since()
+		query, _ = sinceQuery(query, since)
+	}
+	return query
+}
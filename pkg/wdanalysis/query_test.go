@@ -0,0 +1,125 @@
+package wdanalysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestTotalOrder checks that queries are ordered by every variable they
+// project, after the order they already have, and that queries with their
+// own LIMIT or OFFSET are refused.
+func TestTotalOrder(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			"SELECT ?a ?b WHERE { ?a ?p ?b } ORDER BY ?a",
+			"SELECT ?a ?b WHERE { ?a ?p ?b } ORDER BY ?a ?b",
+		},
+		{
+			"SELECT DISTINCT ?a ?b WHERE { ?a ?p ?b }",
+			"SELECT DISTINCT ?a ?b WHERE { ?a ?p ?b }\nORDER BY ?a ?b",
+		},
+		{
+			"select ?a ?b where { ?a ?p ?b } order by desc(?b)",
+			"select ?a ?b where { ?a ?p ?b } order by desc(?b) ?a",
+		},
+		{
+			"SELECT ?a (COUNT(?b) AS ?n) WHERE { ?a ?p ?b } GROUP BY ?a",
+			"SELECT ?a (COUNT(?b) AS ?n) WHERE { ?a ?p ?b } GROUP BY ?a\nORDER BY ?a ?n",
+		},
+		{
+			"SELECT ?a WHERE { { SELECT ?a WHERE { ?a ?p ?b } ORDER BY ?a } }",
+			"SELECT ?a WHERE { { SELECT ?a WHERE { ?a ?p ?b } ORDER BY ?a } }\nORDER BY ?a",
+		},
+		{
+			"SELECT ?a ?b WHERE { ?a ?p ?b } ORDER BY ?b ?a",
+			"SELECT ?a ?b WHERE { ?a ?p ?b } ORDER BY ?b ?a",
+		},
+		{
+			"SELECT * WHERE { ?a ?p ?b }",
+			"SELECT * WHERE { ?a ?p ?b }",
+		},
+		{
+			"SELECT ?a WHERE { { SELECT ?a WHERE { ?a ?p ?b } LIMIT 5 } }",
+			"SELECT ?a WHERE { { SELECT ?a WHERE { ?a ?p ?b } LIMIT 5 } }\nORDER BY ?a",
+		},
+		{
+			"SELECT ?a ?b WHERE { ?a ?p ?b } LIMIT 10",
+			"",
+		},
+		{
+			"SELECT ?a ?b WHERE { ?a ?p ?b } OFFSET 10",
+			"",
+		},
+		{
+			"SELECT ?a ?b WHERE { ?a ?p ?b } ORDER BY ?x LIMIT 5",
+			"",
+		},
+		{
+			"SELECT ?a ?b WHERE { ?a ?p ?b }\norder by ?a\nlimit 5 offset 10\n",
+			"",
+		},
+	}
+	for _, test := range tests {
+		got, err := totalOrder(test.query)
+		if test.want == "" && err == nil {
+			t.Errorf("totalOrder(%q) = %q, want an error", test.query, got)
+		}
+		if test.want != "" && got != test.want {
+			t.Errorf("totalOrder(%q) = %q %v, want %q", test.query, got, err, test.want)
+		}
+	}
+}
+
+// TestPageQuery checks that the pages of the harvest query are ordered by
+// every variable the query projects.
+func TestPageQuery(t *testing.T) {
+	query := pageQuery(buildQuery(DefaultQuery, defaultProperties), 1000, 2)
+	if !strings.HasSuffix(query, "\nLIMIT 1000 OFFSET 2000") {
+		t.Errorf("got a page ending %q, want LIMIT 1000 OFFSET 2000", query[len(query)-40:])
+	}
+	order := query[strings.LastIndex(query, "order by"):]
+	for _, v := range projectedVariables(DefaultQuery) {
+		if !strings.Contains(order+" ", "?"+v+" ") && !strings.Contains(order, "?"+v+"\n") {
+			t.Errorf("the page is not ordered by %s: %s", v, order)
+		}
+	}
+	if !strings.HasPrefix(order, "order by ?format ?formatLabel") {
+		t.Errorf("got %q, want the results ordered by ?format first", order)
+	}
+}
+
+// TestProjectedVariables checks the variables read from the projections of
+// queries.
+func TestProjectedVariables(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT ?a ?b WHERE { ?a ?p ?b }", []string{"a", "b"}},
+		{"select distinct ?a $b where { ?a ?p $b }", []string{"a", "b"}},
+		{"SELECT ?a (COUNT(?b) AS ?n) WHERE { ?a ?p ?b } GROUP BY ?a", []string{"a", "n"}},
+		{"SELECT ?a { ?a ?p ?b }", []string{"a"}},
+		{"SELECT * WHERE { ?a ?p ?b }", nil},
+		{"ASK { ?a ?p ?b }", nil},
+	}
+	for _, test := range tests {
+		if got := projectedVariables(test.query); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("projectedVariables(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+// TestPagingModifiers checks that a harvest in pages is refused for a query
+// with its own LIMIT or OFFSET rather than sent as invalid SPARQL.
+func TestPagingModifiers(t *testing.T) {
+	p := newTestPipeline(t, DefaultEndpoint)
+	p.Query = DefaultQuery + "LIMIT 5\n"
+	p.PageSize = 100
+	if err := p.init(); err == nil {
+		t.Errorf("got a harvest in pages of a query with a LIMIT, want an error")
+	}
+}
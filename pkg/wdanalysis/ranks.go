@@ -0,0 +1,45 @@
+package wdanalysis
+
+import (
+	"strings"
+)
+
+// Editors deprecate a signature statement rather than remove it when the
+// signature is known to be wrong, so that it is not added again. The rank
+// of every signature statement is harvested and deprecated statements are
+// skipped unless they are asked for, when each is reported so that it is
+// not mistaken for a trusted signature.
+
+// Statement ranks.
+const (
+	RankPreferred  = "preferred"
+	RankNormal     = "normal"
+	RankDeprecated = "deprecated"
+)
+
+// rankPrefix is the prefix of the IRIs WDQS uses for ranks, e.g.
+// http://wikiba.se/ontology#DeprecatedRank.
+const rankPrefix = "http://wikiba.se/ontology#"
+
+// rankField is the variable of the rank of a signature statement.
+const rankField = "rank"
+
+// statementRank returns the rank of the signature statement in a row of
+// the results. Rows of queries that do not return a rank are normal.
+func statementRank(wdRecord binding) string {
+	rank := wdRecord.Rank()
+	if rank == "" {
+		return RankNormal
+	}
+	rank = strings.TrimSuffix(strings.TrimPrefix(rank, rankPrefix), "Rank")
+	return strings.ToLower(rank)
+}
+
+// hasSignature returns true if a row of the results has a signature to be
+// harvested.
+func (p *Pipeline) hasSignature(wdRecord binding) bool {
+	if wdRecord.Signature() == "" || wdRecord.SomeValue() {
+		return false
+	}
+	return p.Deprecated || statementRank(wdRecord) != RankDeprecated
+}
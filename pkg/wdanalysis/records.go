@@ -0,0 +1,250 @@
+package wdanalysis
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// p:P31 is an instance of a file format.
+
+// DefaultQuery is the template of the harvest query. Custom queries can be
+// based on it and must return the variables in RequiredVariables.
+const DefaultQuery = `
+	SELECT DISTINCT ?format ?formatLabel ?puid ?ldd ?extension ?mimetype ?version ?sig ?sigProperty ?reference ?referenceLabel ?date ?encodingLabel ?offset ?relativity ?relativityLabel ?partLabel ?modified ?sigSnak ?statement ?rank WHERE
+	{
+	  ?format wdt:<<instanceOf>>/wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	  OPTIONAL { ?format wdt:<<puid>> ?puid. }
+	  OPTIONAL { ?format wdt:<<loc>> ?ldd }
+	  OPTIONAL { ?format wdt:<<extension>> ?extension }
+	  OPTIONAL { ?format wdt:<<mimetype>> ?mimetype }
+	  OPTIONAL { ?format wdt:<<version>> ?version }
+	  OPTIONAL { ?format schema:dateModified ?modified }
+	  OPTIONAL { ?format a wdno:<<signature>>. BIND("novalue" AS ?sigSnak) }
+	  OPTIONAL {
+	     VALUES (?sigProperty ?sigDirect ?sigStatement ?sigValue) { <<signatureValues>> }
+	     ?format ?sigStatement ?statement.
+	     ?statement ?sigValue ?sig;
+	        wikibase:rank ?rank.
+	     OPTIONAL {
+	        ?statement prov:wasDerivedFrom ?provenance.
+	        ?provenance pr:<<reference>> ?reference;
+	           pr:<<date>> ?date.
+	     }
+	     OPTIONAL {
+	        ?statement pq:<<encoding>> ?encoding.
+	        ?statement pq:<<offset>> ?offset.
+	     }
+	     OPTIONAL {
+	        ?statement pq:<<relativity>> ?relativity.
+	     }
+	     OPTIONAL {
+	        ?statement pq:<<part>> ?part.
+	     }
+	  }
+	  SERVICE wikibase:label { bd:serviceParam wikibase:language "[AUTO_LANGUAGE], en". }
+	}
+	order by ?format
+`
+
+const formatField = "format"
+const puidField = "puid"
+const locField = "ldd"
+const extField = "extension"
+const mimeField = "mimetype"
+const versionField = "version"
+const modifiedField = "modified"
+const snakField = "sigSnak"
+
+func getID(wikidataURI string) string {
+	splitURI := strings.Split(wikidataURI, "/")
+	return splitURI[len(splitURI)-1]
+}
+
+func (p *Pipeline) newSignature(wdRecord binding) Signature {
+	tmpWD := Signature{}
+	tmpWD.Signature = wdRecord.Signature()
+	tmpWD.Property = wdRecord.Property()
+	tmpWD.Provenance = wdRecord.ReferenceLabel()
+	tmpWD.Date = wdRecord.Date()
+	tmpWD.Encoding = wdRecord.Encoding()
+	tmpWD.Relativity = wdRecord.RelativityLabel()
+	tmpWD.RelativityURI = wdRecord.Relativity()
+	tmpWD.Offset = wdRecord.OffsetRaw()
+	tmpWD.Scope = wdRecord.Scope()
+	tmpWD.Rank = statementRank(wdRecord)
+	tmpWD.Statement = statementID(wdRecord.Statement())
+	tmpWD.Citation = p.newCitation(wdRecord)
+	tmpWD.rel = parseRelativity(p.Config.Entities, tmpWD.RelativityURI, tmpWD.Relativity)
+	tmpWD.Normalized, tmpWD.conversionErr = p.normalizeSignature(tmpWD.Signature, tmpWD.Encoding)
+	tmpWD.Key = tmpWD.key()
+	return tmpWD
+}
+
+// Create a newRecord with fields from the query sent to Wikidata.
+//
+//		"format"	<-- Wikidata URI.
+//		"formatLabel"	<-- Format name.
+//		"puid"	<-- PUID returned by Wikidata.
+//		"extension"	<-- Format extension.
+//		"mimetype"	<-- MimeType as recorded by Wikidata.
+//		"version"	<-- Version of the format.
+//		"modified"	<-- Date the Wikidata item was last edited.
+//
+//		TODO: Let's begin with a count of Wikidata signatures
+//			  A format might have multiple signatures that can be used to
+//			  match a record. Signatures might have multiple forms, e.g. Hex,
+//			  or PRONOM regular expression.
+//
+//		"sig"	<-- Signature in Wikidata.
+//		"sigProperty"	<-- Property that supplied the signature, e.g. P4152.
+//		"reference"	<-- Item the signature was sourced from.
+//		"referenceLabel"	<-- Signature provenance.
+//		"date"	<-- Date the signature was submitted.
+//		"encodingLabel"	<-- Encoding used for a Signature.
+//		"offset"	<-- Offset relative to a position in a file.
+//		"relativity"	<-- Entity describing the relativity of an offset.
+//		"relativityLabel" 	<-- Direction from which to measure an offset for a signature.
+//		"partLabel"	<-- Part of the format a signature is scoped to.
+//		"statement"	<-- Signature statement.
+//		"rank"	<-- Rank of the signature statement.
+//		"sigSnak"	<-- novalue if Wikidata asserts there is no signature.
+//
+func (p *Pipeline) newRecord(wdRecord binding) Wikidata {
+	wd := Wikidata{}
+
+	wd.ID = p.id(wdRecord.URI())
+	wd.Name = wdRecord.Label()
+	wd.URI = wdRecord.URI()
+	wd.Modified = wdRecord.Modified()
+	wd.SignatureSnak = signatureSnak(wdRecord)
+
+	wd.PRONOM = append(wd.PRONOM, wdRecord.PUID())
+	wd.LOC = append(wd.LOC, wdRecord.LDD())
+	addExtension(&wd, wdRecord.Extension())
+	wd.Mimetype = append(wd.Mimetype, wdRecord.Mimetype())
+	wd.Version = append(wd.Version, wdRecord.Version())
+
+	if p.hasSignature(wdRecord) {
+		wd.Signatures = append(wd.Signatures, p.newSignature(wdRecord))
+	}
+
+	return wd
+}
+
+func contains(items []string, item string) bool {
+	for i := range items {
+		if items[i] == item {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmpty returns the values in a slice that are not empty strings. Records
+// are created with empty values where a SPARQL field was not bound.
+func nonEmpty(items []string) []string {
+	values := []string{}
+	for _, item := range items {
+		if item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+func (p *Pipeline) updateSignatures(wd *Wikidata, wdRecord binding) {
+	// The rows of a statement differ only where it has more than one
+	// reference or qualifier. Without its statement a sequence can only be
+	// told apart by its value.
+	statement := statementID(wdRecord.Statement())
+	found := false
+	for _, s := range wd.Signatures {
+		if statement != "" && s.Statement == statement {
+			found = true
+		}
+		if statement == "" && s.Signature == wdRecord.Signature() {
+			found = true
+		}
+	}
+	if found == false {
+		wd.Signatures = append(wd.Signatures, p.newSignature(wdRecord))
+	}
+}
+
+// A format record has some repeating properties. updateRecord manages those
+// exceptions and adds them to the list if it doesn't already exist.
+func (p *Pipeline) updateRecord(wdRecord binding, wd Wikidata) Wikidata {
+	if contains(wd.PRONOM, wdRecord.PUID()) == false {
+		wd.PRONOM = append(wd.PRONOM, wdRecord.PUID())
+	}
+	if contains(wd.LOC, wdRecord.LDD()) == false {
+		wd.LOC = append(wd.LOC, wdRecord.LDD())
+	}
+	addExtension(&wd, wdRecord.Extension())
+	if contains(wd.Mimetype, wdRecord.Mimetype()) == false {
+		wd.Mimetype = append(wd.Mimetype, wdRecord.Mimetype())
+	}
+	if contains(wd.Version, wdRecord.Version()) == false {
+		wd.Version = append(wd.Version, wdRecord.Version())
+	}
+	if wd.SignatureSnak == "" {
+		wd.SignatureSnak = signatureSnak(wdRecord)
+	}
+	if p.hasSignature(wdRecord) {
+		p.updateSignatures(&wd, wdRecord)
+	}
+	return wd
+}
+
+func (p *Pipeline) analyseWikidataRecords() {
+	summary := &p.summary
+	for id, wd := range p.records {
+		start := time.Now()
+		if len(wd.Signatures) > 1 {
+			summary.MultipleSequences++
+			summary.Multiples = append(summary.Multiples, wd.URI)
+			if !fromStatements(wd.Signatures) {
+				summary.lint(wd.URI, heuWDE01)
+			}
+		}
+		analyseCoverage(summary, wd)
+		for _, signature := range wd.Signatures {
+			signature.analyseSignature(summary, wd.URI)
+		}
+		summary.lintRecord(wd)
+		lintLength(summary, wd, p.MaxLength)
+		lintExtensions(summary, wd)
+		if len(wd.Signatures) != 0 {
+			summary.FormatsWithSignatures++
+		}
+		p.cost(id, 0, time.Since(start))
+	}
+}
+
+// condense creates a single record per Wikidata item from the rows of the
+// SPARQL results.
+func (p *Pipeline) condense(results []map[string]spargo.Item) {
+	for _, wdRecord := range results {
+		p.streamRow(p.id(wdRecord[formatField].Value))
+		p.condenseRow(wdRecord)
+	}
+	p.streamFlush()
+}
+
+// condenseRow adds a single row of the SPARQL results to the record of its
+// Wikidata item.
+func (p *Pipeline) condenseRow(wdRecord binding) {
+	start := time.Now()
+	p.summary.unbound(wdRecord)
+	id := p.id(wdRecord.URI())
+	if p.records[id].ID == "" {
+		p.records[id] = p.newRecord(wdRecord)
+		p.progress.add(1, 1)
+	} else {
+		p.records[id] = p.updateRecord(wdRecord, p.records[id])
+		p.progress.add(1, 0)
+	}
+	p.cost(id, 1, time.Since(start))
+}
@@ -0,0 +1,105 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// benchmarkFormats is the number of formats in the synthetic results the
+// benchmarks process, roughly the size of a full harvest.
+const benchmarkFormats = 20000
+
+// syntheticRows are the rows harvested for each synthetic format: a
+// signature without findings, one missing its provenance, date and
+// relativity, and one whose encoding label contradicts its value.
+var syntheticRows = []map[string]string{
+	{"sig": "89504E470D0A1A0A", "sigProperty": "P4152", "referenceLabel": "PRONOM", "date": "2019-10-01T00:00:00Z", "encodingLabel": "hexadecimal", "offset": "0", "relativityLabel": "beginning of file"},
+	{"sig": "FFD9", "sigProperty": "P4152", "encodingLabel": "hexadecimal", "offset": "0"},
+	{"sig": "GIF89a", "sigProperty": "P4152", "referenceLabel": "PRONOM", "date": "2019-10-01T00:00:00Z", "encodingLabel": "hexadecimal", "offset": "0"},
+}
+
+// syntheticResults returns the rows of formats different formats, in the
+// order they are harvested.
+func syntheticResults(formats int) []map[string]spargo.Item {
+	var results []map[string]spargo.Item
+	for i := 0; i < formats; i++ {
+		for _, row := range syntheticRows {
+			result := map[string]spargo.Item{
+				"format":      {Type: "uri", Value: fmt.Sprintf("http://www.wikidata.org/entity/Q%d", 100000+i)},
+				"formatLabel": {Type: "literal", Value: fmt.Sprintf("Format %d", i)},
+				"puid":        {Type: "literal", Value: fmt.Sprintf("fmt/%d", i)},
+				"mimetype":    {Type: "literal", Value: "application/octet-stream"},
+			}
+			for field, value := range row {
+				result[field] = spargo.Item{Type: "literal", Value: value}
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// TestSyntheticResults checks that the synthetic results condense into a
+// record per format, each listed once however many of its signatures have
+// a finding.
+func TestSyntheticResults(t *testing.T) {
+	p := &Pipeline{Cache: filepath.Join(t.TempDir(), "results.json")}
+	if err := p.Process(syntheticResults(3)); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(p.Records()); got != 3 {
+		t.Errorf("got %d records, want 3", got)
+	}
+	summary := p.Summary()
+	lists := []struct {
+		name string
+		list []string
+	}{
+		{"multiple sequences", summary.Multiples},
+		{"no provenance", summary.NoProvenance},
+		{"no date", summary.NoDate},
+		{"no relativity", summary.NoRelativity},
+		{"encoding mismatch", summary.EncodingMismatch},
+	}
+	for _, list := range lists {
+		if len(list.list) != 3 {
+			t.Errorf("got %d records with %s, want 3", len(list.list), list.name)
+		}
+	}
+}
+
+// BenchmarkCondense measures condensing the rows of a full harvest into
+// records.
+func BenchmarkCondense(b *testing.B) {
+	results := syntheticResults(benchmarkFormats)
+	dir := b.TempDir()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		p := &Pipeline{Cache: filepath.Join(dir, "results.json")}
+		if err := p.init(); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		p.condense(results)
+	}
+}
+
+// BenchmarkProcess measures condensing and analysing the rows of a full
+// harvest.
+func BenchmarkProcess(b *testing.B) {
+	results := syntheticResults(benchmarkFormats)
+	dir := b.TempDir()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		p := &Pipeline{Cache: filepath.Join(dir, "results.json")}
+		b.StartTimer()
+		if err := p.Process(results); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
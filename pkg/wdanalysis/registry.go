@@ -0,0 +1,167 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preservation systems such as Archivematica and Preservica keep a format
+// policy registry describing each format they know about. The registry
+// export is shaped so that those systems can import Wikidata's formats
+// through their existing mechanisms: one row per format with its
+// identifiers, its signatures, and notes on the risks of relying on them.
+
+// registryHeader lists the columns of the TSV export.
+var registryHeader = []string{
+	"id",
+	"name",
+	"version",
+	"uri",
+	"puid",
+	"loc",
+	"extension",
+	"mimetype",
+	"signatures",
+	"citations",
+	"grade",
+	"risks",
+}
+
+// RegistrySignature describes a single sequence of a format in the
+// registry export.
+type RegistrySignature struct {
+	Key        string   `json:"key"`
+	Sequence   string   `json:"sequence"`
+	Relativity string   `json:"relativity"`
+	Offset     string   `json:"offset"`
+	Citation   Citation `json:"citation"`
+}
+
+// RegistryRecord describes a single format in the registry export.
+type RegistryRecord struct {
+	ID         string              `json:"id"`
+	Name       string              `json:"name"`
+	Version    []string            `json:"version"`
+	URI        string              `json:"uri"`
+	PUID       []string            `json:"puid"`
+	LOC        []string            `json:"loc"`
+	Extension  []string            `json:"extension"`
+	MIMEType   []string            `json:"mimetype"`
+	Signatures []RegistrySignature `json:"signatures"`
+	Grade      string              `json:"grade"`
+	Risks      []string            `json:"risks"`
+}
+
+// registryRisks returns notes on the risks of relying on the signatures of
+// a record, based on what we have found wrong with it.
+func registryRisks(summary Summary, wd Wikidata) []string {
+	risks := []string{}
+	switch {
+	case wd.SignatureSnak == NoValue:
+		risks = append(risks, "Wikidata states the format has no signature")
+	case len(wd.Signatures) == 0:
+		risks = append(risks, "format has no signature and cannot be identified by its content")
+	}
+	var codes []string
+	for code := range summary.Lints[wd.URI] {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		risks = append(risks, fmt.Sprintf("%s: %s", code, lintDescriptions[linting(code)]))
+	}
+	return risks
+}
+
+// registryRecords returns the records in the shape of the registry export.
+func registryRecords(summary Summary, records []Wikidata) []RegistryRecord {
+	registry := []RegistryRecord{}
+	for _, wd := range records {
+		record := RegistryRecord{
+			ID:         wd.ID,
+			Name:       wd.Name,
+			Version:    nonEmpty(wd.Version),
+			URI:        wd.URI,
+			PUID:       nonEmpty(wd.PRONOM),
+			LOC:        nonEmpty(wd.LOC),
+			Extension:  nonEmpty(wd.Extension),
+			MIMEType:   nonEmpty(wd.Mimetype),
+			Signatures: []RegistrySignature{},
+			Grade:      wd.Grade,
+			Risks:      registryRisks(summary, wd),
+		}
+		for _, signature := range wd.Signatures {
+			if signature.Normalized == "" {
+				continue
+			}
+			record.Signatures = append(record.Signatures, RegistrySignature{
+				Key:        signature.Key,
+				Sequence:   signature.Normalized,
+				Relativity: signature.relativity().String(),
+				Offset:     signature.Offset,
+				Citation:   signature.Citation,
+			})
+		}
+		registry = append(registry, record)
+	}
+	return registry
+}
+
+// registryTSV returns the registry as a TSV document. Sequences are given
+// in the form relativity:offset:sequence.
+func registryTSV(registry []RegistryRecord) string {
+	var rows []string
+	rows = append(rows, strings.Join(registryHeader, "\t"))
+	for _, record := range registry {
+		var signatures, citations []string
+		for _, signature := range record.Signatures {
+			signatures = append(signatures, fmt.Sprintf("%s:%s:%s", signature.Relativity, signature.Offset, signature.Sequence))
+			citations = append(citations, citationCell(signature.Citation))
+		}
+		row := []string{
+			record.ID,
+			refineCell(record.Name),
+			refineJoin(record.Version),
+			record.URI,
+			refineJoin(record.PUID),
+			refineJoin(record.LOC),
+			refineJoin(record.Extension),
+			refineJoin(record.MIMEType),
+			strings.Join(signatures, refineSeparator),
+			strings.Join(citations, refineSeparator),
+			record.Grade,
+			refineJoin(record.Risks),
+		}
+		rows = append(rows, strings.Join(row, "\t"))
+	}
+	return strings.Join(rows, "\n") + "\n"
+}
+
+// RegistryExporter writes the corpus for import into a preservation
+// system's format policy registry to Path. A JSON document is written if
+// the path has a .json extension, otherwise TSV is written.
+type RegistryExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e RegistryExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	registry := registryRecords(p.Summary(), records)
+	out := []byte(registryTSV(registry))
+	if !isText(e.Path) {
+		out, err = json.MarshalIndent(registry, "", "  ")
+		if err != nil {
+			return err
+		}
+	}
+	if err := p.writeExport(e.Path, out); err != nil {
+		return fmt.Errorf("cannot write registry export: %s", err)
+	}
+	return nil
+}
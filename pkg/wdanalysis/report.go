@@ -0,0 +1,196 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problems in Wikidata are fixed by its community, who triage them on
+// WikiProject pages rather than by running this tool. The report summarises
+// a run for them: the counts, the lint codes found, the records with the
+// worst findings and the records whose sequences can only be grouped into
+// signatures heuristically, each linked to its item. It is written as
+// wikitext to paste onto a wiki, or as Markdown for issue trackers and
+// chat if the path has a .md extension.
+
+// reportOffenders is the number of records listed with the worst findings.
+const reportOffenders = 20
+
+// reportSyntax writes the elements of a report in a markup language.
+type reportSyntax struct {
+	heading func(text string) string
+	link    func(wd Wikidata) string
+	table   func(header []string, rows [][]string) string
+	item    func(text string) string
+}
+
+// wikitextSyntax writes a report as wikitext. Items are linked by QID so
+// the links work when pasted onto Wikidata.
+var wikitextSyntax = reportSyntax{
+	heading: func(text string) string {
+		return fmt.Sprintf("== %s ==\n", text)
+	},
+	link: func(wd Wikidata) string {
+		return fmt.Sprintf("[[%s|%s]]", wd.ID, strings.Replace(wd.Name, "|", "&#124;", -1))
+	},
+	table: func(header []string, rows [][]string) string {
+		var out strings.Builder
+		out.WriteString("{| class=\"wikitable sortable\"\n")
+		fmt.Fprintf(&out, "! %s\n", strings.Join(header, " !! "))
+		for _, row := range rows {
+			fmt.Fprintf(&out, "|-\n| %s\n", strings.Join(row, " || "))
+		}
+		out.WriteString("|}\n")
+		return out.String()
+	},
+	item: func(text string) string {
+		return fmt.Sprintf("* %s\n", text)
+	},
+}
+
+// markdownEscaper escapes the characters that would break a Markdown link
+// or table cell.
+var markdownEscaper = strings.NewReplacer("|", `\|`, "[", `\[`, "]", `\]`)
+
+// markdownSyntax writes a report as Markdown. Items are linked by URI.
+var markdownSyntax = reportSyntax{
+	heading: func(text string) string {
+		return fmt.Sprintf("## %s\n\n", text)
+	},
+	link: func(wd Wikidata) string {
+		return fmt.Sprintf("[%s](%s) (%s)", markdownEscaper.Replace(wd.Name), wd.URI, wd.ID)
+	},
+	table: func(header []string, rows [][]string) string {
+		var out strings.Builder
+		fmt.Fprintf(&out, "| %s |\n", strings.Join(header, " | "))
+		fmt.Fprintf(&out, "|%s\n", strings.Repeat(" --- |", len(header)))
+		for _, row := range rows {
+			fmt.Fprintf(&out, "| %s |\n", strings.Join(row, " | "))
+		}
+		return out.String()
+	},
+	item: func(text string) string {
+		return fmt.Sprintf("- %s\n", text)
+	},
+}
+
+// reportLink returns a link to the record with a URI, or the URI if the
+// record was not harvested.
+func reportLink(syntax reportSyntax, byURI map[string]Wikidata, uri string) string {
+	wd, ok := byURI[uri]
+	if !ok {
+		return uri
+	}
+	return syntax.link(wd)
+}
+
+// report returns the summary of the run as a report in a markup language.
+func (p *Pipeline) report(syntax reportSyntax) string {
+	records := p.Records()
+	summary := p.Summary()
+	byURI := make(map[string]Wikidata)
+	for _, wd := range records {
+		byURI[wd.URI] = wd
+	}
+	var out strings.Builder
+
+	out.WriteString(syntax.heading("Summary"))
+	if summary.Manifest != nil {
+		if summary.Manifest.Demo {
+			fmt.Fprintf(&out, "Processed the bundled demo corpus, written at %s. It is simplified from Wikidata and not kept up to date with it, and some of its values are deliberately wrong.\n\n", summary.Manifest.Harvested)
+		} else if summary.Manifest.Source != "" {
+			fmt.Fprintf(&out, "Replayed from %s (SHA-256 %s), written at %s.\n\n", summary.Manifest.Source, summary.Manifest.SourceSHA256, summary.Manifest.Harvested)
+		} else {
+			fmt.Fprintf(&out, "Harvested from %s at %s.\n\n", summary.Manifest.Endpoint, summary.Manifest.Harvested)
+		}
+	}
+	out.WriteString(syntax.table([]string{"", "records"}, [][]string{
+		{"formats", fmt.Sprintf("%d", len(records))},
+		{"formats with signatures", fmt.Sprintf("%d", summary.FormatsWithSignatures)},
+		{"formats missing a signature", fmt.Sprintf("%d", summary.MissingSignature)},
+		{"formats with multiple sequences", fmt.Sprintf("%d", len(summary.Multiples))},
+		{"formats with findings", fmt.Sprintf("%d", len(summary.Lints))},
+	}))
+	out.WriteString("\n")
+	fmt.Fprintf(&out, "Lint findings: %s.\n\n", describe(summary.Severities))
+
+	var codes []string
+	for code := range summary.LintRecords {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	if len(codes) > 0 {
+		out.WriteString(syntax.heading("Lint codes"))
+		var rows [][]string
+		for _, code := range codes {
+			c := linting(code)
+			rows = append(rows, []string{
+				code,
+				lintDescriptions[c],
+				string(lintSeverities[c]),
+				string(lintCategories[c]),
+				fmt.Sprintf("%d", summary.LintRecords[c]),
+			})
+		}
+		out.WriteString(syntax.table([]string{"code", "description", "severity", "category", "records"}, rows))
+		out.WriteString("\n")
+	}
+
+	worst := offenders(summary.Lints)
+	if len(worst) > 0 {
+		if len(worst) > reportOffenders {
+			worst = worst[:reportOffenders]
+		}
+		out.WriteString(syntax.heading("Records with the worst findings"))
+		var rows [][]string
+		for _, record := range worst {
+			row := []string{reportLink(syntax, byURI, record.URI)}
+			for _, severity := range severities {
+				row = append(row, fmt.Sprintf("%d", record.Severities[severity]))
+			}
+			rows = append(rows, row)
+		}
+		header := []string{"format"}
+		for _, severity := range severities {
+			header = append(header, string(severity))
+		}
+		out.WriteString(syntax.table(header, rows))
+		out.WriteString("\n")
+	}
+
+	var heuristic []string
+	for uri, codes := range summary.Lints {
+		if codes[heuWDE01] > 0 {
+			heuristic = append(heuristic, uri)
+		}
+	}
+	sort.Strings(heuristic)
+	if len(heuristic) > 0 {
+		out.WriteString(syntax.heading("Records grouped into signatures heuristically"))
+		fmt.Fprintf(&out, "These records have multiple sequences that cannot be grouped into signatures without a heuristic (%s).\n\n", heuWDE01)
+		for _, uri := range heuristic {
+			out.WriteString(syntax.item(reportLink(syntax, byURI, uri)))
+		}
+	}
+	return out.String()
+}
+
+// ReportExporter writes a summary report of the run to Path as wikitext,
+// or as Markdown if Path has a .md extension.
+type ReportExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e ReportExporter) Export(p *Pipeline) error {
+	syntax := wikitextSyntax
+	if strings.ToLower(filepath.Ext(e.Path)) == ".md" {
+		syntax = markdownSyntax
+	}
+	if err := p.writeExport(e.Path, []byte(p.report(syntax))); err != nil {
+		return fmt.Errorf("cannot write report: %s", err)
+	}
+	return nil
+}
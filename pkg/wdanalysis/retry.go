@@ -0,0 +1,113 @@
+package wdanalysis
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// WDQS intermittently responds with 429 Too Many Requests or 503 Service
+// Unavailable. Requests that fail in this way, or that fail to connect, are
+// retried with exponential backoff so that a long harvest does not die on a
+// transient failure. A Retry-After header from the server is honoured. A
+// server asking for longer than the maximum backoff is not retried early,
+// which would only be refused again, so the request fails with its
+// response instead.
+
+// Default retry settings.
+const (
+	defaultRetries    = 3
+	defaultBackoff    = "2s"
+	defaultMaxBackoff = "1m"
+)
+
+// retryable returns true if a response status is worth retrying.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by a Retry-After header, which may
+// be a number of seconds or a date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryTransport retries requests that fail transiently.
+type retryTransport struct {
+	next       http.RoundTripper
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	jitter     bool
+	count      *int32 // Retries made, shared with the pipeline.
+}
+
+// delay returns the time to wait before a retry, or false if the server
+// asks for longer than the maximum backoff.
+func (t retryTransport) delay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if delay, ok := retryAfter(resp, time.Now()); ok {
+			return delay, delay <= t.maxBackoff
+		}
+	}
+	delay := t.backoff << uint(attempt)
+	if delay > t.maxBackoff || delay <= 0 {
+		delay = t.maxBackoff
+	}
+	if t.jitter {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	return delay, true
+}
+
+// RoundTrip satisfies the http.RoundTripper interface. Requests with a body
+// are not retried as the body cannot be sent again.
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.retries || req.Body != nil {
+			return resp, err
+		}
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if err != nil && req.Context().Err() != nil {
+			return resp, err
+		}
+		delay, ok := t.delay(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		atomic.AddInt32(t.count, 1)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
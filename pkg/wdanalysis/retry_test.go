@@ -0,0 +1,118 @@
+package wdanalysis
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRetryAfter checks the delays read from Retry-After headers.
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+	}
+	for _, test := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if test.value != "" {
+			resp.Header.Set("Retry-After", test.value)
+		}
+		delay, ok := retryAfter(resp, now)
+		if delay != test.delay || ok != test.ok {
+			t.Errorf("Retry-After %q: got %s %t, want %s %t", test.value, delay, ok, test.delay, test.ok)
+		}
+	}
+}
+
+// TestRetryDelay checks that delays back off exponentially up to the
+// maximum backoff, that a Retry-After header is honoured, and that a
+// request is not retried when the header asks for longer than the maximum.
+func TestRetryDelay(t *testing.T) {
+	retry := retryTransport{backoff: time.Second, maxBackoff: time.Minute}
+	tests := []struct {
+		attempt    int
+		retryAfter string
+		want       time.Duration
+		ok         bool
+	}{
+		{0, "", time.Second, true},
+		{1, "", 2 * time.Second, true},
+		{5, "", 32 * time.Second, true},
+		{6, "", time.Minute, true},
+		{70, "", time.Minute, true},
+		{0, "10", 10 * time.Second, true},
+		{5, "10", 10 * time.Second, true},
+		{0, "60", time.Minute, true},
+		{0, "3600", time.Hour, false},
+		{0, time.Now().Add(24 * time.Hour).Format(http.TimeFormat), 0, false},
+	}
+	for _, test := range tests {
+		var resp *http.Response
+		if test.retryAfter != "" {
+			resp = &http.Response{Header: http.Header{"Retry-After": {test.retryAfter}}}
+		}
+		got, ok := retry.delay(test.attempt, resp)
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("attempt %d with Retry-After %q: got %s %t, want %s %t", test.attempt, test.retryAfter, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+// TestRetryTransport checks which responses are retried and how often.
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		requests   int32
+		status     int
+	}{
+		{"success", []int{http.StatusOK}, "0", 1, http.StatusOK},
+		{"not retryable", []int{http.StatusNotFound}, "0", 1, http.StatusNotFound},
+		{"too many requests", []int{http.StatusTooManyRequests, http.StatusOK}, "0", 2, http.StatusOK},
+		{"unavailable", []int{http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusOK}, "0", 3, http.StatusOK},
+		{"exhausted", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}, "0", 4, http.StatusBadGateway},
+		{"retry after too long", []int{http.StatusTooManyRequests, http.StatusOK}, "3600", 1, http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		var requests int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&requests, 1)
+			w.Header().Set("Retry-After", test.retryAfter)
+			w.WriteHeader(test.statuses[n-1])
+		}))
+		var retries int32
+		client := &http.Client{Transport: retryTransport{
+			next:       http.DefaultTransport,
+			retries:    3,
+			backoff:    time.Millisecond,
+			maxBackoff: time.Millisecond,
+			count:      &retries,
+		}}
+		resp, err := client.Get(srv.URL)
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		resp.Body.Close()
+		if requests != test.requests || resp.StatusCode != test.status {
+			t.Errorf("%s: got %d requests ending %d, want %d ending %d", test.name, requests, resp.StatusCode, test.requests, test.status)
+		}
+		if retries != test.requests-1 {
+			t.Errorf("%s: got %d retries counted, want %d", test.name, retries, test.requests-1)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package wdanalysis
+
+import (
+	"fmt"
+)
+
+// Signatures can be scoped to a part of a format using the applies to part
+// qualifier, e.g. a chunk of a container format, or a specific version.
+// Scoped signatures should not be used for general matching so exports can
+// annotate them, which is the default, or exclude them.
+
+// Scope policies.
+const (
+	ScopeAnnotate = "annotate"
+	ScopeExclude  = "exclude"
+)
+
+// ScopePolicies are the scope policies we support.
+var ScopePolicies = []string{ScopeAnnotate, ScopeExclude}
+
+// applyScopePolicy returns the records to export with the scope policy
+// applied to their signatures. Excluded signatures are recorded in
+// excluded.
+func applyScopePolicy(records []Wikidata, policy string, excluded *Exclusions) ([]Wikidata, error) {
+	switch policy {
+	case ScopeAnnotate, "":
+		return records, nil
+	case ScopeExclude:
+		var scoped []Wikidata
+		for _, wd := range records {
+			var signatures []Signature
+			for _, signature := range wd.Signatures {
+				if signature.Scope != "" {
+					excluded.add(wd, signature, ExcludedScoped)
+					continue
+				}
+				signatures = append(signatures, signature)
+			}
+			wd.Signatures = signatures
+			scoped = append(scoped, wd)
+		}
+		return scoped, nil
+	}
+	return nil, fmt.Errorf("unknown scope policy '%s', expected one of: %v", policy, ScopePolicies)
+}
@@ -0,0 +1,125 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Routine harvests are glanced at rather than read, so each lint code also
+// has a severity and the findings are digested into a few lines: how many
+// of each severity there are and which records have the worst of them.
+// Criticals make a signature unusable or wrong, errors make it ambiguous,
+// and warnings affect only how far it can be trusted.
+
+type lintSeverity string
+
+const (
+	severityCritical lintSeverity = "critical"
+	severityError    lintSeverity = "error"
+	severityWarning  lintSeverity = "warning"
+)
+
+// severities are the lint severities, most severe first.
+var severities = []lintSeverity{severityCritical, severityError, severityWarning}
+
+var lintSeverities = map[linting]lintSeverity{
+	heuWDE02: severityCritical,
+	encWDE02: severityCritical,
+	cnvWDE01: severityCritical,
+	cnvWDE02: severityCritical,
+	cnvWDE03: severityCritical,
+	cnvWDE04: severityCritical,
+	encWDE01: severityError,
+	relWDE01: severityError,
+	relWDE02: severityError,
+	heuWDE01: severityError,
+	lenWDE01: severityError,
+	cnvWDE05: severityError,
+	proWDE01: severityWarning,
+	proWDE02: severityWarning,
+	seqWDE01: severityWarning,
+	extWDE01: severityWarning,
+	rnkWDE01: severityWarning,
+}
+
+// digestOffenders is the number of records listed in a digest.
+const digestOffenders = 5
+
+// offender is a record and the number of its findings of each severity.
+type offender struct {
+	URI        string
+	Severities map[lintSeverity]int
+}
+
+// worse returns true if an offender has more severe findings than another.
+func (o offender) worse(other offender) bool {
+	for _, severity := range severities {
+		if o.Severities[severity] != other.Severities[severity] {
+			return o.Severities[severity] > other.Severities[severity]
+		}
+	}
+	return o.URI < other.URI
+}
+
+// offenders returns the findings of each severity for every record with
+// findings, worst first.
+func offenders(lints map[string]map[linting]int) []offender {
+	var records []offender
+	for uri, codes := range lints {
+		record := offender{URI: uri, Severities: make(map[lintSeverity]int)}
+		for code, count := range codes {
+			record.Severities[lintSeverities[code]] += count
+		}
+		if len(codes) > 0 {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].worse(records[j])
+	})
+	return records
+}
+
+// severityCounts returns the number of findings of each severity.
+func severityCounts(lints map[string]map[linting]int) map[lintSeverity]int {
+	counts := make(map[lintSeverity]int)
+	for _, severity := range severities {
+		counts[severity] = 0
+	}
+	for _, codes := range lints {
+		for code, count := range codes {
+			counts[lintSeverities[code]] += count
+		}
+	}
+	return counts
+}
+
+// describe returns the findings of each severity in a few words, e.g.
+// "1 critical, 2 errors, 0 warnings".
+func describe(counts map[lintSeverity]int) string {
+	var parts []string
+	for _, severity := range severities {
+		name := string(severity)
+		if counts[severity] != 1 && severity != severityCritical {
+			name += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[severity], name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Digest returns a short description of the lint findings, by severity,
+// and the records with the worst findings.
+func (s Summary) Digest() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "lint findings: %s", describe(severityCounts(s.Lints)))
+	worst := offenders(s.Lints)
+	if len(worst) > digestOffenders {
+		worst = worst[:digestOffenders]
+	}
+	for _, record := range worst {
+		fmt.Fprintf(&out, "\n  %s: %s", record.URI, describe(record.Severities))
+	}
+	return out.String()
+}
@@ -0,0 +1,145 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Harvests of very large Wikibase instances can be spread across machines
+// by giving each a shard of the items to harvest. Items are assigned to one
+// of N shards by the number at the end of their concept URI modulo N,
+// which every machine and the endpoint compute the same way without
+// coordinating. The record ID is not used as it depends on an ID scheme the
+// endpoint knows nothing of. SPARQL has no modulo so it is written with
+// FLOOR. Each machine harvests and caches its shard, and the cached results
+// are merged into a single harvest that can be processed with the results
+// as input.
+
+// shardPrefix matches everything before the number at the end of a concept
+// URI, in both Go and SPARQL.
+const shardPrefix = "^.*[^0-9]"
+
+// shardPrefixPattern is shardPrefix compiled.
+var shardPrefixPattern = regexp.MustCompile(shardPrefix)
+
+// Shard is one of Count shards of a harvest, numbered from 1.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses a shard in the form i/N, e.g. 2/4.
+func ParseShard(value string) (Shard, error) {
+	var shard Shard
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return shard, fmt.Errorf("invalid shard '%s', expected i/N", value)
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return shard, fmt.Errorf("invalid shard '%s', expected i/N", value)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return shard, fmt.Errorf("invalid shard '%s', expected i/N", value)
+	}
+	shard = Shard{Index: index, Count: count}
+	return shard, shard.validate()
+}
+
+// String returns the shard in the form i/N.
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// validate returns an error if the shard is not one of its count.
+func (s Shard) validate() error {
+	if s.Count < 1 || s.Index < 1 || s.Index > s.Count {
+		return fmt.Errorf("invalid shard '%s', expected 1 <= i <= N", s)
+	}
+	return nil
+}
+
+// includes returns true if the item with a concept URI belongs to the
+// shard, as the shard's filter would.
+func (s Shard) includes(uri string) bool {
+	number, err := strconv.Atoi(shardPrefixPattern.ReplaceAllString(uri, ""))
+	if err != nil {
+		return false
+	}
+	return number%s.Count == s.Index-1
+}
+
+// filter returns a FILTER restricting a query to the items in the shard.
+func (s Shard) filter() string {
+	number := fmt.Sprintf("xsd:integer(REPLACE(STR(?%s), \"%s\", \"\"))", formatField, shardPrefix)
+	return fmt.Sprintf("FILTER(%s - %d * FLOOR(%s / %d) = %d)", number, s.Count, number, s.Count, s.Index-1)
+}
+
+// filterShard returns the results for the items in the pipeline's shard.
+func (p *Pipeline) filterShard(results []map[string]spargo.Item) []map[string]spargo.Item {
+	filtered := []map[string]spargo.Item{}
+	for _, result := range results {
+		if p.Shard.includes(result[formatField].Value) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// MergeShards merges the cached results of the shards of a harvest into a
+// single harvest. An item with results in more than one shard shows the
+// shards were not harvested with the same count, or one was given twice.
+func MergeShards(paths []string) (spargo.SPARQLResult, error) {
+	var merged spargo.SPARQLResult
+	var vars []interface{}
+	shardOf := make(map[string]string)
+	for _, path := range paths {
+		res, err := readResponse(path)
+		if err != nil {
+			return merged, fmt.Errorf("cannot read shard %s: %s", path, err)
+		}
+		if headVars, ok := res.Head["vars"].([]interface{}); ok {
+			for _, v := range headVars {
+				if !containsVar(vars, v) {
+					vars = append(vars, v)
+				}
+			}
+		}
+		seen := make(map[string]bool)
+		for _, row := range res.Results.Bindings {
+			uri := row[formatField].Value
+			if other, ok := shardOf[uri]; ok && !seen[uri] {
+				return merged, fmt.Errorf("%s has results in both %s and %s, check each shard was harvested once with the same N", uri, other, path)
+			}
+			shardOf[uri] = path
+			seen[uri] = true
+		}
+		merged.Results.Bindings = append(merged.Results.Bindings, res.Results.Bindings...)
+	}
+	merged.Head = map[string]interface{}{"vars": vars}
+	sort.SliceStable(merged.Results.Bindings, func(i, j int) bool {
+		return merged.Results.Bindings[i][formatField].Value < merged.Results.Bindings[j][formatField].Value
+	})
+	human, err := sparqlJSON(merged)
+	if err != nil {
+		return merged, err
+	}
+	merged.Human = human
+	return merged, nil
+}
+
+// containsVar returns true if a variable is in the vars of a head.
+func containsVar(vars []interface{}, v interface{}) bool {
+	for _, existing := range vars {
+		if existing == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package wdanalysis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// TestParseShard checks that shards are read in the form i/N and that a
+// shard must be one of N.
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Shard
+		err   string
+	}{
+		{"2/4", Shard{Index: 2, Count: 4}, ""},
+		{" 1 / 3 ", Shard{Index: 1, Count: 3}, ""},
+		{"1/1", Shard{Index: 1, Count: 1}, ""},
+		{"4/4", Shard{Index: 4, Count: 4}, ""},
+		{"0/4", Shard{}, "expected 1 <= i <= N"},
+		{"5/4", Shard{}, "expected 1 <= i <= N"},
+		{"1/0", Shard{}, "expected 1 <= i <= N"},
+		{"-1/4", Shard{}, "expected 1 <= i <= N"},
+		{"2", Shard{}, "expected i/N"},
+		{"1/2/3", Shard{}, "expected i/N"},
+		{"a/4", Shard{}, "expected i/N"},
+		{"2/b", Shard{}, "expected i/N"},
+		{"", Shard{}, "expected i/N"},
+	}
+	for _, tt := range tests {
+		got, err := ParseShard(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseShard(%q) error = %v, want %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseShard(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseShard(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+		if got.String() != fmt.Sprintf("%d/%d", tt.want.Index, tt.want.Count) {
+			t.Errorf("ParseShard(%q).String() = %s", tt.value, got)
+		}
+	}
+}
+
+// shardFilter reads the FILTER of a shard: the pattern stripping the URI
+// down to its number, N, and the remainder of the number modulo N, written
+// with FLOOR.
+var shardFilter = regexp.MustCompile(`^FILTER\(xsd:integer\(REPLACE\(STR\(\?format\), "([^"]+)", ""\)\) - (\d+) \* FLOOR\(xsd:integer\(REPLACE\(STR\(\?format\), "([^"]+)", ""\)\) / (\d+)\) = (\d+)\)$`)
+
+// filterIncludes evaluates the FILTER of a shard for a concept URI as the
+// endpoint would.
+func filterIncludes(t *testing.T, filter string, uri string) bool {
+	t.Helper()
+	match := shardFilter.FindStringSubmatch(filter)
+	if match == nil {
+		t.Fatalf("cannot read shard filter %s", filter)
+	}
+	if match[1] != match[3] || match[2] != match[4] {
+		t.Fatalf("shard filter %s takes the number of an item in two ways", filter)
+	}
+	// xsd:integer of a string that is not a number is an error, and a
+	// FILTER that errors excludes the row.
+	number, err := strconv.Atoi(regexp.MustCompile(match[1]).ReplaceAllString(uri, ""))
+	if err != nil {
+		return false
+	}
+	count, _ := strconv.Atoi(match[2])
+	remainder, _ := strconv.Atoi(match[5])
+	return float64(number)-float64(count)*math.Floor(float64(number)/float64(count)) == float64(remainder)
+}
+
+// shardURIs are the shapes of concept URI items are sharded by: Wikidata's,
+// and those of private Wikibase instances whose IDs are taken by a prefix
+// or pattern.
+var shardURIs = []string{
+	"http://www.wikidata.org/entity/Q%d",
+	"https://wikibase.example.org/entity/Item:%d",
+	"https://wikibase.example.org/entity/item-%d",
+}
+
+// TestShardIncludes checks that every item falls in exactly one of N
+// shards, and that the shard the pipeline assigns an item to is the shard
+// whose SPARQL filter selects it.
+func TestShardIncludes(t *testing.T) {
+	for count := 1; count <= 7; count++ {
+		t.Run(fmt.Sprintf("N=%d", count), func(t *testing.T) {
+			for _, shape := range shardURIs {
+				for number := 1; number <= 1000; number++ {
+					uri := fmt.Sprintf(shape, number)
+					var shards []int
+					for index := 1; index <= count; index++ {
+						shard := Shard{Index: index, Count: count}
+						included := shard.includes(uri)
+						if filtered := filterIncludes(t, shard.filter(), uri); filtered != included {
+							t.Errorf("shard %s includes %s is %t, its filter %t", shard, uri, included, filtered)
+						}
+						if included {
+							shards = append(shards, index)
+						}
+					}
+					if len(shards) != 1 {
+						t.Errorf("%s is in shards %v of %d, want exactly one", uri, shards, count)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestFilterShard checks that the results kept for a shard do not depend
+// on the scheme IDs are taken from concept URIs with.
+func TestFilterShard(t *testing.T) {
+	var results []map[string]spargo.Item
+	for number := 1; number <= 12; number++ {
+		results = append(results, map[string]spargo.Item{
+			formatField: {Type: "uri", Value: fmt.Sprintf("https://wikibase.example.org/entity/Item:%d", number)},
+		})
+	}
+	for _, ids := range []IDConfig{{}, {Prefix: "https://wikibase.example.org/entity/"}, {Pattern: `/entity/(.+)$`}} {
+		p := &Pipeline{Shard: &Shard{Index: 2, Count: 3}, Cache: filepath.Join(t.TempDir(), "results.json")}
+		p.Config.IDs = ids
+		if err := p.init(); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(p.filterShard(results)); got != 4 {
+			t.Errorf("IDs %+v: got %d results in shard 2/3, want 4", ids, got)
+		}
+	}
+}
+
+// writeShard caches the results of the items numbered in a shard file.
+func writeShard(t *testing.T, dir string, name string, numbers ...int) string {
+	t.Helper()
+	var res spargo.SPARQLResult
+	res.Head = map[string]interface{}{"vars": []string{formatField, "sig"}}
+	for _, number := range numbers {
+		uri := fmt.Sprintf("http://www.wikidata.org/entity/Q%d", number)
+		// Each item has two rows, as items with several signatures do.
+		for _, sig := range []string{"00", "FF"} {
+			res.Results.Bindings = append(res.Results.Bindings, map[string]spargo.Item{
+				formatField: {Type: "uri", Value: uri},
+				"sig":       {Type: "literal", Value: sig},
+			})
+		}
+	}
+	human, err := sparqlJSON(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(human), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestMergeShards checks that shards are merged into a single harvest
+// ordered by item, and that an item harvested in two shards is an error.
+func TestMergeShards(t *testing.T) {
+	dir := t.TempDir()
+	first := writeShard(t, dir, "1.json", 2, 4, 6)
+	second := writeShard(t, dir, "2.json", 1, 3, 5)
+	overlapping := writeShard(t, dir, "3.json", 7, 3)
+
+	merged, err := MergeShards([]string{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(merged.Results.Bindings); got != 12 {
+		t.Errorf("got %d merged results, want 12", got)
+	}
+	for i := 1; i < len(merged.Results.Bindings); i++ {
+		if merged.Results.Bindings[i-1][formatField].Value > merged.Results.Bindings[i][formatField].Value {
+			t.Errorf("merged results are not ordered by format at result %d", i)
+		}
+	}
+	if vars, _ := merged.Head["vars"].([]interface{}); len(vars) != 2 {
+		t.Errorf("got head vars %v, want the 2 vars of the shards", merged.Head["vars"])
+	}
+	if merged.Human == "" {
+		t.Errorf("merged results have no response to cache")
+	}
+
+	tests := []struct {
+		name  string
+		paths []string
+	}{
+		{"same shard twice", []string{first, second, first}},
+		{"overlapping shards", []string{first, second, overlapping}},
+	}
+	for _, tt := range tests {
+		_, err := MergeShards(tt.paths)
+		if err == nil || !strings.Contains(err.Error(), "has results in both") {
+			t.Errorf("%s: got error %v, want results in both shards", tt.name, err)
+		}
+	}
+}
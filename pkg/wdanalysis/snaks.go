@@ -0,0 +1,54 @@
+package wdanalysis
+
+import (
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Wikidata can assert that a format has no signature, a novalue snak, e.g.
+// for formats known to have no magic number, or that it has a signature
+// whose value is unknown, a somevalue snak. Both are different from a
+// signature that has simply not been recorded yet and are reported
+// separately in the coverage statistics.
+
+// Signature snaks.
+const (
+	NoValue   = "novalue"
+	SomeValue = "somevalue"
+)
+
+// genidPrefix is the prefix of the IRIs WDQS uses for somevalue snaks.
+const genidPrefix = "/.well-known/genid/"
+
+// isSomeValue returns true if a value is a somevalue snak rather than a
+// signature.
+func isSomeValue(item spargo.Item) bool {
+	return item.Type == "bnode" || strings.Contains(item.Value, genidPrefix)
+}
+
+// signatureSnak returns the snak asserted about the signature of a format
+// in a row of the results, if any.
+func signatureSnak(wdRecord binding) string {
+	if wdRecord.Snak() == NoValue {
+		return NoValue
+	}
+	if wdRecord.SomeValue() {
+		return SomeValue
+	}
+	return ""
+}
+
+// analyseCoverage adds a record to the coverage statistics of the summary.
+func analyseCoverage(summary *Summary, wd Wikidata) {
+	switch {
+	case len(wd.Signatures) > 0:
+		return
+	case wd.SignatureSnak == NoValue:
+		summary.ConfirmedNoSignature = append(summary.ConfirmedNoSignature, wd.URI)
+	case wd.SignatureSnak == SomeValue:
+		summary.UnknownSignature = append(summary.UnknownSignature, wd.URI)
+	default:
+		summary.MissingSignature++
+	}
+}
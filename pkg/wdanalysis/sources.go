@@ -0,0 +1,245 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A single wrong source can contaminate the signatures of many records. The
+// sources analysis maps each reference to the formats whose signatures it
+// backs so that the reach of each source can be seen. The summary counts
+// the formats per source and the full graph can be exported. A rollup of
+// the grades and lint findings of the formats each source backs can also
+// be exported.
+
+// SourceFormat describes a format whose signatures are backed by a source.
+type SourceFormat struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+// sourceGraph returns the formats backed by each source. Signatures without
+// provenance are not included.
+func sourceGraph(records []Wikidata) map[string][]SourceFormat {
+	graph := make(map[string][]SourceFormat)
+	for _, wd := range records {
+		seen := make(map[string]bool)
+		for _, signature := range wd.Signatures {
+			if signature.Provenance == "" || seen[signature.Provenance] {
+				continue
+			}
+			seen[signature.Provenance] = true
+			graph[signature.Provenance] = append(graph[signature.Provenance], SourceFormat{
+				ID:   wd.ID,
+				Name: wd.Name,
+				URI:  wd.URI,
+			})
+		}
+	}
+	return graph
+}
+
+// analyseSources adds the number of formats backed by each source to the
+// summary.
+func analyseSources(summary *Summary, records []Wikidata) {
+	summary.SourceFormats = make(map[string]int)
+	for source, formats := range sourceGraph(records) {
+		summary.SourceFormats[source] = len(formats)
+	}
+}
+
+// sortedSources returns the sources of a graph in alphabetical order.
+func sortedSources(graph map[string][]SourceFormat) []string {
+	var sources []string
+	for source := range graph {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// sourcesTSV returns the graph as a TSV edge list.
+func sourcesTSV(graph map[string][]SourceFormat) string {
+	var out strings.Builder
+	out.WriteString("source\tid\tname\turi\n")
+	for _, source := range sortedSources(graph) {
+		for _, format := range graph[source] {
+			fmt.Fprintf(&out, "%s\t%s\t%s\t%s\n",
+				refineCell(source),
+				format.ID,
+				refineCell(format.Name),
+				format.URI,
+			)
+		}
+	}
+	return out.String()
+}
+
+// dotQuote returns a string quoted for Graphviz.
+func dotQuote(s string) string {
+	return fmt.Sprintf("\"%s\"", strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s))
+}
+
+// sourcesDOT returns the graph in the Graphviz DOT language.
+func sourcesDOT(graph map[string][]SourceFormat) string {
+	var out strings.Builder
+	out.WriteString("digraph sources {\n\trankdir=LR;\n")
+	for _, source := range sortedSources(graph) {
+		fmt.Fprintf(&out, "\t%s [shape=box];\n", dotQuote(source))
+		for _, format := range graph[source] {
+			fmt.Fprintf(&out, "\t%s -> %s;\n", dotQuote(source), dotQuote(fmt.Sprintf("%s (%s)", format.Name, format.ID)))
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// SourcesExporter writes the graph of sources to the formats they back to
+// Path. A JSON document is written if the path has a .json extension, a
+// Graphviz graph with a .dot extension, otherwise a TSV edge list.
+type SourcesExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e SourcesExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	graph := sourceGraph(records)
+	var out []byte
+	switch strings.ToLower(filepath.Ext(e.Path)) {
+	case ".json":
+		out, err = json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return err
+		}
+	case ".dot":
+		out = []byte(sourcesDOT(graph))
+	default:
+		out = []byte(sourcesTSV(graph))
+	}
+	if err := p.writeExport(e.Path, out); err != nil {
+		return fmt.Errorf("cannot write sources export: %s", err)
+	}
+	return nil
+}
+
+// SourceRollup describes the state of the records whose signatures are
+// backed by a source, so that an institution such as a national archive
+// can see how its contributions fare.
+type SourceRollup struct {
+	Source     string         `json:"source"`
+	Formats    int            `json:"formats"`    // Records backed by the source.
+	Signatures int            `json:"signatures"` // Signatures backed by the source.
+	Grades     map[string]int `json:"grades"`     // Records with each quality grade.
+	Linted     int            `json:"linted"`     // Records with at least one lint finding.
+	LintRate   float64        `json:"lintRate"`   // Proportion of records with a finding.
+	Lints      map[string]int `json:"lints"`      // Records with findings of each lint code.
+}
+
+// sourceRollups returns a rollup for each source in alphabetical order.
+// Findings are made against records rather than signatures, so a record's
+// findings count towards every source backing it.
+func sourceRollups(records []Wikidata, lints map[string]map[linting]int) []SourceRollup {
+	rollups := make(map[string]*SourceRollup)
+	for _, wd := range records {
+		seen := make(map[string]bool)
+		for _, signature := range wd.Signatures {
+			if signature.Provenance == "" {
+				continue
+			}
+			rollup := rollups[signature.Provenance]
+			if rollup == nil {
+				rollup = &SourceRollup{
+					Source: signature.Provenance,
+					Grades: make(map[string]int),
+					Lints:  make(map[string]int),
+				}
+				rollups[signature.Provenance] = rollup
+			}
+			rollup.Signatures++
+			if seen[signature.Provenance] {
+				continue
+			}
+			seen[signature.Provenance] = true
+			rollup.Formats++
+			rollup.Grades[wd.Grade]++
+			linted := false
+			for code, findings := range lints[wd.URI] {
+				if findings > 0 {
+					rollup.Lints[string(code)]++
+					linted = true
+				}
+			}
+			if linted {
+				rollup.Linted++
+			}
+		}
+	}
+	result := []SourceRollup{}
+	for _, rollup := range rollups {
+		rollup.LintRate = float64(rollup.Linted) / float64(rollup.Formats)
+		result = append(result, *rollup)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Source < result[j].Source
+	})
+	return result
+}
+
+// rollupTSV returns the rollups as TSV with a row for each source.
+func rollupTSV(rollups []SourceRollup) string {
+	var out strings.Builder
+	out.WriteString("source\tformats\tsignatures")
+	for _, boundary := range gradeBoundaries {
+		fmt.Fprintf(&out, "\t%s", boundary.grade)
+	}
+	out.WriteString("\tlinted\tlint rate\tlints\n")
+	for _, rollup := range rollups {
+		var lints []string
+		for code, records := range rollup.Lints {
+			lints = append(lints, fmt.Sprintf("%s:%d", code, records))
+		}
+		sort.Strings(lints)
+		fmt.Fprintf(&out, "%s\t%d\t%d", refineCell(rollup.Source), rollup.Formats, rollup.Signatures)
+		for _, boundary := range gradeBoundaries {
+			fmt.Fprintf(&out, "\t%d", rollup.Grades[boundary.grade])
+		}
+		fmt.Fprintf(&out, "\t%d\t%.2f\t%s\n", rollup.Linted, rollup.LintRate, strings.Join(lints, " "))
+	}
+	return out.String()
+}
+
+// RollupExporter writes a rollup of the records backed by each source to
+// Path. A JSON document is written if the path has a .json extension,
+// otherwise TSV is written.
+type RollupExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e RollupExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	rollups := sourceRollups(records, p.Summary().Lints)
+	out := []byte(rollupTSV(rollups))
+	if strings.ToLower(filepath.Ext(e.Path)) == ".json" {
+		report, err := json.MarshalIndent(rollups, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = []byte(fmt.Sprintf("%s\n", report))
+	}
+	if err := p.writeExport(e.Path, out); err != nil {
+		return fmt.Errorf("cannot write rollup export: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package wdanalysis
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Analysts would rather ask ad hoc questions of a dataset in SQL than grep
+// JSON. Unlike a SQLite store, which keeps each record whole so that it
+// can be read back, the SQLite export normalizes the records into tables
+// of formats, their values, their sequences and the findings against them,
+// joined by the ID of the format. The export is written afresh each run.
+
+// sqliteTables creates the tables of the SQLite export.
+var sqliteTables = []string{
+	`CREATE TABLE formats (
+		id TEXT PRIMARY KEY,
+		uri TEXT NOT NULL,
+		name TEXT NOT NULL,
+		grade TEXT NOT NULL,
+		modified TEXT NOT NULL
+	)`,
+	`CREATE TABLE format_values (
+		format_id TEXT NOT NULL REFERENCES formats(id),
+		field TEXT NOT NULL,
+		value TEXT NOT NULL
+	)`,
+	`CREATE TABLE sequences (
+		id INTEGER PRIMARY KEY,
+		format_id TEXT NOT NULL REFERENCES formats(id),
+		key TEXT NOT NULL,
+		statement TEXT NOT NULL,
+		property TEXT NOT NULL,
+		signature TEXT NOT NULL,
+		normalized TEXT NOT NULL,
+		encoding TEXT NOT NULL,
+		offset TEXT NOT NULL,
+		relativity TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		rank TEXT NOT NULL,
+		provenance TEXT NOT NULL,
+		date TEXT NOT NULL
+	)`,
+	`CREATE TABLE lints (
+		format_id TEXT NOT NULL REFERENCES formats(id),
+		code TEXT NOT NULL,
+		description TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		category TEXT NOT NULL,
+		findings INTEGER NOT NULL
+	)`,
+	`CREATE INDEX sequences_format ON sequences (format_id)`,
+	`CREATE INDEX lints_format ON lints (format_id)`,
+}
+
+// formatValues returns the multi-valued fields of a record by the name
+// they are given in the format_values table.
+func formatValues(wd Wikidata) map[string][]string {
+	return map[string][]string{
+		puidField:    nonEmpty(wd.PRONOM),
+		locField:     nonEmpty(wd.LOC),
+		extField:     nonEmpty(wd.Extension),
+		mimeField:    nonEmpty(wd.Mimetype),
+		versionField: nonEmpty(wd.Version),
+	}
+}
+
+// insertRecord inserts a record, its sequences and the findings against it
+// into the tables of the SQLite export.
+func insertRecord(tx *sql.Tx, wd Wikidata, lints map[linting]int) error {
+	if _, err := tx.Exec(`INSERT INTO formats (id, uri, name, grade, modified) VALUES (?, ?, ?, ?, ?)`,
+		wd.ID, wd.URI, wd.Name, wd.Grade, wd.Modified); err != nil {
+		return err
+	}
+	values := formatValues(wd)
+	var fields []string
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		for _, value := range values[field] {
+			if _, err := tx.Exec(`INSERT INTO format_values (format_id, field, value) VALUES (?, ?, ?)`, wd.ID, field, value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, s := range wd.Signatures {
+		if _, err := tx.Exec(`INSERT INTO sequences (format_id, key, statement, property, signature, normalized, encoding, offset, relativity, scope, rank, provenance, date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			wd.ID, s.Key, s.Statement, s.Property, s.Signature, s.Normalized, s.Encoding, s.Offset, s.Relativity, s.Scope, s.Rank, s.Provenance, s.Date); err != nil {
+			return err
+		}
+	}
+	var codes []string
+	for code := range lints {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		c := linting(code)
+		if _, err := tx.Exec(`INSERT INTO lints (format_id, code, description, severity, category, findings) VALUES (?, ?, ?, ?, ?, ?)`,
+			wd.ID, code, lintDescriptions[c], string(lintSeverities[c]), string(lintCategories[c]), lints[c]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLiteExporter writes the records to a normalized SQLite database at
+// Path, replacing any database there.
+type SQLiteExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e SQLiteExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	if err := makeDir(e.Path); err != nil {
+		return fmt.Errorf("cannot write SQLite export: %s", err)
+	}
+	if err := os.Remove(e.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot replace SQLite export: %s", err)
+	}
+	db, err := sql.Open(sqliteDriver, e.Path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		return fmt.Errorf("cannot write SQLite export: %s", err)
+	}
+	defer db.Close()
+	for _, statement := range sqliteTables {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("cannot write SQLite export: %s", err)
+		}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("cannot write SQLite export: %s", err)
+	}
+	lints := p.Summary().Lints
+	for _, wd := range records {
+		if err := insertRecord(tx, wd, lints[wd.URI]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("cannot write %s to SQLite export: %s", wd.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot write SQLite export: %s", err)
+	}
+	return db.Close()
+}
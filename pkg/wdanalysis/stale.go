@@ -0,0 +1,40 @@
+package wdanalysis
+
+import (
+	"time"
+)
+
+// Records that have not been edited for a long time and still lack
+// signatures or signature provenance are good candidates for community
+// editing campaigns. The staleness report lists them.
+
+// needsAttention returns true if a record lacks signatures, or has a
+// signature without provenance. Records asserted to have no signature do not
+// need attention.
+func needsAttention(wd Wikidata) bool {
+	if len(wd.Signatures) == 0 {
+		return wd.SignatureSnak != NoValue
+	}
+	for _, signature := range wd.Signatures {
+		if signature.Provenance == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// analyseStaleness adds the records that have not been modified in the given
+// number of years, and that need attention, to the summary.
+func analyseStaleness(summary *Summary, records []Wikidata, years int, now time.Time) {
+	summary.StaleYears = years
+	cutoff := now.AddDate(-years, 0, 0)
+	for _, wd := range records {
+		modified, err := time.Parse(time.RFC3339, wd.Modified)
+		if err != nil {
+			continue
+		}
+		if modified.Before(cutoff) && needsAttention(wd) {
+			summary.Stale = append(summary.Stale, wd.URI)
+		}
+	}
+}
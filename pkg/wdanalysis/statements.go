@@ -0,0 +1,48 @@
+package wdanalysis
+
+import (
+	"strings"
+)
+
+// Every sequence is the value of its own signature statement, and the
+// qualifiers and references of that statement describe it. Joining them by
+// the statement, rather than by the record, tells us which rows belong to
+// which sequence, so that a record with several sequences no longer has to
+// be grouped by guesswork.
+
+// statementField is the variable of the signature statement.
+const statementField = "statement"
+
+// statementPath is the part of the IRI of a statement before its ID.
+const statementPath = "/statement/"
+
+// statementIRI returns the IRI of the statement with an ID under base,
+// e.g. http://www.wikidata.org/entity/.
+func statementIRI(base string, id string) string {
+	return strings.TrimSuffix(base, "/") + statementPath + strings.Replace(id, "$", "-", 1)
+}
+
+// fromStatements returns true if the statement of every sequence is known.
+func fromStatements(sequences []Signature) bool {
+	for _, sequence := range sequences {
+		if sequence.Statement == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// statementHeuristic treats the sequence of every statement as a signature
+// of its own. Sequences without a statement are paired by relativity.
+func statementHeuristic(sequences []Signature) [][]Signature {
+	var signatures [][]Signature
+	var unknown []Signature
+	for _, sequence := range sequences {
+		if sequence.Statement == "" {
+			unknown = append(unknown, sequence)
+			continue
+		}
+		signatures = append(signatures, []Signature{sequence})
+	}
+	return append(signatures, relativityHeuristic(unknown)...)
+}
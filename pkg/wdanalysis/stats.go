@@ -0,0 +1,61 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+)
+
+// Users can choose to share statistics of their runs with the maintainers
+// to show which heuristics and lint fixes matter most in practice. Nothing
+// is sent unless a URL is given for the statistics. Only aggregate counts
+// and the version of the tool are sent: never the endpoint, the query,
+// paths, or the IDs and contents of records.
+
+// UsageStats are the anonymised statistics of a run.
+type UsageStats struct {
+	Tool       string         `json:"tool"`       // Version of the tool.
+	Records    int            `json:"records"`    // Records processed.
+	Signatures int            `json:"signatures"` // Signatures harvested.
+	Formats    int            `json:"formats"`    // Records with at least one signature.
+	Lints      map[string]int `json:"lints"`      // Records with findings of each lint code.
+	Severities map[string]int `json:"severities"` // Findings of each severity.
+	Grades     map[string]int `json:"grades"`     // Records with each quality grade.
+}
+
+// usageStats returns the anonymised statistics of the run.
+func (p *Pipeline) usageStats() UsageStats {
+	summary := p.Summary()
+	stats := UsageStats{
+		Tool:       p.Tool,
+		Records:    summary.CondensedSparqlResults,
+		Signatures: p.manifest.Signatures,
+		Formats:    summary.FormatsWithSignatures,
+		Lints:      make(map[string]int),
+		Severities: make(map[string]int),
+		Grades:     summary.Grades,
+	}
+	for code, records := range summary.LintRecords {
+		stats.Lints[string(code)] = records
+	}
+	for severity, count := range summary.Severities {
+		stats.Severities[string(severity)] = count
+	}
+	return stats
+}
+
+// shareStats POSTs the anonymised statistics of the run to ShareStats if
+// it is set. Failures are logged and do not affect the result of the run.
+func (p *Pipeline) shareStats() {
+	if p.ShareStats == "" {
+		return
+	}
+	body, err := json.Marshal(p.usageStats())
+	if err != nil {
+		p.Logger.Printf("cannot create usage statistics: %s", err)
+		return
+	}
+	if err := notifyWebhook(p.client, p.ShareStats, body); err != nil {
+		p.Logger.Printf("cannot share usage statistics: %s", err)
+		return
+	}
+	p.Logger.Printf("shared usage statistics with %s", p.ShareStats)
+}
@@ -0,0 +1,141 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A processed corpus can be persisted in a store from which records are
+// read one at a time, so that tools working over a large corpus need not
+// load all of it into memory. A directory holding a JSON file for each
+// record is easy to inspect and to keep in version control. A SQLite
+// database is a single file and is quicker to read a single record from.
+
+// Store persists processed records.
+type Store interface {
+	Put(wd Wikidata) error                 // Put stores a record, replacing any with the same ID.
+	Get(id string) (Wikidata, bool, error) // Get returns the record with an ID and whether it was found.
+	Each(fn func(wd Wikidata) error) error // Each calls fn with every record in order of ID until fn fails.
+	Close() error                          // Close releases the store.
+}
+
+// sqliteExtensions are the extensions of paths opened as SQLite stores.
+var sqliteExtensions = []string{".db", ".sqlite", ".sqlite3"}
+
+// OpenStore opens the store at path, creating it if it does not exist. A
+// path with a SQLite extension, e.g. .db, is opened as a SQLite database,
+// any other as a directory.
+func OpenStore(path string) (Store, error) {
+	if contains(sqliteExtensions, strings.ToLower(filepath.Ext(path))) {
+		return openSQLiteStore(path)
+	}
+	return openFileStore(path)
+}
+
+// fileStore stores each record as a JSON file in a directory.
+type fileStore struct {
+	dir string
+}
+
+// openFileStore opens the directory store at dir.
+func openFileStore(dir string) (*fileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create store: %s", err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+// path returns the path of the file of the record with an ID.
+func (s *fileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Put satisfies the Store interface.
+func (s *fileStore) Put(wd Wikidata) error {
+	if !qidPattern.MatchString(wd.ID) {
+		return fmt.Errorf("cannot store record with invalid ID '%s'", wd.ID)
+	}
+	out, err := json.Marshal(wd)
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path(wd.ID), out)
+}
+
+// Get satisfies the Store interface.
+func (s *fileStore) Get(id string) (Wikidata, bool, error) {
+	var wd Wikidata
+	if !qidPattern.MatchString(id) {
+		return wd, false, nil
+	}
+	data, err := ioutil.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return wd, false, nil
+	}
+	if err != nil {
+		return wd, false, err
+	}
+	if err := json.Unmarshal(data, &wd); err != nil {
+		return wd, false, fmt.Errorf("cannot read record %s: %s", id, err)
+	}
+	return wd, true, nil
+}
+
+// Each satisfies the Store interface.
+func (s *fileStore) Each(fn func(wd Wikidata) error) error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "Q*.json"))
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, path := range paths {
+		if id := strings.TrimSuffix(filepath.Base(path), ".json"); qidPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		wd, _, err := s.Get(id)
+		if err != nil {
+			return err
+		}
+		if err := fn(wd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close satisfies the Store interface.
+func (s *fileStore) Close() error {
+	return nil
+}
+
+// StoreExporter puts every exported record in the store at Path.
+type StoreExporter struct {
+	Path string
+}
+
+// Export satisfies the Exporter interface.
+func (e StoreExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	store, err := OpenStore(e.Path)
+	if err != nil {
+		return err
+	}
+	for _, wd := range records {
+		if err := store.Put(wd); err != nil {
+			store.Close()
+			return fmt.Errorf("cannot store records: %s", err)
+		}
+	}
+	return store.Close()
+}
@@ -0,0 +1,97 @@
+package wdanalysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	// Registers the sqlite driver.
+	_ "modernc.org/sqlite"
+)
+
+// A SQLite store keeps each record as JSON in a single table keyed by ID so
+// that the wire format of a record is the same in either store. The driver
+// is written in pure Go so that SQLite works in binaries built without cgo,
+// which is how every release binary is cross-compiled.
+
+// sqliteDriver is the name the SQLite driver is registered under.
+const sqliteDriver = "sqlite"
+
+// sqliteSchema creates the table of records if it does not exist.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS records (id TEXT PRIMARY KEY, record TEXT NOT NULL)`
+
+// sqliteStore stores records in a SQLite database.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// openSQLiteStore opens the SQLite store at path.
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	if err := makeDir(path); err != nil {
+		return nil, fmt.Errorf("cannot create store: %s", err)
+	}
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open store: %s", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot open store: %s", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// Put satisfies the Store interface.
+func (s *sqliteStore) Put(wd Wikidata) error {
+	out, err := json.Marshal(wd)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO records (id, record) VALUES (?, ?)`, wd.ID, string(out))
+	return err
+}
+
+// Get satisfies the Store interface.
+func (s *sqliteStore) Get(id string) (Wikidata, bool, error) {
+	var wd Wikidata
+	var record string
+	err := s.db.QueryRow(`SELECT record FROM records WHERE id = ?`, id).Scan(&record)
+	if err == sql.ErrNoRows {
+		return wd, false, nil
+	}
+	if err != nil {
+		return wd, false, err
+	}
+	if err := json.Unmarshal([]byte(record), &wd); err != nil {
+		return wd, false, fmt.Errorf("cannot read record %s: %s", id, err)
+	}
+	return wd, true, nil
+}
+
+// Each satisfies the Store interface.
+func (s *sqliteStore) Each(fn func(wd Wikidata) error) error {
+	rows, err := s.db.Query(`SELECT id, record FROM records ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, record string
+		if err := rows.Scan(&id, &record); err != nil {
+			return err
+		}
+		var wd Wikidata
+		if err := json.Unmarshal([]byte(record), &wd); err != nil {
+			return fmt.Errorf("cannot read record %s: %s", id, err)
+		}
+		if err := fn(wd); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Close satisfies the Store interface.
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,218 @@
+package wdanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// The full file format class returns hundreds of thousands of rows. Rather
+// than reading the whole response into memory and condensing it afterwards,
+// rows are decoded one at a time as they arrive and condensed straight
+// away, so only the records they create are held in memory. The response is
+// written to the cache as it is read.
+
+// jsonAccept is the media type of SPARQL JSON results.
+const jsonAccept = "application/sparql-results+json, application/json"
+
+// expectDelim reads the next token from dec and checks it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %s but found %v", delim, token)
+	}
+	return nil
+}
+
+// decodeResults decodes SPARQL JSON results from r calling row with each
+// binding as it is decoded. The results must include the required
+// variables. Results that cannot be decoded return a parseError.
+func decodeResults(r io.Reader, row func(map[string]spargo.Item)) error {
+	dec := json.NewDecoder(r)
+	headed := false
+	decode := func() error {
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			switch key {
+			case "head":
+				var head struct {
+					Vars []string `json:"vars"`
+				}
+				if err := dec.Decode(&head); err != nil {
+					return err
+				}
+				if err := checkVariables(head.Vars); err != nil {
+					return err
+				}
+				headed = true
+			case "results":
+				if err := decodeBindings(dec, row); err != nil {
+					return err
+				}
+			default:
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					return err
+				}
+			}
+		}
+		return expectDelim(dec, '}')
+	}
+	if err := decode(); err != nil {
+		if _, ok := err.(variablesError); ok {
+			return err
+		}
+		return parseError{err: err}
+	}
+	if !headed {
+		return checkVariables(nil)
+	}
+	return nil
+}
+
+// decodeBindings decodes the results object of SPARQL JSON results calling
+// row with each binding.
+func decodeBindings(dec *json.Decoder, row func(map[string]spargo.Item)) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "bindings" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			var binding map[string]spargo.Item
+			if err := dec.Decode(&binding); err != nil {
+				return err
+			}
+			row(binding)
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// ProcessReader condenses SPARQL JSON results as they are read from r and
+// analyses them. Unlike Process the results are never held in memory all
+// at once.
+func (p *Pipeline) ProcessReader(r io.Reader) error {
+	if err := p.init(); err != nil {
+		return err
+	}
+	if p.Progress != nil {
+		p.progress = newProgress(p.Progress, 0)
+	}
+	rows := 0
+	err := decodeResults(r, func(row map[string]spargo.Item) {
+		if len(p.QIDs) > 0 && !contains(p.QIDs, p.id(row[formatField].Value)) {
+			return
+		}
+		if p.Shard != nil && !p.Shard.includes(row[formatField].Value) {
+			return
+		}
+		rows++
+		p.streamRow(p.id(row[formatField].Value))
+		p.condenseRow(row)
+	})
+	p.progress.done()
+	if err != nil {
+		// The rows of the last record may not all have been read.
+		p.streaming = ""
+		return err
+	}
+	p.streamFlush()
+	p.analyse(rows)
+	return nil
+}
+
+// resetHarvest discards everything condensed from results so far, the
+// records, their costs, the unbound variables of the rows, and the record
+// being streamed, so that results can be processed again from the start.
+// Records already streamed cannot be taken back so are not streamed again.
+func (p *Pipeline) resetHarvest() {
+	p.records = make(map[string]Wikidata)
+	p.costs = make(map[string]recordCost)
+	p.summary.Unbound = nil
+	p.streaming = ""
+	p.begun = nil
+}
+
+// streamHarvest runs the harvest query and processes the results as they
+// are received. The response is cached once it has been read completely.
+func (p *Pipeline) streamHarvest(ctx context.Context, query string, harvested time.Time) error {
+	req, err := http.NewRequest(http.MethodGet, p.Endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", p.userAgent())
+	req.Header.Set("Accept", jsonAccept)
+	values := req.URL.Query()
+	values.Add("query", query)
+	req.URL.RawQuery = values.Encode()
+	resp, err := p.contextClient(ctx).Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("query to %s failed: %s", p.Endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("query to %s failed: unexpected response from server: %s", p.Endpoint, resp.Status)
+	}
+	part := p.Cache + ".part"
+	var body io.Reader = resp.Body
+	cache, err := createFile(part)
+	if err != nil {
+		p.Logger.Printf("cannot cache results: %s", err)
+	} else {
+		defer os.Remove(part)
+		defer cache.Close()
+		body = io.TeeReader(resp.Body, cache)
+	}
+	if err := p.ProcessReader(body); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	if cache == nil {
+		return nil
+	}
+	if err := cache.Close(); err != nil {
+		p.Logger.Printf("cannot cache results: %s", err)
+	} else if err := os.Rename(part, p.Cache); err != nil {
+		p.Logger.Printf("cannot cache results: %s", err)
+	} else if err := p.writeCacheEntry(p.summary.AllSparqlResults, harvested); err != nil {
+		p.Logger.Printf("cannot describe cached results: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestHarvestFallbackResets checks that when the streamed response cannot
+// be parsed and the results are requested again, nothing condensed from the
+// first response is counted twice.
+func TestHarvestFallbackResets(t *testing.T) {
+	body := demoBody(t)
+	srv := newTestEndpoint(t, func(n int32) []byte {
+		if n == 1 {
+			return body[:len(body)/2]
+		}
+		return body
+	})
+	p := newTestPipeline(t, srv.URL)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := newTestPipeline(t, srv.URL)
+	if err := want.Process(demoResults().Results.Bindings); err != nil {
+		t.Fatal(err)
+	}
+	got := p.Summary()
+	if got.AllSparqlResults != len(demoRows) {
+		t.Errorf("got %d results, want %d", got.AllSparqlResults, len(demoRows))
+	}
+	if !reflect.DeepEqual(got.Unbound, want.Summary().Unbound) {
+		t.Errorf("got unbound %v, want %v", got.Unbound, want.Summary().Unbound)
+	}
+	if len(p.Records()) != len(want.Records()) {
+		t.Errorf("got %d records, want %d", len(p.Records()), len(want.Records()))
+	}
+}
+
+// TestStreamFallback checks that when the streamed response cannot be
+// parsed part way through a record, the records written as JSON Lines are
+// the same as those of a response that can.
+func TestStreamFallback(t *testing.T) {
+	body := demoBody(t)
+	// Cut the response in the second row of a record so that its rows
+	// have begun but not ended.
+	jpeg := []byte(`entity/Q2195"`)
+	first := bytes.Index(body, jpeg)
+	cut := first + len(jpeg) + bytes.Index(body[first+len(jpeg):], jpeg)
+	srv := newTestEndpoint(t, func(n int32) []byte {
+		if n == 1 {
+			return body[:cut]
+		}
+		return body
+	})
+	var want bytes.Buffer
+	clean := newTestPipeline(t, srv.URL)
+	clean.Stream = &want
+	if err := clean.Process(demoResults().Results.Bindings); err != nil {
+		t.Fatal(err)
+	}
+	var got, logged bytes.Buffer
+	p := newTestPipeline(t, srv.URL)
+	p.Stream = &got
+	p.Logger = log.New(&logged, "", 0)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged.String(), "streaming results failed") {
+		t.Fatalf("the streamed response was parsed, expected it to fail: %s", logged.String())
+	}
+	if strings.Contains(logged.String(), "not together") {
+		t.Errorf("got a warning of rows not together: %s", logged.String())
+	}
+	if got.String() != want.String() {
+		t.Errorf("got streamed records:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+}
+
+// streamedIDs returns the IDs of the records streamed as JSON Lines.
+func streamedIDs(t *testing.T, streamed *bytes.Buffer) []string {
+	t.Helper()
+	var ids []string
+	dec := json.NewDecoder(streamed)
+	for dec.More() {
+		var wd Wikidata
+		if err := dec.Decode(&wd); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, wd.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// TestStreamPUIDs checks that the records streamed from cached results are
+// only those mapped to the pipeline's PUIDs, as in the summary.
+func TestStreamPUIDs(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "demo.json")
+	if err := WriteDemoCorpus(input); err != nil {
+		t.Fatal(err)
+	}
+	var streamed bytes.Buffer
+	p := newTestPipeline(t, DefaultEndpoint)
+	p.Input = input
+	p.PUIDs = []string{"fmt/11", "fmt/4"}
+	p.Stream = &streamed
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, wd := range p.Records() {
+		want = append(want, wd.ID)
+	}
+	if got := streamedIDs(t, &streamed); !reflect.DeepEqual(got, want) || len(got) != 2 {
+		t.Errorf("got streamed records %v, want %v", got, want)
+	}
+}
+
+// TestStreamOrder checks that records are only streamed from a query
+// ordered by format, as records whose rows are not together would be
+// streamed before they are complete.
+func TestStreamOrder(t *testing.T) {
+	tests := []struct {
+		query    string
+		pageSize int
+		ok       bool
+	}{
+		{DefaultQuery, 0, true},
+		{strings.Replace(DefaultQuery, "order by ?format", "ORDER BY ASC(?format) ?statement", 1), 0, true},
+		{strings.Replace(DefaultQuery, "order by ?format", "", 1), 0, false},
+		{strings.Replace(DefaultQuery, "order by ?format", "order by ?formatLabel", 1), 0, false},
+		{strings.Replace(DefaultQuery, "order by ?format", "order by ?formatLabel", 1), 10, false},
+		// Pages are ordered by every variable, ?format first.
+		{strings.Replace(DefaultQuery, "order by ?format", "", 1), 10, true},
+	}
+	body := demoBody(t)
+	srv := newTestEndpoint(t, func(int32) []byte { return body })
+	for _, test := range tests {
+		var streamed bytes.Buffer
+		p := newTestPipeline(t, srv.URL)
+		p.Query = test.query
+		p.PageSize = test.pageSize
+		p.Stream = &streamed
+		err := p.Run(context.Background())
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("query ending %q in pages of %d: got error %v, want streaming %t", test.query[len(test.query)-40:], test.pageSize, err, test.ok)
+		}
+		if !test.ok && streamed.Len() > 0 {
+			t.Errorf("query ending %q: got records streamed from a query that is not ordered by format", test.query[len(test.query)-40:])
+		}
+	}
+}
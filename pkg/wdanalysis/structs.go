@@ -0,0 +1,316 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// Records and the summary are exported as JSON. The names in their json
+// tags are the wire format and are kept stable between releases. Fields are
+// named in lower camel case and fields that are only set when a feature is
+// enabled, e.g. the labels harvested in other languages, are omitted when
+// they are empty.
+
+// Wikidata ... might be commented in Siegfried...
+type Wikidata struct {
+	ID         string      `json:"id"`         // Wikidata short name, e.g. Q12345 can be appended to a URI to be dereferenced.
+	Name       string      `json:"name"`       // Name of the format as described in Wikidata.
+	URI        string      `json:"uri"`        // URI is the absolute URL in Wikidata terms that can be dereferenced.
+	PRONOM     []string    `json:"pronom"`     // 1:1 mapping to PRONOM wherever possible.
+	LOC        []string    `json:"loc"`        // Library of Congress identifiers.
+	Extension  []string    `json:"extension"`  // Extension returned by Wikidata, lowercase without a leading dot.
+	Mimetype   []string    `json:"mimetype"`   // Mimetype as recorded by Wikidata.
+	Version    []string    `json:"version"`    // Version of the format.
+	Signatures []Signature `json:"signatures"` // Signature associated with a record which we will convert to a new Type.
+	Modified   string      `json:"modified"`   // Date the Wikidata item was last edited.
+
+	SignatureSnak string `json:"signatureSnak,omitempty"` // NoValue if Wikidata asserts the format has no signature, SomeValue if it is unknown.
+	Grade         string `json:"grade"`                   // Quality grade of the record from A to E.
+
+	RawExtension []string `json:"rawExtension,omitempty"` // Extensions as they are recorded in Wikidata.
+
+	ExternalLabels map[string]string   `json:"externalLabels,omitempty"` // Authoritative labels for PRONOM and LoC identifiers.
+	Labels         map[string]string   `json:"labels,omitempty"`         // Name of the format in each harvested language.
+	Aliases        map[string][]string `json:"aliases,omitempty"`        // Other names of the format in each harvested language.
+}
+
+// Signature ...
+type Signature struct {
+	Key           string   `json:"key"`           // Stable key of the sequence for joining reports.
+	Signature     string   `json:"signature"`     // Signature byte sequence.
+	Property      string   `json:"property"`      // Property that supplied the signature, e.g. P4152.
+	Provenance    string   `json:"provenance"`    // Provenance of the signature.
+	Date          string   `json:"date"`          // Date the signature was submitted.
+	Encoding      string   `json:"encoding"`      // Signature encoding, e.g. Hexadecimal, ASCII, PRONOM.
+	Relativity    string   `json:"relativity"`    // Position relative to beginning or end of file, or elsewhere.
+	RelativityURI string   `json:"relativityURI"` // Wikidata entity describing Relativity.
+	Offset        string   `json:"offset"`        // Offset of the signature relative to Relativity.
+	Normalized    string   `json:"normalized"`    // Signature normalized to uppercase hexadecimal.
+	Scope         string   `json:"scope"`         // Part of the format the signature applies to, if not all of it.
+	Citation      Citation `json:"citation"`      // Where the signature comes from.
+	Rank          string   `json:"rank"`          // Rank of the signature statement, e.g. normal.
+	Statement     string   `json:"statement"`     // ID of the signature statement, e.g. Q12$5F3E...
+
+	conversionErr error               // Reason the signature could not be normalized.
+	rel           wikidata.Relativity // Relativity resolved against the configured entities.
+}
+
+// Serialize the signature component of our record to a string to debug.
+func (s Signature) String() string {
+	report, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s", report)
+}
+
+// relativity returns the relativity of the signature.
+func (s Signature) relativity() wikidata.Relativity {
+	return s.rel
+}
+
+// parseRelativity returns the relativity of a signature, preferring the
+// entity over its label which may not be in English.
+func parseRelativity(entities wikidata.Entities, uri string, label string) wikidata.Relativity {
+	if rel, err := entities.ParseRelativity(uri); err == nil && rel != wikidata.Unknown {
+		return rel
+	}
+	rel, _ := entities.ParseRelativity(label)
+	return rel
+}
+
+// CSV will serialize the signature component of our record to a csv to debug.
+// Signatures are trimmed to trim characters if trim is greater than zero.
+func (s Signature) CSV(uri string, count int, trim int) string {
+	provenance := s.Provenance
+	date := s.Date
+	encoding := s.Encoding
+	relativity := s.Relativity
+	signature := s.Signature
+	if provenance == "" {
+		provenance = "None"
+	}
+	if date == "" {
+		date = "None"
+	}
+	if encoding == "" {
+		encoding = "None"
+	}
+	if relativity == "" {
+		relativity = "None"
+	}
+	if len(signature) >= trim && trim > 0 {
+		signature = s.Signature[:trim]
+	}
+	return fmt.Sprintf("%s, %d, %s, %s, %s, %s, %s, %s",
+		uri,
+		count,
+		s.Key,
+		signature,
+		provenance,
+		date,
+		encoding,
+		relativity,
+	)
+}
+
+const enc = false
+
+// listRecord adds the URI of a record to a list of records if it is not
+// already listed. Signatures are analysed a record at a time, so a record
+// is listed already if it was the last listed.
+func listRecord(list []string, uri string) []string {
+	if uri == "" || (len(list) > 0 && list[len(list)-1] == uri) {
+		return list
+	}
+	return append(list, uri)
+}
+
+func (s Signature) analyseSignature(summary *Summary, uri string) {
+	summary.SignatureCharacters.count(s.Signature)
+	if summary.SignatureProperties == nil {
+		summary.SignatureProperties = make(map[string]int)
+	}
+	summary.SignatureProperties[s.Property]++
+	mismatch := false
+	if s.Rank == RankDeprecated {
+		summary.lint(uri, rnkWDE01)
+	}
+	if ambiguousWhitespace(s) {
+		summary.lint(uri, cnvWDE05)
+	}
+	if s.Provenance == "" {
+		summary.ErrNoProvenance++
+		summary.lint(uri, proWDE01)
+		summary.NoProvenance = listRecord(summary.NoProvenance, uri)
+	}
+	if s.Date == "" {
+		summary.ErrNoDate++
+		summary.lint(uri, proWDE02)
+		summary.NoDate = listRecord(summary.NoDate, uri)
+	}
+	if s.Encoding == "" {
+		summary.ErrNoEncoding++
+		summary.lint(uri, encWDE01)
+		summary.NoEncoding = listRecord(summary.NoEncoding, uri)
+		if !enc && !contains(summary.EncodingSet, "None") {
+			summary.EncodingSet = append(summary.EncodingSet, "None")
+		}
+	} else {
+		if !contains(summary.EncodingSet, s.Encoding) {
+			summary.EncodingSet = append(summary.EncodingSet, s.Encoding)
+		}
+		if contradictsEncoding(s.Signature, s.Encoding) {
+			mismatch = true
+			summary.ErrEncodingMismatch++
+			summary.lint(uri, encWDE02)
+			summary.EncodingMismatch = listRecord(summary.EncodingMismatch, uri)
+		}
+	}
+	if s.Relativity == "" {
+		summary.lint(uri, relWDE01)
+		summary.NoRelativity = listRecord(summary.NoRelativity, uri)
+	}
+	// A contradictory encoding label is the more specific finding, so we
+	// do not also report it as a generic conversion failure.
+	if s.conversionErr != nil && !mismatch {
+		summary.ErrConversion++
+		summary.lint(uri, conversionLint(s.conversionErr))
+	}
+}
+
+// CharacterStats describes the distribution of characters found in raw
+// signature values across the corpus so that we can understand what the
+// signature converters need to cope with.
+type CharacterStats struct {
+	HexDigits    int      `json:"hexDigits"`    // 0-9, a-f, A-F.
+	OtherLetters int      `json:"otherLetters"` // Letters that are not hexadecimal digits.
+	Spaces       int      `json:"spaces"`       // Spaces, tabs, and line endings.
+	HexPrefixes  int      `json:"hexPrefixes"`  // 0x or 0X prefixes.
+	Brackets     int      `json:"brackets"`     // Brackets of any type, e.g. (, [, {, <.
+	Commas       int      `json:"commas"`       // Commas.
+	Other        int      `json:"other"`        // Everything else.
+	OtherSet     []string `json:"otherSet"`     // Unique characters counted in Other.
+}
+
+// count adds the characters of a signature to our statistics.
+func (c *CharacterStats) count(signature string) {
+	runes := []rune(signature)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '0' && i+1 < len(runes) && (runes[i+1] == 'x' || runes[i+1] == 'X') {
+			c.HexPrefixes++
+			i++
+			continue
+		}
+		switch {
+		case strings.ContainsRune("0123456789abcdefABCDEF", r):
+			c.HexDigits++
+		case unicode.IsLetter(r):
+			c.OtherLetters++
+		case unicode.IsSpace(r):
+			c.Spaces++
+		case strings.ContainsRune("()[]{}<>", r):
+			c.Brackets++
+		case r == ',':
+			c.Commas++
+		default:
+			c.Other++
+			char := fmt.Sprintf("%q", r)
+			if !contains(c.OtherSet, char) {
+				c.OtherSet = append(c.OtherSet, char)
+			}
+		}
+	}
+}
+
+// Summary of the identifier.
+type Summary struct {
+	AllSparqlResults       int `json:"allSparqlResults"`
+	Pages                  int `json:"pages"`   // Pages of results harvested.
+	Retries                int `json:"retries"` // Requests retried after a transient failure.
+	CondensedSparqlResults int `json:"condensedSparqlResults"`
+	FormatsWithSignatures  int `json:"formatsWithSignatures"`
+	MissingSignature       int `json:"missingSignature"` // Formats with no signature and no assertion about it.
+	MultipleSequences      int `json:"multipleSequences"`
+	ErrNoProvenance        int `json:"errNoProvenance"`
+	ErrNoDate              int `json:"errNoDate"`
+	ErrNoRelativity        int `json:"errNoRelativity"` // Records with a signature missing its relativity.
+	ErrNoEncoding          int `json:"errNoEncoding"`
+	ErrEncodingMismatch    int `json:"errEncodingMismatch"` // Encoding label contradicts value.
+	ErrConversion          int `json:"errConversion"`       // Signature could not be normalized.
+
+	// Sets to help understand content.
+	EncodingSet []string `json:"encodingSet"`
+
+	// Statistics to help understand content.
+	SignatureCharacters CharacterStats `json:"signatureCharacters"`
+	SignatureProperties map[string]int `json:"signatureProperties"` // Signatures supplied by each property.
+	SourceFormats       map[string]int `json:"sourceFormats"`       // Formats whose signatures are backed by each source.
+	Grades              map[string]int `json:"grades"`              // Records with each quality grade.
+	Transfer            Transfer       `json:"transfer"`            // Size of the responses received.
+	Unbound             map[string]int `json:"unbound"`             // Rows leaving each variable of the harvest query unbound.
+
+	// Records and signatures under each top-level MIME type, e.g. image.
+	MediaTypes map[string]MediaTypeStats `json:"mediaTypes"`
+
+	// Records asserted to have no signature, or a signature whose value is
+	// unknown.
+	ConfirmedNoSignature []string `json:"confirmedNoSignature"`
+	UnknownSignature     []string `json:"unknownSignature"`
+
+	// Records that need investigating.
+	Multiples    []string `json:"multiples"`
+	NoProvenance []string `json:"noProvenance"`
+	NoDate       []string `json:"noDate"`
+	NoRelativity []string `json:"noRelativity"`
+	NoEncoding   []string `json:"noEncoding"`
+
+	// Records where the encoding label contradicts the signature value.
+	EncodingMismatch []string `json:"encodingMismatch"`
+
+	// Findings per record and code, the number of records per code, and a
+	// description of each code found.
+	Lints       map[string]map[linting]int `json:"lints"`
+	LintRecords map[linting]int            `json:"lintRecords"`
+	LintCodes   map[linting]string         `json:"lintCodes"`
+
+	// Records with findings in each lint category so that each community
+	// can be given its own worklist.
+	Worklists map[lintCategory][]string `json:"worklists"`
+
+	// Findings of each severity.
+	Severities map[lintSeverity]int `json:"severities"`
+
+	// Records untouched for StaleYears that lack signatures or provenance.
+	StaleYears int      `json:"staleYears"`
+	Stale      []string `json:"stale"`
+
+	// Records whose signatures are grouped by an override, and the IDs of
+	// overrides that no longer match Wikidata.
+	Overridden     []string `json:"overridden"`
+	StaleOverrides []string `json:"staleOverrides"`
+
+	// Records that are expensive to process, if requested.
+	Outliers []Outlier `json:"outliers,omitempty"`
+
+	Manifest    *Manifest    `json:"manifest,omitempty"`    // Where and when the records were harvested, and with which query.
+	Incremental *Incremental `json:"incremental,omitempty"` // How an incremental harvest changed the previous export.
+	Exclusions  *Exclusions  `json:"exclusions,omitempty"`  // Sequences excluded from exports by export policies.
+	Suspicious  []string     `json:"suspicious,omitempty"`  // Alert thresholds exceeded by the changes since the baseline.
+
+	onLint func(LintResult) // Called for every finding as it is made.
+}
+
+// String will return a summary report to be printed.
+func (s Summary) String() string {
+	report, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s", report)
+}
@@ -0,0 +1,110 @@
+package wdanalysis
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+)
+
+// Most institutions identify formats with PRONOM. Rather than build an
+// identifier in parallel to PRONOM's, a supplement contains only what
+// Wikidata adds to it: the signatures of formats PRONOM does not cover, and
+// the sequences PRONOM lacks for the formats it does. The supplement is
+// created against a DROID signature file.
+
+// droidSignatureFile describes the parts of a DROID signature file we need.
+type droidSignatureFile struct {
+	Signatures []struct {
+		ID        string   `xml:"ID,attr"`
+		Sequences []string `xml:"ByteSequence>SubSequence>Sequence"`
+	} `xml:"InternalSignatureCollection>InternalSignature"`
+	Formats []struct {
+		PUID         string   `xml:"PUID,attr"`
+		SignatureIDs []string `xml:"InternalSignatureID"`
+	} `xml:"FileFormatCollection>FileFormat"`
+}
+
+// DROID describes the byte sequences of each format in a DROID signature
+// file.
+type DROID struct {
+	sequences map[string][]string // PUID to its sequences.
+}
+
+// LoadDROID reads a DROID signature file.
+func LoadDROID(path string) (DROID, error) {
+	droid := DROID{sequences: make(map[string][]string)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return droid, err
+	}
+	var file droidSignatureFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return droid, err
+	}
+	signatures := make(map[string][]string)
+	for _, signature := range file.Signatures {
+		for _, sequence := range signature.Sequences {
+			signatures[signature.ID] = append(signatures[signature.ID], strings.ToUpper(sequence))
+		}
+	}
+	for _, format := range file.Formats {
+		sequences := []string{}
+		for _, id := range format.SignatureIDs {
+			sequences = append(sequences, signatures[id]...)
+		}
+		droid.sequences[format.PUID] = append(droid.sequences[format.PUID], sequences...)
+	}
+	return droid, nil
+}
+
+// covers returns true if PRONOM describes the format of a PUID.
+func (d DROID) covers(puid string) bool {
+	_, ok := d.sequences[puid]
+	return ok
+}
+
+// contains returns true if a sequence is part of one of PRONOM's sequences
+// for the given PUIDs. Sequences that could not be normalized cannot be
+// compared and are assumed to be missing from PRONOM.
+func (d DROID) contains(puids []string, s Signature) bool {
+	if s.Normalized == "" {
+		return false
+	}
+	for _, puid := range puids {
+		for _, sequence := range d.sequences[puid] {
+			if strings.Contains(sequence, s.Normalized) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// supplement returns the records with only the signatures that Wikidata
+// adds to PRONOM. Records that add nothing are omitted. Signatures already
+// in PRONOM are recorded in excluded.
+func (d DROID) supplement(records []Wikidata, excluded *Exclusions) []Wikidata {
+	var supplement []Wikidata
+	for _, wd := range records {
+		var covered []string
+		for _, puid := range nonEmpty(wd.PRONOM) {
+			if d.covers(puid) {
+				covered = append(covered, puid)
+			}
+		}
+		var signatures []Signature
+		for _, signature := range wd.Signatures {
+			if d.contains(covered, signature) {
+				excluded.add(wd, signature, ExcludedDROID)
+				continue
+			}
+			signatures = append(signatures, signature)
+		}
+		if len(signatures) == 0 {
+			continue
+		}
+		wd.Signatures = signatures
+		supplement = append(supplement, wd)
+	}
+	return supplement
+}
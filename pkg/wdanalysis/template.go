@@ -0,0 +1,83 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Users often need the records in a shape no exporter writes, e.g. the
+// patterns of another identification tool or a CSV with bespoke columns.
+// Rather than waiting for a new exporter they can render the records
+// through a Go text/template. The template is given the records as they
+// would be exported along with the manifest of the harvest and the
+// summary, and a few functions for the formatting that templates cannot
+// easily do themselves.
+
+// TemplateData is what a template is executed with.
+type TemplateData struct {
+	Manifest Manifest
+	Records  []Wikidata
+	Summary  Summary
+}
+
+// templateFuncs are the functions available to templates in addition to
+// those built in to text/template.
+var templateFuncs = template.FuncMap{
+	"join":     strings.Join,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"replace":  strings.Replace,
+	"nonEmpty": nonEmpty,
+	"csv": func(values ...string) (string, error) {
+		var out bytes.Buffer
+		w := csv.NewWriter(&out)
+		w.Write(values)
+		w.Flush()
+		return strings.TrimSuffix(out.String(), "\n"), w.Error()
+	},
+	"json": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+}
+
+// LoadTemplate reads and parses the template at path.
+func LoadTemplate(path string) (*template.Template, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+}
+
+// TemplateExporter renders the records through Template to Writer.
+type TemplateExporter struct {
+	Template *template.Template
+	Writer   io.Writer
+}
+
+// Export satisfies the Exporter interface.
+func (e TemplateExporter) Export(p *Pipeline) error {
+	records, err := p.ExportRecords()
+	if err != nil {
+		return err
+	}
+	// The template is rendered in full before anything is written so that
+	// an error part way through does not leave partial output.
+	var out bytes.Buffer
+	data := TemplateData{Manifest: p.manifest, Records: records, Summary: p.Summary()}
+	if err := e.Template.Execute(&out, data); err != nil {
+		return fmt.Errorf("cannot render template: %s", err)
+	}
+	if _, err := e.Writer.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("cannot render template: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package wdanalysis
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wikidata"
+)
+
+// TrID describes file formats with definitions made of patterns at fixed
+// positions from the beginning of a file. TrID definitions are matched to
+// Wikidata records by extension, or MIME type, and their patterns compared
+// with the records' beginning of file sequences so that divergent magic
+// numbers can be investigated.
+
+// tridDefinition describes the parts of a TrID XML definition we need.
+type tridDefinition struct {
+	FileType string `xml:"Info>FileType"`
+	Ext      string `xml:"Info>Ext"`
+	Mime     string `xml:"Info>Mime"`
+	Patterns []struct {
+		Bytes string `xml:"Bytes"`
+		Pos   int    `xml:"Pos"`
+	} `xml:"FrontBlock>Pattern"`
+}
+
+// tridPattern is a pattern at a position from the beginning of a file.
+type tridPattern struct {
+	bytes []byte
+	pos   int
+}
+
+// TrIDDefinition is a single TrID definition.
+type TrIDDefinition struct {
+	File       string
+	FileType   string
+	Extensions []string
+	MIMETypes  []string
+	patterns   []tridPattern
+}
+
+// charsetReader reads the ISO-8859-1 encoding TrID definitions are written
+// in.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		data, err := ioutil.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(data))
+		for idx, b := range data {
+			runes[idx] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return nil, fmt.Errorf("unsupported charset: %s", charset)
+}
+
+// splitTrID splits a TrID list, e.g. of extensions, separated by slashes.
+func splitTrID(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, "/") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// loadTrIDDefinition reads a single TrID definition.
+func loadTrIDDefinition(path string) (TrIDDefinition, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return TrIDDefinition{}, err
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charsetReader
+	var def tridDefinition
+	if err := decoder.Decode(&def); err != nil {
+		return TrIDDefinition{}, fmt.Errorf("cannot read '%s': %s", path, err)
+	}
+	definition := TrIDDefinition{
+		File:       filepath.Base(path),
+		FileType:   strings.TrimSpace(def.FileType),
+		Extensions: splitTrID(def.Ext),
+		MIMETypes:  splitTrID(def.Mime),
+	}
+	for _, pattern := range def.Patterns {
+		value, err := hex.DecodeString(strings.TrimSpace(pattern.Bytes))
+		if err != nil {
+			return TrIDDefinition{}, fmt.Errorf("invalid pattern in '%s': %s", path, err)
+		}
+		definition.patterns = append(definition.patterns, tridPattern{bytes: value, pos: pattern.Pos})
+	}
+	return definition, nil
+}
+
+// LoadTrID reads a TrID XML definition, or every definition in a directory.
+func LoadTrID(path string) ([]TrIDDefinition, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		def, err := loadTrIDDefinition(path)
+		return []TrIDDefinition{def}, err
+	}
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var definitions []TrIDDefinition
+	for _, file := range files {
+		if file.IsDir() || strings.ToLower(filepath.Ext(file.Name())) != ".xml" {
+			continue
+		}
+		def, err := loadTrIDDefinition(filepath.Join(path, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, def)
+	}
+	return definitions, nil
+}
+
+// TrIDComparison describes a TrID definition and the records it is matched
+// to.
+type TrIDComparison struct {
+	File     string   `json:"file"`
+	FileType string   `json:"fileType"`
+	Anchor   string   `json:"anchor"` // How the definition was matched, by extension or MIME type.
+	Records  []string `json:"records"`
+	TrID     []string `json:"trid"`     // Patterns of the definition as hex@position.
+	Wikidata []string `json:"wikidata"` // Beginning of file sequences of the records as hex@offset.
+}
+
+// TrIDReport describes how TrID definitions compare with Wikidata.
+type TrIDReport struct {
+	Agreeing    int              `json:"agreeing"`    // Definitions that agree with a sequence in Wikidata.
+	Unmatched   int              `json:"unmatched"`   // Definitions not matched to any record.
+	Divergent   []TrIDComparison `json:"divergent"`   // Definitions that agree with none of the sequences in Wikidata.
+	NoSequences []TrIDComparison `json:"noSequences"` // Definitions whose records have no sequences to compare.
+}
+
+// overlaps returns true if a pattern and a sequence at the given positions
+// overlap, and the bytes where they overlap are the same.
+func overlaps(pattern tridPattern, sequence []byte, offset int) bool {
+	start, end := pattern.pos, pattern.pos+len(pattern.bytes)
+	if offset > start {
+		start = offset
+	}
+	if offset+len(sequence) < end {
+		end = offset + len(sequence)
+	}
+	if start >= end {
+		return false
+	}
+	return bytes.Equal(pattern.bytes[start-pattern.pos:end-pattern.pos], sequence[start-offset:end-offset])
+}
+
+// matchTrID returns the records a definition is matched to by extension
+// or, failing that, by MIME type.
+func matchTrID(def TrIDDefinition, records []Wikidata) ([]Wikidata, string) {
+	var matched []Wikidata
+	for _, wd := range records {
+		for _, ext := range nonEmpty(wd.Extension) {
+			if contains(def.Extensions, strings.ToLower(strings.TrimPrefix(ext, "."))) {
+				matched = append(matched, wd)
+				break
+			}
+		}
+	}
+	if len(matched) > 0 {
+		return matched, "extension"
+	}
+	for _, wd := range records {
+		for _, mime := range nonEmpty(wd.Mimetype) {
+			if contains(def.MIMETypes, strings.ToLower(mime)) {
+				matched = append(matched, wd)
+				break
+			}
+		}
+	}
+	if len(matched) > 0 {
+		return matched, "mime"
+	}
+	return nil, ""
+}
+
+// CompareTrID compares TrID definitions with the processed records.
+func (p *Pipeline) CompareTrID(definitions []TrIDDefinition) TrIDReport {
+	report := TrIDReport{Divergent: []TrIDComparison{}, NoSequences: []TrIDComparison{}}
+	records := p.Records()
+	for _, def := range definitions {
+		matched, anchor := matchTrID(def, records)
+		if len(matched) == 0 {
+			report.Unmatched++
+			continue
+		}
+		comparison := TrIDComparison{
+			File:     def.File,
+			FileType: def.FileType,
+			Anchor:   anchor,
+		}
+		for _, pattern := range def.patterns {
+			comparison.TrID = append(comparison.TrID, fmt.Sprintf("%X@%d", pattern.bytes, pattern.pos))
+		}
+		agrees := false
+		for _, wd := range matched {
+			comparison.Records = append(comparison.Records, wd.URI)
+			for _, signature := range wd.Signatures {
+				if signature.relativity() == wikidata.EOF || signature.Normalized == "" {
+					continue
+				}
+				sequence, err := hex.DecodeString(signature.Normalized)
+				if err != nil {
+					continue
+				}
+				offset, _ := strconv.Atoi(signature.Offset)
+				comparison.Wikidata = append(comparison.Wikidata, fmt.Sprintf("%s@%d", signature.Normalized, offset))
+				for _, pattern := range def.patterns {
+					if overlaps(pattern, sequence, offset) {
+						agrees = true
+					}
+				}
+			}
+		}
+		sort.Strings(comparison.Wikidata)
+		switch {
+		case len(comparison.Wikidata) == 0:
+			report.NoSequences = append(report.NoSequences, comparison)
+		case agrees:
+			report.Agreeing++
+		default:
+			report.Divergent = append(report.Divergent, comparison)
+		}
+	}
+	return report
+}
@@ -0,0 +1,136 @@
+package wdanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ross-spencer/spargo/pkg/spargo"
+)
+
+// Many formats have an item for each version linked by follows (P155) and
+// followed by (P156). Assembled into ordered chains, e.g. PDF 1.0 to 1.7 to
+// 2.0, they let downstream tools fall back to the nearest version when a
+// signature match is ambiguous. The links are not needed to build records
+// so they are harvested with a dedicated query. Where a version is followed
+// by more than one other, each branch is its own chain.
+
+var versionsQuery = `
+	SELECT DISTINCT ?format ?formatLabel ?next ?nextLabel WHERE
+	{
+	  ?format wdt:<<instanceOf>>/wdt:<<subclassOf>>* wd:<<fileFormat>>.
+	  { ?format wdt:<<followedBy>> ?next. } UNION { ?next wdt:<<follows>> ?format. }
+	  SERVICE wikibase:label { bd:serviceParam wikibase:language "[AUTO_LANGUAGE], en". }
+	}
+	order by ?format
+`
+
+// ChainFormat describes a single version in a version chain.
+type ChainFormat struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	URI     string   `json:"uri"`
+	Version []string `json:"version"`
+}
+
+// VersionChain lists the versions of a format, earliest first.
+type VersionChain struct {
+	Formats []ChainFormat `json:"formats"`
+}
+
+// versionChains assembles the links between versions into chains. Chains
+// start at the versions that follow no other. Versions in a loop with no
+// start are chained from the lowest ID and the loop is broken there.
+func (p *Pipeline) versionChains(results []map[string]spargo.Item) []VersionChain {
+	formats := make(map[string]ChainFormat)
+	next := make(map[string][]string)
+	previous := make(map[string]bool)
+	for _, row := range results {
+		from := ChainFormat{ID: p.id(row[formatField].Value), Name: row[labelField].Value, URI: row[formatField].Value}
+		to := ChainFormat{ID: p.id(row["next"].Value), Name: row["nextLabel"].Value, URI: row["next"].Value}
+		for _, format := range []ChainFormat{from, to} {
+			format.Version = nonEmpty(p.records[format.ID].Version)
+			formats[format.ID] = format
+		}
+		if !contains(next[from.ID], to.ID) {
+			next[from.ID] = append(next[from.ID], to.ID)
+		}
+		previous[to.ID] = true
+	}
+	var ids []string
+	for id := range formats {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	chained := make(map[string]bool)
+	chains := []VersionChain{}
+	var walk func(path []string)
+	walk = func(path []string) {
+		last := path[len(path)-1]
+		chained[last] = true
+		var branches []string
+		for _, id := range next[last] {
+			if !contains(path, id) {
+				branches = append(branches, id)
+			}
+		}
+		if len(branches) == 0 {
+			chain := VersionChain{}
+			for _, id := range path {
+				chain.Formats = append(chain.Formats, formats[id])
+			}
+			chains = append(chains, chain)
+			return
+		}
+		sort.Strings(branches)
+		for _, id := range branches {
+			walk(append(append([]string{}, path...), id))
+		}
+	}
+	for _, id := range ids {
+		if !previous[id] {
+			walk([]string{id})
+		}
+	}
+	for _, id := range ids {
+		if !chained[id] {
+			walk([]string{id})
+		}
+	}
+	return chains
+}
+
+// versionsTSV returns the chains as TSV with a row for each version.
+func versionsTSV(chains []VersionChain) string {
+	var out strings.Builder
+	out.WriteString("chain\tposition\tid\tname\turi\tversion\n")
+	for i, chain := range chains {
+		for j, format := range chain.Formats {
+			fmt.Fprintf(&out, "%d\t%d\t%s\t%s\t%s\t%s\n",
+				i+1,
+				j+1,
+				format.ID,
+				refineCell(format.Name),
+				format.URI,
+				refineJoin(format.Version),
+			)
+		}
+	}
+	return out.String()
+}
+
+// exportVersions writes the version chains to path. A JSON document is
+// written if the path has a .json extension, otherwise TSV is written.
+func (p *Pipeline) exportVersions(path string, chains []VersionChain) error {
+	out := []byte(versionsTSV(chains))
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		report, err := json.MarshalIndent(chains, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = []byte(fmt.Sprintf("%s\n", report))
+	}
+	return p.writeExport(path, out)
+}
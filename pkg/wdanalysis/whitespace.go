@@ -0,0 +1,79 @@
+package wdanalysis
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// ASCII magic often contains line endings or tabs, e.g. the newline ending
+// a #! line or the CRLF after an XML prolog. Wikidata strings cannot hold
+// control characters and have their surrounding whitespace trimmed, so
+// editors write them as escapes. ASCII signatures are converted with the
+// escapes of file(1) magic: \n, \r, \t, \0, \\ and \xHH stand for the
+// bytes they name. A backslash followed by anything else stands for
+// itself. Literal tabs, carriage returns and line feeds, which can reach
+// us from dumps, are converted as they are.
+//
+// A signature with whitespace escapes is converted as the policy assumes
+// but is reported, as an editor may have meant the backslash literally or
+// meant a different line ending. Recording such a signature in hexadecimal
+// removes the doubt.
+
+// asciiEscapes are the bytes named by single character escapes.
+var asciiEscapes = map[byte]byte{
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+	'0':  0x00,
+	'\\': '\\',
+}
+
+// whitespaceEscapes are the escapes that name whitespace.
+var whitespaceEscapes = []string{`\n`, `\r`, `\t`}
+
+// unescapeASCII returns the bytes of an ASCII signature.
+func unescapeASCII(value string) []byte {
+	var out []byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i+1 == len(value) {
+			out = append(out, c)
+			continue
+		}
+		if b, ok := asciiEscapes[value[i+1]]; ok {
+			out = append(out, b)
+			i++
+			continue
+		}
+		if value[i+1] == 'x' && i+4 <= len(value) {
+			if b, err := hex.DecodeString(value[i+2 : i+4]); err == nil {
+				out = append(out, b...)
+				i += 3
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// escapesWhitespace returns true if an ASCII signature writes whitespace
+// as escapes.
+func escapesWhitespace(value string) bool {
+	for _, escape := range whitespaceEscapes {
+		if strings.Contains(value, escape) {
+			return true
+		}
+	}
+	return false
+}
+
+// ambiguousWhitespace returns true if the whitespace of an ASCII signature
+// is converted by assumption. An escaped backslash is not, so \\n is read
+// as a backslash followed by n.
+func ambiguousWhitespace(s Signature) bool {
+	if normalizeEncoding(s.Encoding) != encodingASCII {
+		return false
+	}
+	return escapesWhitespace(strings.Replace(s.Signature, `\\`, "", -1))
+}
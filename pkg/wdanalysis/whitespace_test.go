@@ -0,0 +1,107 @@
+package wdanalysis
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// escapeASCII returns bytes as an ASCII signature that unescapeASCII
+// converts back to the same bytes.
+func escapeASCII(data []byte) string {
+	var out strings.Builder
+	for _, b := range data {
+		switch {
+		case b == '\\':
+			out.WriteString(`\\`)
+		case b == '\n':
+			out.WriteString(`\n`)
+		case b == '\r':
+			out.WriteString(`\r`)
+		case b == '\t':
+			out.WriteString(`\t`)
+		case b == 0x00:
+			out.WriteString(`\0`)
+		case b < 0x20 || b > 0x7E:
+			fmt.Fprintf(&out, `\x%02X`, b)
+		default:
+			out.WriteByte(b)
+		}
+	}
+	return out.String()
+}
+
+// asciiCases are ASCII signatures with whitespace and other escapes, and
+// their normalized form.
+var asciiCases = []struct {
+	value      string
+	normalized string
+}{
+	{"%PDF-", "255044462D"},
+	{`#!/bin/sh\n`, "23212F62696E2F73680A"},
+	{`<?xml version="1.0"?>\r\n`, "3C3F786D6C2076657273696F6E3D22312E30223F3E0D0A"},
+	{"a\tb\r\n", "6109620D0A"},
+	{`a\tb\0`, "61096200"},
+	{`\\n\x1A\q`, "5C6E1A5C71"},
+	{`\x4`, "5C7834"},
+	{`end\`, "656E645C"},
+}
+
+// TestASCIIEscapes checks the bytes ASCII signatures are converted to.
+func TestASCIIEscapes(t *testing.T) {
+	for _, c := range asciiCases {
+		normalized, err := validateAndReturnSignature(c.value, encodingASCII)
+		if err != nil {
+			t.Errorf("%q: %s", c.value, err)
+			continue
+		}
+		if normalized != c.normalized {
+			t.Errorf("%q: got %s, want %s", c.value, normalized, c.normalized)
+		}
+	}
+}
+
+// TestASCIIRoundTrip checks that ASCII signatures escaped back from their
+// bytes convert to the same bytes, so that no byte is lost or changed by
+// the escapes.
+func TestASCIIRoundTrip(t *testing.T) {
+	var every []byte
+	for b := 0; b < 256; b++ {
+		every = append(every, byte(b))
+	}
+	normalized := []string{strings.ToUpper(hex.EncodeToString(every))}
+	for _, c := range asciiCases {
+		normalized = append(normalized, c.normalized)
+	}
+	for _, want := range normalized {
+		data, _ := hex.DecodeString(want)
+		escaped := escapeASCII(data)
+		if got, _ := validateAndReturnSignature(escaped, encodingASCII); got != want {
+			t.Errorf("%q: got %s, want %s", escaped, got, want)
+		}
+	}
+}
+
+// TestAmbiguousWhitespace checks which ASCII signatures have whitespace
+// that is converted by assumption.
+func TestAmbiguousWhitespace(t *testing.T) {
+	tests := []struct {
+		signature Signature
+		ambiguous bool
+	}{
+		{Signature{Signature: `#!/bin/sh\n`, Encoding: "ASCII"}, true},
+		{Signature{Signature: `a\tb`, Encoding: "ASCII"}, true},
+		{Signature{Signature: `<?xml\r\n`, Encoding: "ASCII"}, true},
+		{Signature{Signature: `\\n`, Encoding: "ASCII"}, false},
+		{Signature{Signature: `\\\n`, Encoding: "ASCII"}, true},
+		{Signature{Signature: "a\tb", Encoding: "ASCII"}, false},
+		{Signature{Signature: `%PDF-`, Encoding: "ASCII"}, false},
+		{Signature{Signature: `5C6E`, Encoding: "hexadecimal"}, false},
+	}
+	for _, test := range tests {
+		if got := ambiguousWhitespace(test.signature); got != test.ambiguous {
+			t.Errorf("%q (%s): got ambiguous %t, want %t", test.signature.Signature, test.signature.Encoding, got, test.ambiguous)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package wikidata
+
+// Wikidata entities used to describe the encoding (P3294) of a signature.
+// Normalized sequences are written in one of these two encodings.
+const (
+	EncodingHexadecimal = "http://www.wikidata.org/entity/Q82828"    // Hexadecimal.
+	EncodingPRONOM      = "http://www.wikidata.org/entity/Q35432091" // PRONOM internal signature.
+)
@@ -0,0 +1,112 @@
+// Package wikidata provides types and constants that describe how file
+// format signatures are modelled in Wikidata so that tools working with
+// Wikidata signatures do not need to hard-code them.
+package wikidata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Wikidata entities used to describe the relativity (P2210) of a
+// signature's offset.
+const (
+	RelativeBOF = "http://www.wikidata.org/entity/Q35436009" // Beginning of file.
+	RelativeEOF = "http://www.wikidata.org/entity/Q1148480"  // End of file.
+)
+
+// Entities describes the entities a Wikibase uses for the relativity of a
+// signature's offset. Entities may be given as URIs or QIDs.
+type Entities struct {
+	BOF string `json:"bof"` // Beginning of file.
+	EOF string `json:"eof"` // End of file.
+}
+
+// DefaultEntities are the relativity entities of Wikidata.
+var DefaultEntities = Entities{BOF: RelativeBOF, EOF: RelativeEOF}
+
+// Relativity describes the position a signature's offset is measured from.
+type Relativity int
+
+// Relativities we know about. Unknown is used where a signature does not
+// state its relativity.
+const (
+	Unknown Relativity = iota
+	BOF
+	EOF
+)
+
+// Labels of the relativity entities in Wikidata.
+const (
+	bofLabel = "beginning of file"
+	eofLabel = "end of file"
+)
+
+// String returns the conventional short form of a relativity, e.g. BOF.
+func (r Relativity) String() string {
+	switch r {
+	case BOF:
+		return "BOF"
+	case EOF:
+		return "EOF"
+	}
+	return "Unknown"
+}
+
+// URI returns the Wikidata entity describing a relativity.
+func (r Relativity) URI() string {
+	return DefaultEntities.URI(r)
+}
+
+// URI returns the entity describing a relativity.
+func (e Entities) URI(r Relativity) string {
+	switch r {
+	case BOF:
+		return e.BOF
+	case EOF:
+		return e.EOF
+	}
+	return ""
+}
+
+// ParseRelativity returns the relativity described by s using the entities
+// of Wikidata. See Entities.ParseRelativity.
+func ParseRelativity(s string) (Relativity, error) {
+	return DefaultEntities.ParseRelativity(s)
+}
+
+// ParseRelativity returns the relativity described by s which may be an
+// entity URI, a QID, an English label, or the short form of a relativity.
+// An empty string is Unknown and not an error.
+func (e Entities) ParseRelativity(s string) (Relativity, error) {
+	value := strings.TrimSpace(s)
+	switch {
+	case value == "":
+		return Unknown, nil
+	case isEntity(value, e.BOF), strings.EqualFold(value, bofLabel), strings.EqualFold(value, "BOF"):
+		return BOF, nil
+	case isEntity(value, e.EOF), strings.EqualFold(value, eofLabel), strings.EqualFold(value, "EOF"):
+		return EOF, nil
+	}
+	return Unknown, fmt.Errorf("unknown relativity: '%s'", s)
+}
+
+// isEntity returns true if value is the entity, or its QID. Entities of a
+// Wikibase are matched on their QID alone if either is given as a QID.
+func isEntity(value string, entity string) bool {
+	if entity == "" {
+		return false
+	}
+	if value == entity {
+		return true
+	}
+	if !strings.Contains(value, "/") || !strings.Contains(entity, "/") {
+		return qid(value) == qid(entity)
+	}
+	return false
+}
+
+// qid returns the QID at the end of an entity URI.
+func qid(uri string) string {
+	return uri[strings.LastIndex(uri, "/")+1:]
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ross-spencer/wdanalysis/pkg/wdanalysis"
+)
+
+// mergeShardsCommand implements the merge-shards subcommand which merges
+// the cached results of each shard of a harvest into a single harvest that
+// can be processed with -input.
+//
+//	merge-shards -o merged.json shard1.json shard2.json
+func mergeShardsCommand(args []string) error {
+	const usage = "usage: merge-shards -o <merged results> <shard results>..."
+	flags := flag.NewFlagSet("merge-shards", flag.ExitOnError)
+	out := flags.String("o", "", "write the merged results to this file")
+	flags.Parse(args)
+	if *out == "" || flags.NArg() == 0 {
+		return misused("no shards or output given", nil, usage)
+	}
+	merged, err := wdanalysis.MergeShards(flags.Args())
+	if err != nil {
+		return failed("cannot merge shards", err, "give the cached results of every shard, e.g. from cache list on each machine")
+	}
+	if err := ioutil.WriteFile(*out, []byte(merged.Human), 0644); err != nil {
+		return failed("cannot write merged results", err, "")
+	}
+	fmt.Printf("merged %d results from %d shards into %s, process them with -input %s\n", len(merged.Results.Bindings), flags.NArg(), *out, *out)
+	return nil
+}
@@ -0,0 +1,10 @@
+package main
+
+// versionString is set when a release is built, e.g.
+//
+//	go build -ldflags "-X main.versionString=wdanalysis-v0.1.0"
+var versionString = "wdanalysis-dev"
+
+func version() string {
+	return versionString
+}